@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"gorm.io/gorm"
+
+	"laundry-status-backend/config"
+	"laundry-status-backend/internal/db"
+	"laundry-status-backend/internal/logging"
+	"laundry-status-backend/internal/model"
+	"laundry-status-backend/internal/store"
+)
+
+const apiKeyUsage = `usage: laundryd apikey [-config path] <command>
+
+Commands:
+  create -name NAME [-role viewer|operator|admin]   create a key and print it once
+  list                                              list keys
+  revoke ID                                         revoke a key
+`
+
+// runAPIKey manages the API keys of the admin API.
+func runAPIKey(args []string) int {
+	fs := flag.NewFlagSet("apikey", flag.ContinueOnError)
+	configPath := fs.String("config", defaultConfigPath(), "path to the configuration file")
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), apiKeyUsage)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to load configuration from %s: %v\n", *configPath, err)
+		return 2
+	}
+	// Keep stdout for the command's output
+	logging.Setup(os.Stderr, cfg.Log)
+	gormDB, err := db.Init(&cfg.Database)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to initialize database: %v\n", err)
+		return 1
+	}
+
+	ctx := context.Background()
+	command, rest := fs.Arg(0), fs.Args()[1:]
+	switch command {
+	case "create":
+		return createAPIKey(ctx, gormDB, rest)
+	case "list":
+		return listAPIKeys(ctx, gormDB)
+	case "revoke":
+		return revokeAPIKey(ctx, gormDB, rest)
+	}
+	fmt.Fprintf(os.Stderr, "unknown apikey command %q\n", command)
+	fs.Usage()
+	return 2
+}
+
+func createAPIKey(ctx context.Context, gormDB *gorm.DB, args []string) int {
+	fs := flag.NewFlagSet("apikey create", flag.ContinueOnError)
+	name := fs.String("name", "", "who or what the key is for")
+	role := fs.String("role", model.RoleViewer, "viewer, operator or admin")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *name == "" {
+		fmt.Fprintln(os.Stderr, "-name is required")
+		return 2
+	}
+
+	key, record, err := store.CreateAPIKey(ctx, gormDB, *name, *role, time.Now().UTC())
+	if errors.Is(err, store.ErrInvalidRole) {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	} else if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "Created API key %d (%s, %s). It is shown only once:\n", record.ID, record.Name, record.Role)
+	fmt.Println(key)
+	return 0
+}
+
+func listAPIKeys(ctx context.Context, gormDB *gorm.DB) int {
+	keys, err := store.ListAPIKeys(ctx, gormDB)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tROLE\tPREFIX\tCREATED\tLAST USED\tREVOKED")
+	formatTime := func(t *time.Time) string {
+		if t == nil {
+			return "-"
+		}
+		return t.UTC().Format(time.RFC3339)
+	}
+	for _, k := range keys {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s…\t%s\t%s\t%s\n",
+			k.ID, k.Name, k.Role, k.Prefix, formatTime(&k.CreatedAt), formatTime(k.LastUsedAt), formatTime(k.RevokedAt))
+	}
+	w.Flush()
+	return 0
+}
+
+func revokeAPIKey(ctx context.Context, gormDB *gorm.DB, args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "usage: laundryd apikey revoke ID")
+		return 2
+	}
+	id, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid key ID %q\n", args[0])
+		return 2
+	}
+
+	err = store.RevokeAPIKey(ctx, gormDB, id, time.Now().UTC())
+	if errors.Is(err, store.ErrAPIKeyNotFound) {
+		fmt.Fprintf(os.Stderr, "no active API key with ID %d\n", id)
+		return 1
+	} else if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Printf("Revoked API key %d\n", id)
+	return 0
+}
@@ -4,7 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
@@ -14,31 +14,50 @@ import (
 	"laundry-status-backend/config"
 	"laundry-status-backend/internal/api"
 	"laundry-status-backend/internal/db"
+	"laundry-status-backend/internal/live"
+	"laundry-status-backend/internal/logging"
+	"laundry-status-backend/internal/metrics"
+	"laundry-status-backend/internal/parse"
+	"laundry-status-backend/internal/retention"
 	"laundry-status-backend/internal/scraper"
 	"laundry-status-backend/internal/store" // <- New import
 
 	"github.com/SherClockHolmes/webpush-go"
 )
 
-func main() {
-	// Setup logger
-	logger := log.New(os.Stdout, "laundry-backend ", log.LstdFlags)
+// defaultConfigPath returns CONFIG_PATH or the local development default.
+func defaultConfigPath() string {
+	if configPath := os.Getenv("CONFIG_PATH"); configPath != "" {
+		return configPath
+	}
+	return "./config/config.yaml" // Default path for local development
+}
 
-	// Load configuration
-	configPath := os.Getenv("CONFIG_PATH")
-	if configPath == "" {
-		configPath = "./config/config.yaml" // Default path for local development
+func main() {
+	// Subcommands
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "parse-test":
+			os.Exit(runParseTest(os.Args[2:]))
+		case "apikey":
+			os.Exit(runAPIKey(os.Args[2:]))
+		}
 	}
 
+	// Load configuration
+	configPath := defaultConfigPath()
 	cfg, err := config.Load(configPath)
 	if err != nil {
-		logger.Fatalf("failed to load configuration from %s: %v", configPath, err)
+		fatal("failed to load configuration", "path", configPath, "error", err)
 	}
-	logger.Printf("configuration loaded successfully from %s", configPath)
+
+	// Setup logger; everything logged through slog or the log package goes here
+	logging.Setup(os.Stdout, cfg.Log)
+	slog.Info("configuration loaded successfully", "path", configPath, "log_level", cfg.Log.Level, "log_format", cfg.Log.Format)
 
 	// Check for VAPID keys
 	if cfg.Push.PublicKey == "" || cfg.Push.PrivateKey == "" {
-		logger.Fatalf("VAPID keys must be configured. Please generate them and add them to your config file.")
+		fatal("VAPID keys must be configured. Please generate them and add them to your config file.")
 	}
 
 	webpushOptions := webpush.Options{
@@ -51,34 +70,52 @@ func main() {
 	// Initialize database
 	gormDB, err := db.Init(&cfg.Database)
 	if err != nil {
-		logger.Fatalf("failed to initialize database: %v", err)
+		fatal("failed to initialize database", "error", err)
 	}
-	logger.Println("database initialized successfully")
+	slog.Info("database initialized successfully")
 
 	// Create a context that can be cancelled
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	// Compile the machine name parsing rules
+	parser, err := parse.NewParser(cfg.Parser)
+	if err != nil {
+		fatal("invalid parser configuration", "error", err)
+	}
+
 	// Create the new store layer instance
-	appStore := store.NewGormStore(gormDB)
-	logger.Println("data store initialized")
+	appStore := store.NewGormStore(gormDB, parser)
+	slog.Info("data store initialized")
+
+	// Committed occupancy changes are fanned out to live streams
+	events := live.NewBroker(256, 16)
 
 	// Initialize and run the scraper in the background with the store
-	scraperSvc := scraper.NewService(cfg, appStore) // <- Inject store instead of db
+	scraperSvc := scraper.NewService(cfg, appStore, events) // <- Inject store instead of db
 	go scraperSvc.Run(ctx)
 
+	// Machine counts for /metrics are read from the database on collection
+	metrics.Registry.MustRegister(metrics.NewMachineCollector(gormDB, scraperSvc.StateType))
+
+	// Roll up and prune occupancy history when TimescaleDB policies aren't available
+	retentionSvc := retention.NewService(&cfg.Database, gormDB)
+	go retentionSvc.Run(ctx)
+
 	// Initialize router
-	router := api.NewRouter(appStore, &webpushOptions)
+	router := api.NewRouter(cfg, appStore, &webpushOptions, events, scraperSvc)
 	server := &http.Server{
 		Addr:    fmt.Sprintf(":%d", cfg.Server.Port),
 		Handler: router,
 	}
+	// End open streams so Shutdown doesn't wait on them
+	server.RegisterOnShutdown(events.Close)
 
 	// Start the server in a goroutine
 	go func() {
-		logger.Printf("HTTP server starting on port %d", cfg.Server.Port)
+		slog.Info("HTTP server starting", "port", cfg.Server.Port)
 		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
-			logger.Fatalf("HTTP server ListenAndServe: %v", err)
+			fatal("HTTP server ListenAndServe failed", "error", err)
 		}
 	}()
 
@@ -88,15 +125,21 @@ func main() {
 
 	// Block until a signal is received.
 	<-stop
-	logger.Println("Shutdown signal received, stopping services...")
+	slog.Info("Shutdown signal received, stopping services")
 
 	// Create a deadline to wait for.
 	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer shutdownCancel()
 
 	if err := server.Shutdown(shutdownCtx); err != nil {
-		logger.Fatalf("HTTP server Shutdown: %v", err)
+		fatal("HTTP server Shutdown failed", "error", err)
 	}
 
-	logger.Println("Server gracefully stopped")
+	slog.Info("Server gracefully stopped")
+}
+
+// fatal logs an error and exits, like log.Fatal.
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
 }
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"laundry-status-backend/config"
+	"laundry-status-backend/internal/parse"
+)
+
+// runParseTest checks machine names against the configured parsing rules.
+// Names are read one per line from the given file (or stdin), optionally
+// followed by a tab and the upstream floorCode. It exits non-zero if any
+// name fails to parse.
+func runParseTest(args []string) int {
+	fs := flag.NewFlagSet("parse-test", flag.ContinueOnError)
+	configPath := fs.String("config", defaultConfigPath(), "path to the configuration file")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: laundryd parse-test [-config path] [names-file]")
+		fmt.Fprintln(fs.Output(), "Each line is a machine name, optionally followed by a tab and its floorCode.")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to load configuration from %s: %v\n", *configPath, err)
+		return 2
+	}
+	parser, err := parse.NewParser(cfg.Parser)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid parser configuration: %v\n", err)
+		return 2
+	}
+
+	var in io.Reader = os.Stdin
+	if fs.NArg() > 0 {
+		f, err := os.Open(fs.Arg(0))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to open %s: %v\n", fs.Arg(0), err)
+			return 2
+		}
+		defer f.Close()
+		in = f
+	}
+
+	failed, total := 0, 0
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tFLOOR CODE\tRULE\tDORM\tFLOOR\tSEQ")
+
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		name, floorCode, _ := strings.Cut(line, "\t")
+		total++
+
+		parsed, rule, err := parser.Explain(name, floorCode)
+		if err != nil {
+			failed++
+			fmt.Fprintf(w, "%s\t%s\t-\tERROR: %v\t\t\n", name, floorCode, err)
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%d\n", name, floorCode, rule, parsed.Dorm, parsed.Floor, parsed.Seq)
+	}
+	w.Flush()
+
+	if err := scanner.Err(); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to read names: %v\n", err)
+		return 2
+	}
+
+	fmt.Printf("\n%d/%d names parsed\n", total-failed, total)
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
@@ -1,7 +1,9 @@
 package config
 
 import (
-	"log"
+	"fmt"
+	"log/slog"
+	"net"
 	"os"
 	"time"
 
@@ -15,6 +17,56 @@ type Config struct {
 	Database   DatabaseConfig   `yaml:"database"`
 	Push       PushConfig       `yaml:"push"`
 	WorkerPool WorkerPoolConfig `yaml:"worker_pool"`
+	Parser     ParserConfig     `yaml:"parser"`
+	Log        LogConfig        `yaml:"log"`
+}
+
+// Log formats.
+const (
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+)
+
+// LogConfig controls the structured logger.
+type LogConfig struct {
+	LevelName string     `yaml:"level"`  // debug, info, warn or error; defaults to info
+	Level     slog.Level `yaml:"-"`      // Ignored by YAML parser
+	Format    string     `yaml:"format"` // text or json; defaults to text
+}
+
+// ParserConfig declares how upstream machine names are split into dorm, floor
+// and sequence number. Target rules are tried first, then the global rules, then
+// the built-in parser unless it is disabled.
+type ParserConfig struct {
+	Rules          []ParseRule   `yaml:"rules"`
+	Targets        []ParseTarget `yaml:"targets"`
+	DisableBuiltin bool          `yaml:"disable_builtin"`
+	KindRules      []KindRule    `yaml:"kind_rules"`
+	DefaultKind    string        `yaml:"default_kind"` // Kind of machines no rule matches; defaults to "washer"
+}
+
+// KindRule classifies machines by name and/or upstream device ID. The first
+// matching rule wins; a rule with both criteria matches if either does.
+type KindRule struct {
+	Kind        string  `yaml:"kind"` // e.g. "washer", "dryer", "shoe_washer"
+	NamePattern string  `yaml:"name_pattern"`
+	DeviceIDs   []int64 `yaml:"device_ids"`
+}
+
+// ParseRule is a regex with named groups "dorm", "floor" and "seq". Missing
+// groups fall back to the dorm template, the floorCode and 0 respectively.
+type ParseRule struct {
+	Name    string `yaml:"name"`
+	Pattern string `yaml:"pattern"`
+	Dorm    string `yaml:"dorm"` // Optional template expanded with the match, e.g. "北区${building}号楼"
+}
+
+// ParseTarget overrides the global rules for names matching Match, such as
+// the devices of one campus.
+type ParseTarget struct {
+	Name  string      `yaml:"name"`
+	Match string      `yaml:"match"`
+	Rules []ParseRule `yaml:"rules"`
 }
 
 // WorkerPoolConfig holds the configuration for the notification worker pool.
@@ -32,17 +84,34 @@ type PushConfig struct {
 
 // ServerConfig holds the server-related configuration.
 type ServerConfig struct {
-	Port            int     `yaml:"port"`
-	RequestIPHeader string  `yaml:"request_ip_header"`
-	RateLimitPerSec float64 `yaml:"rate_limit_per_sec"`
-	CacheTTLSeconds int     `yaml:"cache_ttl_seconds"`
+	Port            int      `yaml:"port"`
+	RequestIPHeader string   `yaml:"request_ip_header"`  // e.g. "X-Forwarded-For"; only honored from TrustedProxies
+	TrustedProxies  []string `yaml:"trusted_proxies"`    // IPs or CIDRs of reverse proxies allowed to set RequestIPHeader
+	RateLimitPerSec float64  `yaml:"rate_limit_per_sec"` // Per-client request rate on /api; defaults to 10
+	RateLimitBurst  int      `yaml:"rate_limit_burst"`   // Defaults to 5
+
+	SubscriptionRateLimitPerMin float64 `yaml:"subscription_rate_limit_per_min"` // Per-client rate of subscription writes; defaults to 6
+	SubscriptionRateLimitBurst  int     `yaml:"subscription_rate_limit_burst"`   // Defaults to 3
+
+	CacheTTLSeconds int `yaml:"cache_ttl_seconds"` // Upper bound for cached responses; scrapes invalidate them earlier
+
+	CacheTTL time.Duration `yaml:"-"` // Ignored by YAML parser
+
+	ReadinessMaxDataAgeSeconds int           `yaml:"readiness_max_data_age_seconds"` // /readyz fails once the last successful scrape is older; defaults to 10 intervals
+	ReadinessMaxDataAge        time.Duration `yaml:"-"`                              // Ignored by YAML parser
+
+	StreamMaxConnsPerIP    int           `yaml:"stream_max_conns_per_ip"` // Concurrent live streams per client; defaults to 4
+	StreamHeartbeatSeconds int           `yaml:"stream_heartbeat_seconds"`
+	StreamHeartbeat        time.Duration `yaml:"-"` // Ignored by YAML parser
 }
 
 // ScraperConfig holds the scraper-related configuration.
 type ScraperConfig struct {
 	Enabled             bool           `yaml:"enabled"`
 	IntervalSeconds     int            `yaml:"interval_seconds"`
-	Interval            time.Duration  `yaml:"-"` // Ignored by YAML parser
+	Interval            time.Duration  `yaml:"-"`                   // Ignored by YAML parser
+	StaleAfterSeconds   int            `yaml:"stale_after_seconds"` // Data older than this is flagged stale; defaults to 3 intervals
+	StaleAfter          time.Duration  `yaml:"-"`                   // Ignored by YAML parser
 	HTTPProxy           string         `yaml:"http_proxy"`
 	Timezone            string         `yaml:"timezone"`
 	Request             ScraperRequest `yaml:"request"`
@@ -61,11 +130,24 @@ type ScraperRequest struct {
 
 // DatabaseConfig holds the database connection configuration.
 type DatabaseConfig struct {
-	DSN                    string `yaml:"dsn"`
-	MaxOpenConns           int    `yaml:"max_open_conns"`
-	MaxIdleConns           int    `yaml:"max_idle_conns"`
-	ConnMaxLifetimeMinutes int    `yaml:"conn_max_lifetime_minutes"`
-	EnableTimescale        bool   `yaml:"enable_timescale"`
+	DSN                    string          `yaml:"dsn"`
+	MaxOpenConns           int             `yaml:"max_open_conns"`
+	MaxIdleConns           int             `yaml:"max_idle_conns"`
+	ConnMaxLifetimeMinutes int             `yaml:"conn_max_lifetime_minutes"`
+	EnableTimescale        bool            `yaml:"enable_timescale"`
+	Retention              RetentionConfig `yaml:"retention"`
+	SlowQueryMillis        int             `yaml:"slow_query_ms"` // Statements slower than this are logged as warnings; defaults to 200, negative disables the warnings
+	SlowQuery              time.Duration   `yaml:"-"`             // Ignored by YAML parser
+}
+
+// RetentionConfig controls how long occupancy history is kept and how it is rolled up.
+// Statistics read hours past HistoryDays from the rollups, so RollupDays bounds
+// how far back they reach.
+type RetentionConfig struct {
+	HistoryDays           int           `yaml:"history_days"`            // Raw occupancy_histories rows; 0 keeps them forever
+	RollupDays            int           `yaml:"rollup_days"`             // Hourly rollups; 0 keeps them forever
+	RollupIntervalMinutes int           `yaml:"rollup_interval_minutes"` // Go rollup job period when TimescaleDB is off
+	RollupInterval        time.Duration `yaml:"-"`                       // Ignored by YAML parser
 }
 
 // Load reads the configuration from the given path.
@@ -82,21 +164,91 @@ func Load(path string) (*Config, error) {
 		return nil, err
 	}
 
+	if cfg.Log.LevelName != "" {
+		if err := cfg.Log.Level.UnmarshalText([]byte(cfg.Log.LevelName)); err != nil {
+			return nil, fmt.Errorf("log.level: %w", err)
+		}
+	}
+	switch cfg.Log.Format {
+	case "":
+		cfg.Log.Format = LogFormatText
+	case LogFormatText, LogFormatJSON:
+	default:
+		return nil, fmt.Errorf("log.format: must be %q or %q, got %q", LogFormatText, LogFormatJSON, cfg.Log.Format)
+	}
+
 	if cfg.Scraper.IntervalSeconds <= 0 {
 		cfg.Scraper.IntervalSeconds = 60
 	}
 	cfg.Scraper.Interval = time.Duration(cfg.Scraper.IntervalSeconds) * time.Second
+	if cfg.Scraper.StaleAfterSeconds <= 0 {
+		cfg.Scraper.StaleAfterSeconds = 3 * cfg.Scraper.IntervalSeconds
+	}
+	cfg.Scraper.StaleAfter = time.Duration(cfg.Scraper.StaleAfterSeconds) * time.Second
+
+	if cfg.Server.RateLimitPerSec <= 0 {
+		cfg.Server.RateLimitPerSec = 10
+	}
+	if cfg.Server.RateLimitBurst <= 0 {
+		cfg.Server.RateLimitBurst = 5
+	}
+	if cfg.Server.SubscriptionRateLimitPerMin <= 0 {
+		cfg.Server.SubscriptionRateLimitPerMin = 6
+	}
+	if cfg.Server.SubscriptionRateLimitBurst <= 0 {
+		cfg.Server.SubscriptionRateLimitBurst = 3
+	}
+
+	for _, proxy := range cfg.Server.TrustedProxies {
+		if net.ParseIP(proxy) == nil {
+			if _, _, err := net.ParseCIDR(proxy); err != nil {
+				return nil, fmt.Errorf("server.trusted_proxies: invalid address %q", proxy)
+			}
+		}
+	}
+	if cfg.Server.RequestIPHeader != "" && len(cfg.Server.TrustedProxies) == 0 {
+		slog.Warn("server.request_ip_header is set but server.trusted_proxies is empty; the header will be ignored")
+	}
+
+	if cfg.Server.CacheTTLSeconds <= 0 {
+		cfg.Server.CacheTTLSeconds = 300
+	}
+	cfg.Server.CacheTTL = time.Duration(cfg.Server.CacheTTLSeconds) * time.Second
+
+	if cfg.Server.ReadinessMaxDataAgeSeconds <= 0 {
+		cfg.Server.ReadinessMaxDataAgeSeconds = 10 * cfg.Scraper.IntervalSeconds
+	}
+	cfg.Server.ReadinessMaxDataAge = time.Duration(cfg.Server.ReadinessMaxDataAgeSeconds) * time.Second
+
+	if cfg.Server.StreamMaxConnsPerIP <= 0 {
+		cfg.Server.StreamMaxConnsPerIP = 4
+	}
+	if cfg.Server.StreamHeartbeatSeconds <= 0 {
+		cfg.Server.StreamHeartbeatSeconds = 15
+	}
+	cfg.Server.StreamHeartbeat = time.Duration(cfg.Server.StreamHeartbeatSeconds) * time.Second
 
 	if cfg.Scraper.Request.PageSize <= 0 {
 		cfg.Scraper.Request.PageSize = 100
 	}
 
+	if cfg.Database.SlowQueryMillis == 0 {
+		cfg.Database.SlowQueryMillis = 200
+	}
+	// 负值关闭慢查询告警，对应 NewGormLogger 的零阈值
+	cfg.Database.SlowQuery = time.Duration(max(cfg.Database.SlowQueryMillis, 0)) * time.Millisecond
+
+	if cfg.Database.Retention.RollupIntervalMinutes <= 0 {
+		cfg.Database.Retention.RollupIntervalMinutes = 60
+	}
+	cfg.Database.Retention.RollupInterval = time.Duration(cfg.Database.Retention.RollupIntervalMinutes) * time.Minute
+
 	if cfg.Push.TTL <= 0 {
 		cfg.Push.TTL = 3600
 	}
 
 	if cfg.WorkerPool.Size <= 0 {
-		log.Printf("worker_pool.size is not set or invalid; defaulting to 1")
+		slog.Warn("worker_pool.size is not set or invalid; defaulting to 1")
 		cfg.WorkerPool.Size = 1
 	}
 
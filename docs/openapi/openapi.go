@@ -0,0 +1,99 @@
+// Package openapi embeds the OpenAPI description of the HTTP API, so the
+// binary serves and validates against the spec it was built with.
+package openapi
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+//go:embed openapi.yaml paths components
+var files embed.FS
+
+// Load parses the embedded spec and resolves its references between files.
+// The document is parsed once and shared, so callers must not modify it.
+var Load = sync.OnceValues(load)
+
+func load() (*openapi3.T, error) {
+	loader := openapi3.NewLoader()
+	loader.IsExternalRefsAllowed = true
+	loader.ReadFromURIFunc = func(_ *openapi3.Loader, uri *url.URL) ([]byte, error) {
+		return fs.ReadFile(files, path.Clean(uri.Path))
+	}
+	doc, err := loader.LoadFromFile("openapi.yaml")
+	if err != nil {
+		return nil, fmt.Errorf("failed to load OpenAPI spec: %w", err)
+	}
+	if err := doc.Validate(context.Background()); err != nil {
+		return nil, fmt.Errorf("invalid OpenAPI spec: %w", err)
+	}
+	return doc, nil
+}
+
+// Operation is an operation of the spec with the full URL path it is served
+// at, e.g. GET /api/dorms/{dorm_id}/machines.
+type Operation struct {
+	Method    string
+	Path      string // Server base path followed by the spec path
+	SpecPath  string // Key of the path in the spec, e.g. /dorms/{dorm_id}/machines
+	Server    *openapi3.Server
+	PathItem  *openapi3.PathItem
+	Operation *openapi3.Operation
+}
+
+// Operations lists every operation of doc, sorted by path and method. A path
+// is served relative to its own servers if it has any, else relative to the
+// document's first server.
+func Operations(doc *openapi3.T) ([]Operation, error) {
+	var ops []Operation
+	for specPath, item := range doc.Paths.Map() {
+		servers := doc.Servers
+		if len(item.Servers) > 0 {
+			servers = item.Servers
+		}
+		var server *openapi3.Server
+		if len(servers) > 0 {
+			server = servers[0]
+		}
+		base, err := server.BasePath()
+		if err != nil {
+			return nil, fmt.Errorf("invalid server of path %s: %w", specPath, err)
+		}
+		base = strings.TrimSuffix(base, "/")
+		for method, op := range item.Operations() {
+			ops = append(ops, Operation{
+				Method: method, Path: base + specPath, SpecPath: specPath,
+				Server: server, PathItem: item, Operation: op,
+			})
+		}
+	}
+	slices.SortFunc(ops, func(a, b Operation) int {
+		if c := strings.Compare(a.Path, b.Path); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Method, b.Method)
+	})
+	return ops, nil
+}
+
+// JSON returns the spec as a single JSON document, with the references
+// between files turned into references to its components, for viewers that
+// cannot follow references to other files.
+var JSON = sync.OnceValues(func() ([]byte, error) {
+	doc, err := load() // InternalizeRefs modifies the document, so Load's is not used
+	if err != nil {
+		return nil, err
+	}
+	doc.InternalizeRefs(context.Background(), nil)
+	return json.Marshal(doc)
+})
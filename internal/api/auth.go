@@ -0,0 +1,112 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"laundry-status-backend/internal/logging"
+	"laundry-status-backend/internal/model"
+	"laundry-status-backend/internal/mw"
+	"laundry-status-backend/internal/store"
+)
+
+// apiKeyContextKey holds the authenticated model.APIKey in the gin context.
+const apiKeyContextKey = "apiKey"
+
+// requireAPIKey is a middleware that only admits requests carrying an active
+// API key as bearer token.
+func requireAPIKey(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		provided, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || provided == "" {
+			c.Header("WWW-Authenticate", `Bearer realm="admin"`)
+			mw.AbortWithError(c, http.StatusUnauthorized, mw.CodeUnauthorized, "unauthorized")
+			return
+		}
+
+		key, err := store.AuthenticateAPIKey(c.Request.Context(), db, provided, time.Now().UTC())
+		if errors.Is(err, store.ErrAPIKeyNotFound) {
+			c.Header("WWW-Authenticate", `Bearer realm="admin", error="invalid_token"`)
+			mw.AbortWithError(c, http.StatusUnauthorized, mw.CodeUnauthorized, "unauthorized")
+			return
+		} else if err != nil {
+			slog.ErrorContext(c.Request.Context(), "Error authenticating API key", "error", err)
+			mw.AbortWithError(c, http.StatusInternalServerError, mw.CodeInternal, "Failed to authenticate")
+			return
+		}
+		c.Set(apiKeyContextKey, key)
+		c.Next()
+	}
+}
+
+// requireRole is a middleware that only admits keys with at least the given
+// role. It must run after requireAPIKey.
+func requireRole(role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key, _ := c.MustGet(apiKeyContextKey).(model.APIKey)
+		if !store.RoleAllows(key.Role, role) {
+			mw.AbortWithError(c, http.StatusForbidden, mw.CodeForbidden, "this action requires the "+role+" role")
+			return
+		}
+		c.Next()
+	}
+}
+
+// auditWrites is a middleware recording every admin request that may change
+// something, including those denied for lack of a role. It must run after
+// requireAPIKey.
+func auditWrites(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+			c.Next()
+			return
+		}
+
+		// Keep the head of the body for the log and hand the whole body on.
+		// One byte more than is stored lets RecordAudit cut it at a character
+		// boundary rather than keep a partial UTF-8 sequence.
+		var body []byte
+		if c.Request.Body != nil {
+			head, _ := io.ReadAll(io.LimitReader(c.Request.Body, maxAuditBody+1))
+			body = head
+			c.Request.Body = readCloser{io.MultiReader(bytes.NewReader(head), c.Request.Body), c.Request.Body}
+		}
+
+		c.Next()
+
+		key, _ := c.MustGet(apiKeyContextKey).(model.APIKey)
+		ctx := c.Request.Context()
+		entry := model.AuditLog{
+			At:        time.Now().UTC(),
+			APIKeyID:  key.ID,
+			KeyName:   key.Name,
+			Role:      key.Role,
+			Method:    c.Request.Method,
+			Route:     c.FullPath(),
+			Path:      c.Request.URL.RequestURI(),
+			Body:      string(body),
+			Status:    c.Writer.Status(),
+			RequestID: logging.RequestID(ctx),
+			ClientIP:  c.ClientIP(),
+		}
+		if err := store.RecordAudit(ctx, db, entry); err != nil {
+			slog.ErrorContext(ctx, "Error recording admin action", "error", err, "key", key.Name, "path", entry.Path)
+		}
+	}
+}
+
+// maxAuditBody is how much of a request body is read for the audit log.
+const maxAuditBody = 2048
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
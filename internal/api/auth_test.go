@@ -0,0 +1,122 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"laundry-status-backend/internal/model"
+	"laundry-status-backend/internal/store"
+)
+
+func TestAdminAPI_RolesAndAudit(t *testing.T) {
+	testDB := newTestDB(t)
+	require.NoError(t, testDB.Create(&model.Dorm{ID: 1, Name: "东3"}).Error)
+	require.NoError(t, testDB.Create(&model.Machine{ID: 11, DormID: 1, DisplayName: "东3#1-1", Kind: model.MachineKindWasher}).Error)
+	r := newTestRouter(t, testDB)
+
+	now := time.Now().UTC()
+	keys := make(map[string]string)
+	for _, role := range []string{model.RoleViewer, model.RoleOperator, model.RoleAdmin} {
+		key, _, err := store.CreateAPIKey(t.Context(), testDB, role+"-key", role, now)
+		require.NoError(t, err)
+		keys[role] = key
+	}
+
+	do := func(method, path, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if key != "" {
+			req.Header.Set("Authorization", "Bearer "+key)
+		}
+		if body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do(http.MethodGet, "/api/admin/overrides", "", "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.NotEmpty(t, w.Header().Get("WWW-Authenticate"))
+	assert.Equal(t, http.StatusUnauthorized, do(http.MethodGet, "/api/admin/overrides", "lsk_unknown", "").Code)
+
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, "/api/admin/overrides", keys[model.RoleViewer], "").Code)
+	override := `{"display_name":"1号机","notes":"relabelled"}`
+	assert.Equal(t, http.StatusForbidden, do(http.MethodPut, "/api/admin/machines/11/override", keys[model.RoleViewer], override).Code)
+	w = do(http.MethodPut, "/api/admin/machines/11/override", keys[model.RoleOperator], override)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, http.StatusForbidden, do(http.MethodGet, "/api/admin/audit", keys[model.RoleOperator], "").Code)
+	assert.Equal(t, http.StatusConflict, do(http.MethodPost, "/api/admin/scraper/run", keys[model.RoleOperator], "").Code,
+		"no scraper runs in this process")
+
+	w = do(http.MethodGet, "/api/admin/audit", keys[model.RoleAdmin], "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var audit []auditLogResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &audit))
+	require.Len(t, audit, 3, "writes are audited, reads are not")
+	assert.Equal(t, "/api/admin/scraper/run", audit[0].Path)
+	assert.Equal(t, http.StatusConflict, audit[0].Status)
+	assert.Equal(t, "operator-key", audit[1].KeyName)
+	assert.Equal(t, "/api/admin/machines/:machine_id/override", audit[1].Route)
+	assert.Equal(t, override, audit[1].Body)
+	assert.Equal(t, http.StatusOK, audit[1].Status)
+	assert.NotEmpty(t, audit[1].RequestID)
+	assert.Equal(t, http.StatusForbidden, audit[2].Status, "denied attempts are audited too")
+	assert.Equal(t, model.RoleViewer, audit[2].Role)
+
+	var machine model.Machine
+	require.NoError(t, testDB.First(&machine, 11).Error)
+	assert.Equal(t, "1号机", machine.DisplayName, "the handler still received the body the audit read")
+}
+
+func TestAdminAPI_AuditTruncatesAtCharacterBoundary(t *testing.T) {
+	testDB := newTestDB(t)
+	require.NoError(t, testDB.Create(&model.Dorm{ID: 1, Name: "东3"}).Error)
+	require.NoError(t, testDB.Create(&model.Machine{ID: 11, DormID: 1, DisplayName: "东3#1-1"}).Error)
+	r := newTestRouter(t, testDB)
+	key, _, err := store.CreateAPIKey(t.Context(), testDB, "operator", model.RoleOperator, time.Now().UTC())
+	require.NoError(t, err)
+
+	// The audit limit falls inside a three-byte character
+	override := `{"notes":"` + strings.Repeat("洗", 700) + `"}`
+	req := httptest.NewRequest(http.MethodPut, "/api/admin/machines/11/override", strings.NewReader(override))
+	req.Header.Set("Authorization", "Bearer "+key)
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	var entry model.AuditLog
+	require.NoError(t, testDB.First(&entry).Error)
+	assert.True(t, utf8.ValidString(entry.Body), "the body is cut before the partial character")
+	assert.Len(t, entry.Body, maxAuditBody-1)
+	assert.True(t, strings.HasPrefix(override, entry.Body))
+}
+
+func TestGetConfig_RedactsSecrets(t *testing.T) {
+	testDB := newTestDB(t)
+	r := newTestRouter(t, testDB)
+	key, _, err := store.CreateAPIKey(t.Context(), testDB, "root", model.RoleAdmin, time.Now().UTC())
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/admin/config", nil)
+	req.Header.Set("Authorization", "Bearer "+key)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	body := w.Body.String()
+	for _, secret := range []string{"pw@db", "vapid-secret", "s3cret"} {
+		assert.NotContains(t, body, secret)
+	}
+	var view map[string]any
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &view))
+	assert.Equal(t, "public", view["push"].(map[string]any)["vapid_public_key"])
+	assert.Equal(t, redacted, view["database"].(map[string]any)["dsn"])
+}
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"laundry-status-backend/internal/model"
+	"laundry-status-backend/internal/mw"
+	"laundry-status-backend/internal/store"
+)
+
+// freshnessResponse tells clients how current the served data is, so they
+// can warn that it may be outdated while the scraper is failing.
+type freshnessResponse struct {
+	LastScrapeAt      *time.Time `json:"lastScrapeAt"` // Last cycle that stored the whole feed
+	Stale             bool       `json:"stale"`
+	StaleAfterSeconds int        `json:"staleAfterSeconds"`
+}
+
+func newFreshnessResponse(state model.ScraperState, staleAfter time.Duration, now time.Time) freshnessResponse {
+	f := freshnessResponse{
+		LastScrapeAt:      state.LastSuccessAt,
+		StaleAfterSeconds: int(staleAfter.Seconds()),
+	}
+	// 从未成功抓取过的数据一律视为过期
+	f.Stale = state.LastSuccessAt == nil || (staleAfter > 0 && now.Sub(*state.LastSuccessAt) > staleAfter)
+	return f
+}
+
+// loadFreshness reads the scrape outcome and sets the X-Last-Scrape-At and
+// X-Data-Stale headers, as well as the freshness meta of /api/v2 responses.
+// On failure it writes the error response itself.
+func loadFreshness(c *gin.Context, db *gorm.DB, staleAfter time.Duration, now time.Time) (freshnessResponse, bool) {
+	state, err := store.LoadScraperState(c.Request.Context(), db)
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "Error loading scraper state", "error", err)
+		mw.AbortWithError(c, http.StatusInternalServerError, mw.CodeInternal, "Failed to retrieve data freshness")
+		return freshnessResponse{}, false
+	}
+	f := newFreshnessResponse(state, staleAfter, now)
+	if f.LastScrapeAt != nil {
+		c.Header("X-Last-Scrape-At", f.LastScrapeAt.UTC().Format(time.RFC3339))
+	}
+	c.Header("X-Data-Stale", strconv.FormatBool(f.Stale))
+	mw.SetMeta(c, "freshness", f)
+	return f, true
+}
+
+// confirmedAt is when the state of an idle machine was last confirmed: the
+// last scrape that included it, else the last successful scrape.
+func confirmedAt(m model.Machine, f freshnessResponse) time.Time {
+	switch {
+	case m.LastConfirmedAt != nil:
+		return m.LastConfirmedAt.UTC()
+	case f.LastScrapeAt != nil:
+		return f.LastScrapeAt.UTC()
+	}
+	return time.Time{}
+}
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"laundry-status-backend/internal/mw"
+	"laundry-status-backend/internal/store"
+)
+
+type auditLogResponse struct {
+	ID        int64     `json:"id"`
+	At        time.Time `json:"at"`
+	APIKeyID  int64     `json:"apiKeyId"`
+	KeyName   string    `json:"keyName"`
+	Role      string    `json:"role"`
+	Method    string    `json:"method"`
+	Route     string    `json:"route"`
+	Path      string    `json:"path"`
+	Body      string    `json:"body"`
+	Status    int       `json:"status"`
+	RequestID string    `json:"requestId"`
+	ClientIP  string    `json:"clientIp"`
+}
+
+// GetAuditLog handles GET /api/admin/audit, newest entries first. key_id
+// limits the log to one API key; before pages back from an entry ID.
+func (h *Handler) GetAuditLog(c *gin.Context) {
+	limit, ok := intQuery(c, "limit", defaultAdminPageSize, 1, maxAdminPageSize)
+	if !ok {
+		return
+	}
+	query := store.AuditQuery{Limit: limit}
+	for name, target := range map[string]*int64{"key_id": &query.APIKeyID, "before": &query.BeforeID} {
+		if raw := c.Query(name); raw != "" {
+			v, err := strconv.ParseInt(raw, 10, 64)
+			if err != nil || v <= 0 {
+				mw.AbortWithError(c, http.StatusBadRequest, mw.CodeInvalidParameter, "'"+name+"' must be a positive ID")
+				return
+			}
+			*target = v
+		}
+	}
+
+	entries, err := store.ListAuditLogs(c.Request.Context(), h.store.DB(), query)
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "Error listing audit log", "error", err)
+		mw.AbortWithError(c, http.StatusInternalServerError, mw.CodeInternal, "Failed to retrieve audit log")
+		return
+	}
+	response := make([]auditLogResponse, len(entries))
+	for i, e := range entries {
+		response[i] = auditLogResponse{
+			ID: e.ID, At: e.At, APIKeyID: e.APIKeyID, KeyName: e.KeyName, Role: e.Role,
+			Method: e.Method, Route: e.Route, Path: e.Path, Body: e.Body, Status: e.Status,
+			RequestID: e.RequestID, ClientIP: e.ClientIP,
+		}
+	}
+	mw.Respond(c, http.StatusOK, response)
+}
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v3"
+
+	"laundry-status-backend/config"
+	"laundry-status-backend/internal/mw"
+)
+
+// redacted replaces secrets in the config introspection response.
+const redacted = "[redacted]"
+
+// GetConfig handles GET /api/admin/config, returning the effective
+// configuration, defaults applied, in the layout of the config file.
+// Credentials are redacted.
+func GetConfig(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		view, err := redactedConfig(cfg)
+		if err != nil {
+			slog.ErrorContext(c.Request.Context(), "Error rendering configuration", "error", err)
+			mw.AbortWithError(c, http.StatusInternalServerError, mw.CodeInternal, "Failed to render configuration")
+			return
+		}
+		mw.Respond(c, http.StatusOK, view)
+	}
+}
+
+// redactedConfig renders cfg with its YAML keys, without credentials.
+func redactedConfig(cfg *config.Config) (map[string]any, error) {
+	clone := *cfg
+	if clone.Database.DSN != "" {
+		clone.Database.DSN = redacted
+	}
+	if clone.Push.PrivateKey != "" {
+		clone.Push.PrivateKey = redacted
+	}
+	// Upstream headers typically carry the session token
+	if len(clone.Scraper.Request.Headers) > 0 {
+		headers := make(map[string]string, len(clone.Scraper.Request.Headers))
+		for name := range clone.Scraper.Request.Headers {
+			headers[name] = redacted
+		}
+		clone.Scraper.Request.Headers = headers
+	}
+	if proxy, err := url.Parse(clone.Scraper.HTTPProxy); err == nil && proxy.User != nil {
+		proxy.User = url.User(redacted)
+		clone.Scraper.HTTPProxy = proxy.String()
+	}
+
+	raw, err := yaml.Marshal(&clone)
+	if err != nil {
+		return nil, err
+	}
+	var view map[string]any
+	if err := yaml.Unmarshal(raw, &view); err != nil {
+		return nil, err
+	}
+	return view, nil
+}
@@ -0,0 +1,138 @@
+package api
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"laundry-status-backend/internal/model"
+	"laundry-status-backend/internal/mw"
+	"laundry-status-backend/internal/store"
+)
+
+type renameDormRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+type mergeDormRequest struct {
+	Into int64 `json:"into" binding:"required"`
+}
+
+type dormAliasRequest struct {
+	Alias string `json:"alias" binding:"required"`
+}
+
+// RenameDorm handles PATCH /api/admin/dorms/{dorm_id}.
+func (h *Handler) RenameDorm(c *gin.Context) {
+	dormID, err := strconv.ParseInt(c.Param("dorm_id"), 10, 64)
+	if err != nil {
+		mw.AbortWithError(c, http.StatusBadRequest, mw.CodeInvalidParameter, "Invalid dorm ID")
+		return
+	}
+
+	var req renameDormRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortInvalidBody(c, err)
+		return
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		mw.AbortWithError(c, http.StatusBadRequest, mw.CodeInvalidParameter, "name must not be blank")
+		return
+	}
+
+	dorm, err := store.RenameDorm(c.Request.Context(), h.store.DB(), dormID, name)
+	if err != nil {
+		writeDormAdminError(c, err)
+		return
+	}
+	mw.Respond(c, http.StatusOK, DormResponse{ID: dorm.ID, Name: dorm.Name})
+}
+
+// MergeDorm handles POST /api/admin/dorms/{dorm_id}/merge.
+func (h *Handler) MergeDorm(c *gin.Context) {
+	dormID, err := strconv.ParseInt(c.Param("dorm_id"), 10, 64)
+	if err != nil {
+		mw.AbortWithError(c, http.StatusBadRequest, mw.CodeInvalidParameter, "Invalid dorm ID")
+		return
+	}
+
+	var req mergeDormRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortInvalidBody(c, err)
+		return
+	}
+
+	target, err := store.MergeDorms(c.Request.Context(), h.store.DB(), dormID, req.Into)
+	if err != nil {
+		writeDormAdminError(c, err)
+		return
+	}
+	mw.Respond(c, http.StatusOK, DormResponse{ID: target.ID, Name: target.Name})
+}
+
+// GetDormAliases handles GET /api/admin/dorms/{dorm_id}/aliases.
+func (h *Handler) GetDormAliases(c *gin.Context) {
+	dormID, err := strconv.ParseInt(c.Param("dorm_id"), 10, 64)
+	if err != nil {
+		mw.AbortWithError(c, http.StatusBadRequest, mw.CodeInvalidParameter, "Invalid dorm ID")
+		return
+	}
+
+	var aliases []model.DormAlias
+	if err := h.store.DB().Where("dorm_id = ?", dormID).Order("alias").Find(&aliases).Error; err != nil {
+		mw.AbortWithError(c, http.StatusInternalServerError, mw.CodeInternal, "Failed to retrieve aliases")
+		return
+	}
+
+	names := make([]string, len(aliases))
+	for i, a := range aliases {
+		names[i] = a.Alias
+	}
+	mw.Respond(c, http.StatusOK, gin.H{"aliases": names})
+}
+
+// PutDormAlias handles POST /api/admin/dorms/{dorm_id}/aliases, pointing an
+// alias at this dorm even if it previously resolved elsewhere.
+func (h *Handler) PutDormAlias(c *gin.Context) {
+	dormID, err := strconv.ParseInt(c.Param("dorm_id"), 10, 64)
+	if err != nil {
+		mw.AbortWithError(c, http.StatusBadRequest, mw.CodeInvalidParameter, "Invalid dorm ID")
+		return
+	}
+
+	var req dormAliasRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortInvalidBody(c, err)
+		return
+	}
+	alias := strings.TrimSpace(req.Alias)
+	if alias == "" {
+		mw.AbortWithError(c, http.StatusBadRequest, mw.CodeInvalidParameter, "alias must not be blank")
+		return
+	}
+
+	if err := store.SetDormAlias(c.Request.Context(), h.store.DB(), dormID, alias); err != nil {
+		writeDormAdminError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func writeDormAdminError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, store.ErrDormNotFound):
+		mw.AbortWithError(c, http.StatusNotFound, mw.CodeDormNotFound, err.Error())
+	case errors.Is(err, store.ErrDormNameTaken), errors.Is(err, store.ErrAliasTaken):
+		mw.AbortWithError(c, http.StatusConflict, mw.CodeConflict, err.Error())
+	case errors.Is(err, store.ErrDormMergeSelf):
+		mw.AbortWithError(c, http.StatusBadRequest, mw.CodeInvalidBody, err.Error())
+	default:
+		slog.ErrorContext(c.Request.Context(), "Error in dorm admin operation", "error", err)
+		mw.AbortWithError(c, http.StatusInternalServerError, mw.CodeInternal, "Failed to update dorm")
+	}
+}
@@ -0,0 +1,143 @@
+package api
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"laundry-status-backend/internal/model"
+	"laundry-status-backend/internal/mw"
+	"laundry-status-backend/internal/store"
+)
+
+// machineOverrideRequest replaces a machine's override. Omitted (null)
+// metadata fields keep following upstream data.
+type machineOverrideRequest struct {
+	DisplayName *string `json:"display_name"`
+	DormID      *int64  `json:"dorm_id"`
+	Floor       *int    `json:"floor"`
+	Seq         *int    `json:"seq"`
+	Hidden      bool    `json:"hidden"`
+	Notes       string  `json:"notes"`
+}
+
+type machineOverrideResponse struct {
+	MachineID   int64     `json:"machineId"`
+	DisplayName *string   `json:"displayName"`
+	DormID      *int64    `json:"dormId"`
+	Floor       *int      `json:"floor"`
+	Seq         *int      `json:"seq"`
+	Hidden      bool      `json:"hidden"`
+	Notes       string    `json:"notes"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+func newMachineOverrideResponse(o model.MachineOverride) machineOverrideResponse {
+	return machineOverrideResponse{
+		MachineID: o.MachineID, DisplayName: o.DisplayName, DormID: o.DormID,
+		Floor: o.Floor, Seq: o.Seq, Hidden: o.Hidden, Notes: o.Notes, UpdatedAt: o.UpdatedAt,
+	}
+}
+
+// GetMachineOverrides handles GET /api/admin/overrides.
+func (h *Handler) GetMachineOverrides(c *gin.Context) {
+	var overrides []model.MachineOverride
+	if err := h.store.DB().Order("machine_id").Find(&overrides).Error; err != nil {
+		mw.AbortWithError(c, http.StatusInternalServerError, mw.CodeInternal, "Failed to retrieve overrides")
+		return
+	}
+
+	response := make([]machineOverrideResponse, len(overrides))
+	for i, o := range overrides {
+		response[i] = newMachineOverrideResponse(o)
+	}
+	mw.Respond(c, http.StatusOK, response)
+}
+
+// GetMachineOverride handles GET /api/admin/machines/{machine_id}/override.
+func (h *Handler) GetMachineOverride(c *gin.Context) {
+	machineID, err := strconv.ParseInt(c.Param("machine_id"), 10, 64)
+	if err != nil {
+		mw.AbortWithError(c, http.StatusBadRequest, mw.CodeInvalidParameter, "Invalid machine ID")
+		return
+	}
+
+	var override model.MachineOverride
+	if err := h.store.DB().First(&override, machineID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			mw.AbortWithError(c, http.StatusNotFound, mw.CodeOverrideNotFound, store.ErrOverrideNotFound.Error())
+		} else {
+			mw.AbortWithError(c, http.StatusInternalServerError, mw.CodeInternal, "Failed to retrieve override")
+		}
+		return
+	}
+	mw.Respond(c, http.StatusOK, newMachineOverrideResponse(override))
+}
+
+// PutMachineOverride handles PUT /api/admin/machines/{machine_id}/override.
+func (h *Handler) PutMachineOverride(c *gin.Context) {
+	machineID, err := strconv.ParseInt(c.Param("machine_id"), 10, 64)
+	if err != nil {
+		mw.AbortWithError(c, http.StatusBadRequest, mw.CodeInvalidParameter, "Invalid machine ID")
+		return
+	}
+
+	var req machineOverrideRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortInvalidBody(c, err)
+		return
+	}
+
+	override := model.MachineOverride{
+		MachineID:   machineID,
+		DisplayName: req.DisplayName,
+		DormID:      req.DormID,
+		Floor:       req.Floor,
+		Seq:         req.Seq,
+		Hidden:      req.Hidden,
+		Notes:       req.Notes,
+	}
+	if _, err := store.SaveMachineOverride(c.Request.Context(), h.store.DB(), override); err != nil {
+		switch {
+		case errors.Is(err, store.ErrMachineNotFound):
+			mw.AbortWithError(c, http.StatusNotFound, mw.CodeMachineNotFound, err.Error())
+		case errors.Is(err, store.ErrDormNotFound):
+			mw.AbortWithError(c, http.StatusNotFound, mw.CodeDormNotFound, err.Error())
+		default:
+			slog.ErrorContext(c.Request.Context(), "Error saving override", "machine_id", machineID, "error", err)
+			mw.AbortWithError(c, http.StatusInternalServerError, mw.CodeInternal, "Failed to save override")
+		}
+		return
+	}
+
+	if err := h.store.DB().First(&override, machineID).Error; err != nil {
+		mw.AbortWithError(c, http.StatusInternalServerError, mw.CodeInternal, "Failed to retrieve override")
+		return
+	}
+	mw.Respond(c, http.StatusOK, newMachineOverrideResponse(override))
+}
+
+// DeleteMachineOverride handles DELETE /api/admin/machines/{machine_id}/override.
+func (h *Handler) DeleteMachineOverride(c *gin.Context) {
+	machineID, err := strconv.ParseInt(c.Param("machine_id"), 10, 64)
+	if err != nil {
+		mw.AbortWithError(c, http.StatusBadRequest, mw.CodeInvalidParameter, "Invalid machine ID")
+		return
+	}
+
+	if err := store.DeleteMachineOverride(c.Request.Context(), h.store.DB(), machineID); err != nil {
+		if errors.Is(err, store.ErrOverrideNotFound) {
+			mw.AbortWithError(c, http.StatusNotFound, mw.CodeOverrideNotFound, err.Error())
+		} else {
+			slog.ErrorContext(c.Request.Context(), "Error deleting override", "machine_id", machineID, "error", err)
+			mw.AbortWithError(c, http.StatusInternalServerError, mw.CodeInternal, "Failed to delete override")
+		}
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,85 @@
+package api
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"laundry-status-backend/internal/model"
+	"laundry-status-backend/internal/mw"
+	"laundry-status-backend/internal/store"
+)
+
+// quarantineResponse is a device whose name could not be parsed.
+type quarantineResponse struct {
+	ID          int64     `json:"id"`
+	RawName     string    `json:"rawName"`
+	FloorCode   string    `json:"floorCode"`
+	IMEI        string    `json:"imei"`
+	DeviceID    int64     `json:"deviceId"`
+	Error       string    `json:"error"`
+	FirstSeenAt time.Time `json:"firstSeenAt"`
+	LastSeenAt  time.Time `json:"lastSeenAt"`
+}
+
+type promoteMachineRequest struct {
+	DormID int64 `json:"dorm_id" binding:"required"`
+	Floor  int   `json:"floor" binding:"required"`
+	Seq    int   `json:"seq"`
+}
+
+// GetQuarantine handles GET /api/admin/quarantine.
+func (h *Handler) GetQuarantine(c *gin.Context) {
+	var machines []model.QuarantinedMachine
+	if err := h.store.DB().Order("last_seen_at DESC").Find(&machines).Error; err != nil {
+		mw.AbortWithError(c, http.StatusInternalServerError, mw.CodeInternal, "Failed to retrieve quarantined machines")
+		return
+	}
+
+	response := make([]quarantineResponse, len(machines))
+	for i, m := range machines {
+		response[i] = quarantineResponse{
+			ID: m.ID, RawName: m.RawName, FloorCode: m.FloorCode, IMEI: m.IMEI, DeviceID: m.DeviceID,
+			Error: m.Error, FirstSeenAt: m.FirstSeenAt, LastSeenAt: m.LastSeenAt,
+		}
+	}
+	mw.Respond(c, http.StatusOK, response)
+}
+
+// PromoteQuarantinedMachine handles POST /api/admin/quarantine/{machine_id}/promote.
+func (h *Handler) PromoteQuarantinedMachine(c *gin.Context) {
+	machineID, err := strconv.ParseInt(c.Param("machine_id"), 10, 64)
+	if err != nil {
+		mw.AbortWithError(c, http.StatusBadRequest, mw.CodeInvalidParameter, "Invalid machine ID")
+		return
+	}
+
+	var req promoteMachineRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortInvalidBody(c, err)
+		return
+	}
+
+	machine, err := store.PromoteQuarantinedMachine(c.Request.Context(), h.store.DB(), machineID, store.MachinePlacement{
+		DormID: req.DormID,
+		Floor:  req.Floor,
+		Seq:    req.Seq,
+	})
+	switch {
+	case errors.Is(err, store.ErrQuarantineNotFound):
+		mw.AbortWithError(c, http.StatusNotFound, mw.CodeMachineNotFound, err.Error())
+		return
+	case errors.Is(err, store.ErrDormNotFound):
+		mw.AbortWithError(c, http.StatusNotFound, mw.CodeDormNotFound, err.Error())
+		return
+	case err != nil:
+		slog.ErrorContext(c.Request.Context(), "Error promoting quarantined machine", "machine_id", machineID, "error", err)
+		mw.AbortWithError(c, http.StatusInternalServerError, mw.CodeInternal, "Failed to promote machine")
+		return
+	}
+	mw.Respond(c, http.StatusCreated, machine)
+}
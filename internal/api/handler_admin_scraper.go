@@ -0,0 +1,58 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"laundry-status-backend/internal/mw"
+	"laundry-status-backend/internal/scraper"
+)
+
+// scraperControl resolves the scraper an admin request controls, answering
+// 409 if none runs in this process.
+func scraperControl(c *gin.Context, scraperSvc *scraper.Service) (*scraper.Service, bool) {
+	if scraperSvc == nil || !scraperSvc.Status().Enabled {
+		mw.AbortWithError(c, http.StatusConflict, mw.CodeConflict, "scraper is disabled")
+		return nil, false
+	}
+	return scraperSvc, true
+}
+
+// RunScraper handles POST /api/admin/scraper/run, starting a scrape cycle
+// right away. Requests made while one is already pending share that cycle.
+func RunScraper(scraperSvc *scraper.Service) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		svc, ok := scraperControl(c, scraperSvc)
+		if !ok {
+			return
+		}
+		svc.Trigger()
+		c.Status(http.StatusAccepted)
+	}
+}
+
+// PauseScraper handles POST /api/admin/scraper/pause. Scheduled cycles are
+// skipped until the scraper is resumed or the process restarts.
+func PauseScraper(scraperSvc *scraper.Service) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		svc, ok := scraperControl(c, scraperSvc)
+		if !ok {
+			return
+		}
+		svc.Pause()
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// ResumeScraper handles POST /api/admin/scraper/resume.
+func ResumeScraper(scraperSvc *scraper.Service) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		svc, ok := scraperControl(c, scraperSvc)
+		if !ok {
+			return
+		}
+		svc.Resume()
+		c.Status(http.StatusNoContent)
+	}
+}
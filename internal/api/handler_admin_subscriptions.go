@@ -0,0 +1,114 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"laundry-status-backend/internal/model"
+	"laundry-status-backend/internal/mw"
+)
+
+const (
+	defaultAdminPageSize = 100
+	maxAdminPageSize     = 500
+)
+
+// subscriptionInfo describes a push subscription without its endpoint URL,
+// which works as a credential for pushing to the browser.
+type subscriptionInfo struct {
+	ID          string    `json:"id"`          // Stable digest of the endpoint
+	PushService string    `json:"pushService"` // Host of the endpoint, e.g. fcm.googleapis.com
+	CreatedAt   time.Time `json:"createdAt"`
+	MachineIDs  []int64   `json:"machineIds"`
+}
+
+type subscriptionsResponse struct {
+	Total         int64              `json:"total"`
+	Subscriptions []subscriptionInfo `json:"subscriptions"`
+}
+
+// GetSubscriptions handles GET /api/admin/subscriptions. machine_id limits
+// the list to subscribers of one machine; limit and offset page it.
+func (h *Handler) GetSubscriptions(c *gin.Context) {
+	limit, ok := intQuery(c, "limit", defaultAdminPageSize, 1, maxAdminPageSize)
+	if !ok {
+		return
+	}
+	offset, ok := intQuery(c, "offset", 0, 0, 1<<30)
+	if !ok {
+		return
+	}
+
+	db := h.store.DB().WithContext(c.Request.Context())
+	filter := func(tx *gorm.DB) *gorm.DB { return tx }
+	if raw := c.Query("machine_id"); raw != "" {
+		machineID, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			mw.AbortWithError(c, http.StatusBadRequest, mw.CodeInvalidParameter, "Invalid machine ID")
+			return
+		}
+		filter = func(tx *gorm.DB) *gorm.DB {
+			return tx.Where("endpoint IN (?)", db.Table("subscription_machine_mapping").
+				Select("push_subscription_endpoint").Where("machine_id = ?", machineID))
+		}
+	}
+
+	var total int64
+	if err := db.Model(&model.PushSubscription{}).Scopes(filter).Count(&total).Error; err != nil {
+		mw.AbortWithError(c, http.StatusInternalServerError, mw.CodeInternal, "Failed to retrieve subscriptions")
+		return
+	}
+	var subscriptions []model.PushSubscription
+	if err := db.Scopes(filter).Order("created_at DESC, endpoint").Limit(limit).Offset(offset).Find(&subscriptions).Error; err != nil {
+		mw.AbortWithError(c, http.StatusInternalServerError, mw.CodeInternal, "Failed to retrieve subscriptions")
+		return
+	}
+
+	endpoints := make([]string, len(subscriptions))
+	for i, s := range subscriptions {
+		endpoints[i] = s.Endpoint
+	}
+	var mappings []struct {
+		PushSubscriptionEndpoint string
+		MachineID                int64
+	}
+	if len(endpoints) > 0 {
+		err := db.Table("subscription_machine_mapping").
+			Where("push_subscription_endpoint IN ?", endpoints).
+			Order("machine_id").
+			Find(&mappings).Error
+		if err != nil {
+			mw.AbortWithError(c, http.StatusInternalServerError, mw.CodeInternal, "Failed to retrieve subscriptions")
+			return
+		}
+	}
+	machinesByEndpoint := make(map[string][]int64, len(endpoints))
+	for _, m := range mappings {
+		machinesByEndpoint[m.PushSubscriptionEndpoint] = append(machinesByEndpoint[m.PushSubscriptionEndpoint], m.MachineID)
+	}
+
+	response := subscriptionsResponse{Total: total, Subscriptions: make([]subscriptionInfo, len(subscriptions))}
+	for i, s := range subscriptions {
+		digest := sha256.Sum256([]byte(s.Endpoint))
+		info := subscriptionInfo{
+			ID:         hex.EncodeToString(digest[:8]),
+			CreatedAt:  s.CreatedAt,
+			MachineIDs: machinesByEndpoint[s.Endpoint],
+		}
+		if u, err := url.Parse(s.Endpoint); err == nil {
+			info.PushService = u.Host
+		}
+		if info.MachineIDs == nil {
+			info.MachineIDs = []int64{}
+		}
+		response.Subscriptions[i] = info
+	}
+	mw.Respond(c, http.StatusOK, response)
+}
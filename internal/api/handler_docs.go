@@ -0,0 +1,47 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"laundry-status-backend/docs/openapi"
+	"laundry-status-backend/internal/mw"
+)
+
+// docsPage shows the spec in Swagger UI. The URL of the spec is relative, so
+// the page keeps working behind a proxy serving the API under a prefix.
+const docsPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <meta name="viewport" content="width=device-width, initial-scale=1">
+  <title>Laundry Status API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js" crossorigin></script>
+  <script>
+    window.ui = SwaggerUIBundle({ url: "docs/openapi.json", dom_id: "#swagger-ui" });
+  </script>
+</body>
+</html>
+`
+
+// GetDocs handles GET /api/docs, an interactive viewer of the spec.
+func GetDocs(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(docsPage))
+}
+
+// GetSpec handles GET /api/docs/openapi.json, the spec embedded in the binary.
+func GetSpec(c *gin.Context) {
+	spec, err := openapi.JSON()
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "Error rendering OpenAPI spec", "error", err)
+		mw.AbortWithError(c, http.StatusInternalServerError, mw.CodeInternal, "Failed to render OpenAPI spec")
+		return
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", spec)
+}
@@ -1,62 +1,143 @@
 package api
 
 import (
+	"log/slog"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 
+	"laundry-status-backend/config"
 	"laundry-status-backend/internal/model"
+	"laundry-status-backend/internal/mw"
+	"laundry-status-backend/internal/store"
 )
 
 // DormResponse represents the API response for a single dorm.
 type DormResponse struct {
-	ID            int64  `json:"id"`
-	Name          string `json:"name"`
-	MaxFloor      int    `json:"maxFloor"`
-	TotalMachines int64  `json:"totalMachines"`
+	ID             int64            `json:"id"`
+	Name           string           `json:"name"`
+	MaxFloor       int              `json:"maxFloor"`
+	TotalMachines  int64            `json:"totalMachines"`
+	MachinesByKind map[string]int64 `json:"machinesByKind"`
+
+	Availability *DormAvailability `json:"availability,omitempty"` // Only with ?include=availability
+}
+
+// DormAvailability counts the machines of a dorm by current state, overall
+// and per floor. Machines in states of unknown type are in neither Occupied
+// nor Faulty.
+type DormAvailability struct {
+	Available    int                 `json:"available"`
+	Occupied     int                 `json:"occupied"`
+	Faulty       int                 `json:"faulty"`
+	NextFinishAt *time.Time          `json:"nextFinishAt"` // Soonest predicted finish, null if none is predicted
+	Floors       []FloorAvailability `json:"floors"`
 }
 
-// GetDorms handles the GET /api/dorms request.
-func GetDorms(db *gorm.DB) gin.HandlerFunc {
+// FloorAvailability counts the machines of one floor by current state.
+type FloorAvailability struct {
+	Floor        int        `json:"floor"`
+	Available    int        `json:"available"`
+	Occupied     int        `json:"occupied"`
+	Faulty       int        `json:"faulty"`
+	NextFinishAt *time.Time `json:"nextFinishAt"`
+}
+
+// GetDorms handles the GET /api/dorms request. ?include=availability adds
+// the current machine counts by state of every dorm and floor.
+func GetDorms(db *gorm.DB, cfg *config.Config) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		withAvailability := false
+		if include := c.Query("include"); include != "" {
+			for _, part := range strings.Split(include, ",") {
+				if part != "availability" {
+					mw.AbortWithError(c, http.StatusBadRequest, mw.CodeInvalidParameter, "Unknown include '"+part+"', supported: availability")
+					return
+				}
+				withAvailability = true
+			}
+		}
+
 		// 1) 一次取所有宿舍
 		var dorms []model.Dorm
 		if err := db.Find(&dorms).Error; err != nil {
-			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve dorms"})
+			mw.AbortWithError(c, http.StatusInternalServerError, mw.CodeInternal, "Failed to retrieve dorms")
 			return
 		}
 
-		// 2) 一次聚合出每个宿舍的统计
+		// 2) 一次聚合出每个宿舍、每种机型的统计
 		type AggRow struct {
 			DormID        int64
+			Kind          string
 			TotalMachines int64
 			MaxFloor      int
 		}
 		var aggs []AggRow
 		if err := db.
 			Model(&model.Machine{}).
-			Select("dorm_id as dorm_id, COUNT(*) as total_machines, COALESCE(MAX(floor), 0) as max_floor").
-			Group("dorm_id").
+			Scopes(store.VisibleMachines).
+			Select("dorm_id as dorm_id, kind as kind, COUNT(*) as total_machines, COALESCE(MAX(floor), 0) as max_floor").
+			Group("dorm_id, kind").
 			Scan(&aggs).Error; err != nil {
-			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to aggregate machines"})
+			mw.AbortWithError(c, http.StatusInternalServerError, mw.CodeInternal, "Failed to aggregate machines")
 			return
 		}
 
 		// 3) 合并
-		aggMap := make(map[int64]AggRow, len(aggs))
-		for _, a := range aggs {
-			aggMap[a.DormID] = a
-		}
-
 		responses := make([]DormResponse, 0, len(dorms))
-		for _, d := range dorms {
-			a := aggMap[d.ID] // 不存在时使用零值
+		indexByDorm := make(map[int64]int, len(dorms))
+		for i, d := range dorms {
+			indexByDorm[d.ID] = i
 			responses = append(responses, DormResponse{
 				ID: d.ID, Name: d.Name,
-				MaxFloor: a.MaxFloor, TotalMachines: a.TotalMachines,
+				MachinesByKind: make(map[string]int64),
 			})
 		}
-		c.JSON(http.StatusOK, responses)
+		for _, a := range aggs {
+			i, ok := indexByDorm[a.DormID]
+			if !ok {
+				continue
+			}
+			r := &responses[i]
+			r.TotalMachines += a.TotalMachines
+			r.MachinesByKind[a.Kind] = a.TotalMachines
+			if a.MaxFloor > r.MaxFloor {
+				r.MaxFloor = a.MaxFloor
+			}
+		}
+
+		if withAvailability {
+			// 4) 一次聚合出每个宿舍、每层楼的可用情况
+			floors, err := store.CountAvailability(c.Request.Context(), db, cfg.Scraper.StateOccupiedValues, cfg.Scraper.StateFaultyValues)
+			if err != nil {
+				slog.ErrorContext(c.Request.Context(), "Error counting machine availability", "error", err)
+				mw.AbortWithError(c, http.StatusInternalServerError, mw.CodeInternal, "Failed to count machine availability")
+				return
+			}
+			for i := range responses {
+				responses[i].Availability = &DormAvailability{Floors: []FloorAvailability{}}
+			}
+			for _, f := range floors {
+				i, ok := indexByDorm[f.DormID]
+				if !ok {
+					continue
+				}
+				a := responses[i].Availability
+				a.Available += f.Available
+				a.Occupied += f.Occupied
+				a.Faulty += f.Faulty
+				if f.NextFinishAt != nil && (a.NextFinishAt == nil || f.NextFinishAt.Before(*a.NextFinishAt)) {
+					a.NextFinishAt = f.NextFinishAt
+				}
+				a.Floors = append(a.Floors, FloorAvailability{
+					Floor: f.Floor, Available: f.Available, Occupied: f.Occupied, Faulty: f.Faulty,
+					NextFinishAt: f.NextFinishAt,
+				})
+			}
+		}
+		mw.Respond(c, http.StatusOK, responses)
 	}
 }
@@ -0,0 +1,108 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"laundry-status-backend/config"
+	"laundry-status-backend/internal/model"
+	"laundry-status-backend/internal/testdb"
+)
+
+// newTestDB creates an isolated in-memory database with the full schema.
+func newTestDB(t *testing.T) *gorm.DB {
+	return testdb.New(t,
+		&model.Dorm{}, &model.Machine{}, &model.MachineOverride{},
+		&model.OccupancyOpen{}, &model.OccupancyHistory{}, &model.ScraperState{},
+		&model.PushSubscription{}, &model.APIKey{}, &model.AuditLog{},
+	)
+}
+
+func TestGetDorms(t *testing.T) {
+	testDB := newTestDB(t)
+	require.NoError(t, testDB.Create(&[]model.Dorm{{ID: 1, Name: "东3"}, {ID: 2, Name: "空楼"}}).Error)
+	require.NoError(t, testDB.Create(&[]model.Machine{
+		{ID: 11, DormID: 1, DisplayName: "东3#1-1", Floor: 1, Kind: model.MachineKindWasher},
+		{ID: 12, DormID: 1, DisplayName: "东3#5-1", Floor: 5, Kind: model.MachineKindWasher},
+		{ID: 13, DormID: 1, DisplayName: "东3#2-烘干", Floor: 2, Kind: model.MachineKindDryer},
+		{ID: 14, DormID: 1, DisplayName: "东3#6-1", Floor: 6, Kind: model.MachineKindWasher},
+	}).Error)
+	require.NoError(t, testDB.Create(&model.MachineOverride{MachineID: 14, Hidden: true}).Error)
+
+	r := gin.New()
+	r.GET("/api/dorms", GetDorms(testDB, &config.Config{}))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/api/dorms", nil)
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var dorms []DormResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &dorms))
+	require.Len(t, dorms, 2)
+
+	assert.Equal(t, int64(3), dorms[0].TotalMachines, "hidden machines are not counted")
+	assert.Equal(t, 5, dorms[0].MaxFloor)
+	assert.Equal(t, map[string]int64{"washer": 2, "dryer": 1}, dorms[0].MachinesByKind)
+
+	assert.Equal(t, int64(0), dorms[1].TotalMachines)
+	assert.Empty(t, dorms[1].MachinesByKind)
+}
+
+func TestGetDorms_Availability(t *testing.T) {
+	testDB := newTestDB(t)
+	observed := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, testDB.Create(&[]model.Dorm{{ID: 1, Name: "东3"}, {ID: 2, Name: "空楼"}}).Error)
+	require.NoError(t, testDB.Create(&[]model.Machine{
+		{ID: 11, DormID: 1, DisplayName: "东3#1-1", Floor: 1, Kind: model.MachineKindWasher},
+		{ID: 12, DormID: 1, DisplayName: "东3#1-2", Floor: 1, Kind: model.MachineKindWasher},
+		{ID: 13, DormID: 1, DisplayName: "东3#2-1", Floor: 2, Kind: model.MachineKindWasher},
+		{ID: 14, DormID: 1, DisplayName: "东3#2-2", Floor: 2, Kind: model.MachineKindWasher},
+	}).Error)
+	require.NoError(t, testDB.Create(&[]model.OccupancyOpen{
+		{MachineID: 12, ObservedAt: observed, Status: 2, Message: "使用中", TimeRemaining: 1200},
+		{MachineID: 13, ObservedAt: observed, Status: 2, Message: "使用中", TimeRemaining: 600},
+		{MachineID: 14, ObservedAt: observed, Status: 3, Message: "故障"},
+	}).Error)
+
+	cfg := &config.Config{Scraper: config.ScraperConfig{StateOccupiedValues: []int{2}, StateFaultyValues: []int{3}}}
+	r := gin.New()
+	r.GET("/api/dorms", GetDorms(testDB, cfg))
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	w := get("/api/dorms?include=availability")
+	require.Equal(t, http.StatusOK, w.Code)
+	var dorms []DormResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &dorms))
+	require.Len(t, dorms, 2)
+
+	floor1Finish, floor2Finish := observed.Add(20*time.Minute), observed.Add(10*time.Minute)
+	assert.Equal(t, &DormAvailability{
+		Available: 1, Occupied: 2, Faulty: 1, NextFinishAt: &floor2Finish,
+		Floors: []FloorAvailability{
+			{Floor: 1, Available: 1, Occupied: 1, NextFinishAt: &floor1Finish},
+			{Floor: 2, Occupied: 1, Faulty: 1, NextFinishAt: &floor2Finish},
+		},
+	}, dorms[0].Availability)
+	assert.Equal(t, &DormAvailability{Floors: []FloorAvailability{}}, dorms[1].Availability)
+
+	assert.NotContains(t, get("/api/dorms").Body.String(), "availability", "only included on request")
+	assert.Equal(t, http.StatusBadRequest, get("/api/dorms?include=machines").Code)
+
+	// The full router checks the response against the spec
+	w = httptest.NewRecorder()
+	newTestRouter(t, testDB).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v2/dorms?include=availability", nil))
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+}
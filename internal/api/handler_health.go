@@ -0,0 +1,165 @@
+package api
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"laundry-status-backend/config"
+	"laundry-status-backend/internal/db"
+	"laundry-status-backend/internal/mw"
+	"laundry-status-backend/internal/scraper"
+	"laundry-status-backend/internal/store"
+)
+
+// readinessTimeout bounds each readiness probe so a hung database fails it
+// instead of piling up probes.
+const readinessTimeout = 2 * time.Second
+
+// Healthz handles the GET /healthz request: the process is up and serving.
+func Healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+type readinessResponse struct {
+	Status string            `json:"status"` // "ready" or "unavailable"
+	Checks map[string]string `json:"checks"` // "ok" or the reason the check failed
+}
+
+// Readyz handles the GET /readyz request. The service is ready when the
+// database is reachable, its schema is migrated and, while the scraper is
+// enabled, the last successful scrape is recent enough to serve.
+func Readyz(gormDB *gorm.DB, cfg *config.Config) gin.HandlerFunc {
+	// The schema never regresses while running, so one success is enough
+	var schemaOK atomic.Bool
+
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), readinessTimeout)
+		defer cancel()
+
+		response := readinessResponse{Status: "ready", Checks: make(map[string]string)}
+		fail := func(check, reason string) {
+			response.Status = "unavailable"
+			response.Checks[check] = reason
+		}
+
+		sqlDB, err := gormDB.DB()
+		if err == nil {
+			err = sqlDB.PingContext(ctx)
+		}
+		if err != nil {
+			slog.WarnContext(ctx, "Readiness: database unreachable", "error", err)
+			fail("database", "unreachable")
+			fail("migrations", "unknown")
+			fail("data", "unknown")
+			c.JSON(http.StatusServiceUnavailable, response)
+			return
+		}
+		response.Checks["database"] = "ok"
+
+		if !schemaOK.Load() {
+			if err := db.CheckSchema(gormDB.WithContext(ctx)); err != nil {
+				fail("migrations", err.Error())
+			} else {
+				schemaOK.Store(true)
+			}
+		}
+		if schemaOK.Load() {
+			response.Checks["migrations"] = "ok"
+		}
+
+		switch {
+		case !cfg.Scraper.Enabled:
+			response.Checks["data"] = "scraper disabled"
+		case response.Checks["migrations"] != "ok":
+			fail("data", "unknown")
+		default:
+			state, err := store.LoadScraperState(ctx, gormDB)
+			switch {
+			case err != nil:
+				slog.WarnContext(ctx, "Readiness: cannot load scraper state", "error", err)
+				fail("data", "unknown")
+			case state.LastSuccessAt == nil:
+				fail("data", "no successful scrape yet")
+			case time.Since(*state.LastSuccessAt) > cfg.Server.ReadinessMaxDataAge:
+				fail("data", "last successful scrape at "+state.LastSuccessAt.UTC().Format(time.RFC3339))
+			default:
+				response.Checks["data"] = "ok"
+			}
+		}
+
+		status := http.StatusOK
+		if response.Status != "ready" {
+			status = http.StatusServiceUnavailable
+		}
+		c.JSON(status, response)
+	}
+}
+
+type scraperStatusResponse struct {
+	Enabled             bool       `json:"enabled"`
+	Paused              bool       `json:"paused"`
+	LastCycleAt         *time.Time `json:"lastCycleAt"` // Last cycle run by this process
+	LastCycleDurationMs int64      `json:"lastCycleDurationMs"`
+	LastAttemptAt       *time.Time `json:"lastAttemptAt"`
+	LastSuccessAt       *time.Time `json:"lastSuccessAt"`
+	ConsecutiveFailures int        `json:"consecutiveFailures"`
+	LastError           string     `json:"lastError"`
+	UpstreamLatencyMs   int64      `json:"upstreamLatencyMs"` // Mean page fetch time of the last cycle
+	UpstreamPages       int        `json:"upstreamPages"`
+}
+
+type notificationStatusResponse struct {
+	QueueDepth int `json:"queueDepth"`
+	Workers    int `json:"workers"`
+}
+
+type statusResponse struct {
+	Scraper       scraperStatusResponse      `json:"scraper"`
+	Freshness     freshnessResponse          `json:"freshness"`
+	Notifications notificationStatusResponse `json:"notifications"`
+}
+
+// GetStatus handles the GET /api/status request, reporting scraper and
+// notification health. scraperSvc may be nil if no scraper runs in this
+// process; only the persisted scrape outcome is reported then.
+func GetStatus(db *gorm.DB, cfg *config.Config, scraperSvc *scraper.Service) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		state, err := store.LoadScraperState(c.Request.Context(), db)
+		if err != nil {
+			slog.ErrorContext(c.Request.Context(), "Error loading scraper state", "error", err)
+			mw.AbortWithError(c, http.StatusInternalServerError, mw.CodeInternal, "Failed to retrieve scraper status")
+			return
+		}
+
+		response := statusResponse{
+			Scraper: scraperStatusResponse{
+				Enabled:             cfg.Scraper.Enabled,
+				LastSuccessAt:       state.LastSuccessAt,
+				ConsecutiveFailures: state.ConsecutiveFailures,
+				LastError:           state.LastError,
+			},
+			Freshness: newFreshnessResponse(state, cfg.Scraper.StaleAfter, time.Now().UTC()),
+		}
+		if !state.LastAttemptAt.IsZero() {
+			response.Scraper.LastAttemptAt = &state.LastAttemptAt
+		}
+		if scraperSvc != nil {
+			live := scraperSvc.Status()
+			if !live.LastCycleAt.IsZero() {
+				response.Scraper.LastCycleAt = &live.LastCycleAt
+			}
+			response.Scraper.Paused = live.Paused
+			response.Scraper.LastCycleDurationMs = live.LastCycleDuration.Milliseconds()
+			response.Scraper.UpstreamLatencyMs = live.UpstreamLatency.Milliseconds()
+			response.Scraper.UpstreamPages = live.UpstreamPages
+			response.Notifications = notificationStatusResponse{QueueDepth: live.QueueDepth, Workers: live.Workers}
+		}
+		mw.Respond(c, http.StatusOK, response)
+	}
+}
@@ -0,0 +1,90 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"laundry-status-backend/config"
+	"laundry-status-backend/internal/model"
+)
+
+func TestReadyz(t *testing.T) {
+	testDB := newTestDB(t)
+	cfg := &config.Config{}
+	cfg.Scraper.Enabled = true
+	cfg.Server.ReadinessMaxDataAge = 10 * time.Minute
+
+	r := gin.New()
+	r.GET("/healthz", Healthz)
+	r.GET("/readyz", Readyz(testDB, cfg))
+	probe := func(path string) (int, readinessResponse) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		r.ServeHTTP(w, req)
+		var resp readinessResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return w.Code, resp
+	}
+
+	code, _ := probe("/healthz")
+	assert.Equal(t, http.StatusOK, code)
+
+	code, resp := probe("/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "ok", resp.Checks["database"])
+	assert.Contains(t, resp.Checks["migrations"], "dorm_aliases", "the test schema lacks some tables")
+
+	require.NoError(t, testDB.AutoMigrate(&model.DormAlias{}, &model.QuarantinedMachine{}, &model.PushSubscription{}))
+	code, resp = probe("/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "ok", resp.Checks["migrations"])
+	assert.Equal(t, "no successful scrape yet", resp.Checks["data"])
+
+	old := time.Now().Add(-time.Hour)
+	require.NoError(t, testDB.Create(&model.ScraperState{ID: model.ScraperStateID, LastAttemptAt: old, LastSuccessAt: &old}).Error)
+	code, _ = probe("/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code, "data older than the threshold")
+
+	recent := time.Now().Add(-time.Minute)
+	require.NoError(t, testDB.Model(&model.ScraperState{}).Where("id = ?", model.ScraperStateID).Update("last_success_at", recent).Error)
+	code, resp = probe("/readyz")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "ready", resp.Status)
+}
+
+func TestGetStatus(t *testing.T) {
+	testDB := newTestDB(t)
+	attempt := time.Now().UTC().Truncate(time.Second)
+	success := attempt.Add(-time.Minute)
+	require.NoError(t, testDB.Create(&model.ScraperState{
+		ID: model.ScraperStateID, LastAttemptAt: attempt, LastSuccessAt: &success,
+		ConsecutiveFailures: 1, LastError: "received non-200 status code: 502",
+	}).Error)
+
+	cfg := &config.Config{}
+	cfg.Scraper.Enabled = true
+	cfg.Scraper.StaleAfter = 3 * time.Minute
+	r := gin.New()
+	r.GET("/api/status", GetStatus(testDB, cfg, nil))
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/api/status", nil)
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp statusResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.True(t, resp.Scraper.Enabled)
+	assert.Equal(t, 1, resp.Scraper.ConsecutiveFailures)
+	assert.Equal(t, "received non-200 status code: 502", resp.Scraper.LastError)
+	require.NotNil(t, resp.Scraper.LastAttemptAt)
+	assert.True(t, attempt.Equal(*resp.Scraper.LastAttemptAt))
+	assert.Nil(t, resp.Scraper.LastCycleAt, "no scraper runs in this process")
+	assert.False(t, resp.Freshness.Stale)
+}
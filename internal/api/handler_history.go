@@ -0,0 +1,146 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"laundry-status-backend/internal/model"
+	"laundry-status-backend/internal/mw"
+	"laundry-status-backend/internal/store"
+)
+
+const (
+	defaultHistoryLimit = 50
+	maxHistoryLimit     = 500
+)
+
+// historySegment is one continuous non-idle state of a machine.
+type historySegment struct {
+	State        int        `json:"state"`
+	Message      string     `json:"message"`
+	Start        time.Time  `json:"start"`
+	PredictedEnd *time.Time `json:"predictedEnd"`
+	ObservedEnd  *time.Time `json:"observedEnd"` // nil while the state is still ongoing
+}
+
+type machineHistoryResponse struct {
+	MachineID  int64            `json:"machineId"`
+	Segments   []historySegment `json:"segments"`
+	NextCursor *string          `json:"nextCursor"`
+}
+
+// GetMachineHistory handles the GET /api/machines/{machine_id}/history request.
+// Segments overlapping [from, to) are returned newest first; pass nextCursor
+// back as ?cursor= to fetch the following page.
+func GetMachineHistory(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		machineID, err := strconv.ParseInt(c.Param("machine_id"), 10, 64)
+		if err != nil {
+			mw.AbortWithError(c, http.StatusBadRequest, mw.CodeInvalidParameter, "Invalid machine ID")
+			return
+		}
+
+		q := store.HistoryQuery{MachineID: machineID, Limit: defaultHistoryLimit}
+		for param, dst := range map[string]*time.Time{"from": &q.From, "to": &q.To, "cursor": &q.Before} {
+			raw := c.Query(param)
+			if raw == "" {
+				continue
+			}
+			if *dst, err = time.Parse(time.RFC3339Nano, raw); err != nil {
+				mw.AbortWithError(c, http.StatusBadRequest, mw.CodeInvalidParameter, "Invalid '"+param+"' timestamp format. Use RFC3339.")
+				return
+			}
+		}
+		if !q.From.IsZero() && !q.To.IsZero() && !q.From.Before(q.To) {
+			mw.AbortWithError(c, http.StatusBadRequest, mw.CodeInvalidParameter, "'from' must be before 'to'")
+			return
+		}
+		if raw := c.Query("limit"); raw != "" {
+			limit, err := strconv.Atoi(raw)
+			if err != nil || limit <= 0 || limit > maxHistoryLimit {
+				mw.AbortWithError(c, http.StatusBadRequest, mw.CodeInvalidParameter, "'limit' must be between 1 and "+strconv.Itoa(maxHistoryLimit))
+				return
+			}
+			q.Limit = limit
+		}
+
+		var machine model.Machine
+		if err := db.Scopes(store.VisibleMachines).First(&machine, machineID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				mw.AbortWithError(c, http.StatusNotFound, mw.CodeMachineNotFound, "Machine not found")
+				return
+			}
+			mw.AbortWithError(c, http.StatusInternalServerError, mw.CodeInternal, "Failed to retrieve machine")
+			return
+		}
+
+		response := machineHistoryResponse{MachineID: machineID, Segments: []historySegment{}}
+		archived := q
+
+		// 当前未结束的状态只出现在第一页
+		if q.Before.IsZero() {
+			var open model.OccupancyOpen
+			err := db.Where("machine_id = ?", machineID).Limit(1).Find(&open).Error
+			if err != nil {
+				mw.AbortWithError(c, http.StatusInternalServerError, mw.CodeInternal, "Failed to retrieve current status")
+				return
+			}
+			if open.MachineID != 0 && (q.To.IsZero() || open.ObservedAt.Before(q.To)) {
+				var predictedEnd *time.Time
+				if open.TimeRemaining > 0 {
+					end := open.ObservedAt.Add(time.Duration(open.TimeRemaining) * time.Second)
+					predictedEnd = &end
+				}
+				response.Segments = append(response.Segments, historySegment{
+					State:        open.Status,
+					Message:      open.Message,
+					Start:        open.ObservedAt,
+					PredictedEnd: predictedEnd,
+				})
+				// 当前状态也计入本页条数
+				archived.Limit--
+			}
+		}
+
+		var history []model.OccupancyHistory
+		if archived.Limit > 0 {
+			history, err = store.MachineHistory(c.Request.Context(), db, archived)
+			if err != nil {
+				mw.AbortWithError(c, http.StatusInternalServerError, mw.CodeInternal, "Failed to retrieve machine history")
+				return
+			}
+		}
+		for _, h := range history {
+			observedEnd := h.ObservedAt
+			// 无预计时长的状态归档时 period_end 等于观测到的结束时间
+			var predictedEnd *time.Time
+			if !h.PeriodEnd.Equal(h.ObservedAt) && h.PeriodEnd.After(h.PeriodStart) {
+				end := h.PeriodEnd
+				predictedEnd = &end
+			}
+			response.Segments = append(response.Segments, historySegment{
+				State:        h.Status,
+				Message:      h.Message,
+				Start:        h.PeriodStart,
+				PredictedEnd: predictedEnd,
+				ObservedEnd:  &observedEnd,
+			})
+		}
+		switch {
+		case archived.Limit == 0:
+			// 页内只有当前状态：已归档的状态都在它开始时或之前结束
+			cursor := response.Segments[0].Start.Add(time.Microsecond).UTC().Format(time.RFC3339Nano)
+			response.NextCursor = &cursor
+		case len(history) == archived.Limit:
+			cursor := history[len(history)-1].ObservedAt.UTC().Format(time.RFC3339Nano)
+			response.NextCursor = &cursor
+		}
+
+		mw.Respond(c, http.StatusOK, response)
+	}
+}
@@ -0,0 +1,94 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"laundry-status-backend/internal/model"
+)
+
+func TestGetMachineHistory(t *testing.T) {
+	testDB := newTestDB(t)
+	base := time.Date(2025, 3, 10, 8, 0, 0, 0, time.UTC)
+
+	require.NoError(t, testDB.Create(&model.Dorm{ID: 1, Name: "东3"}).Error)
+	require.NoError(t, testDB.Create(&model.Machine{ID: 11, DormID: 1, DisplayName: "东3#1-1", Floor: 1}).Error)
+	require.NoError(t, testDB.Create(&[]model.OccupancyHistory{
+		{MachineID: 11, Status: 2, Message: "使用中", PeriodStart: base, PeriodEnd: base.Add(40 * time.Minute), ObservedAt: base.Add(45 * time.Minute)},
+		{MachineID: 11, Status: 3, Message: "故障", PeriodStart: base.Add(2 * time.Hour), PeriodEnd: base.Add(3 * time.Hour), ObservedAt: base.Add(3 * time.Hour)},
+		{MachineID: 11, Status: 2, Message: "使用中", PeriodStart: base.Add(4 * time.Hour), PeriodEnd: base.Add(5 * time.Hour), ObservedAt: base.Add(5 * time.Hour)},
+		{MachineID: 12, Status: 2, Message: "使用中", PeriodStart: base, PeriodEnd: base.Add(time.Hour), ObservedAt: base.Add(time.Hour)},
+	}).Error)
+	require.NoError(t, testDB.Create(&model.OccupancyOpen{
+		MachineID: 11, Status: 2, Message: "使用中", ObservedAt: base.Add(6 * time.Hour), TimeRemaining: 1800,
+	}).Error)
+
+	r := gin.New()
+	r.GET("/api/machines/:machine_id/history", GetMachineHistory(testDB))
+	get := func(path string, query url.Values) (int, machineHistoryResponse) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, path+"?"+query.Encode(), nil)
+		r.ServeHTTP(w, req)
+		var resp machineHistoryResponse
+		if w.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		}
+		return w.Code, resp
+	}
+
+	// The first page starts with the ongoing state, which counts towards the limit.
+	code, page := get("/api/machines/11/history", url.Values{"limit": {"2"}})
+	require.Equal(t, http.StatusOK, code)
+	require.Len(t, page.Segments, 2)
+	assert.Nil(t, page.Segments[0].ObservedEnd)
+	assert.Equal(t, base.Add(6*time.Hour+30*time.Minute), page.Segments[0].PredictedEnd.UTC())
+	assert.Equal(t, base.Add(4*time.Hour), page.Segments[1].Start.UTC())
+	require.NotNil(t, page.NextCursor)
+
+	code, page = get("/api/machines/11/history", url.Values{"limit": {"2"}, "cursor": {*page.NextCursor}})
+	require.Equal(t, http.StatusOK, code)
+	require.Len(t, page.Segments, 2)
+	assert.Equal(t, "故障", page.Segments[0].Message)
+	assert.Nil(t, page.Segments[0].PredictedEnd, "states without a predicted duration have no predicted end")
+	assert.Equal(t, base.Add(45*time.Minute), page.Segments[1].ObservedEnd.UTC())
+	require.NotNil(t, page.NextCursor)
+
+	code, page = get("/api/machines/11/history", url.Values{"limit": {"2"}, "cursor": {*page.NextCursor}})
+	require.Equal(t, http.StatusOK, code)
+	assert.Empty(t, page.Segments)
+	assert.Nil(t, page.NextCursor)
+
+	// A first page holding only the ongoing state continues with the latest archived one.
+	code, page = get("/api/machines/11/history", url.Values{"limit": {"1"}})
+	require.Equal(t, http.StatusOK, code)
+	require.Len(t, page.Segments, 1)
+	assert.Nil(t, page.Segments[0].ObservedEnd)
+	require.NotNil(t, page.NextCursor)
+	code, page = get("/api/machines/11/history", url.Values{"limit": {"1"}, "cursor": {*page.NextCursor}})
+	require.Equal(t, http.StatusOK, code)
+	require.Len(t, page.Segments, 1)
+	assert.Equal(t, base.Add(4*time.Hour), page.Segments[0].Start.UTC())
+
+	// Segments overlapping the range are returned, including ones that only partly overlap.
+	code, page = get("/api/machines/11/history", url.Values{
+		"from": {base.Add(30 * time.Minute).Format(time.RFC3339)},
+		"to":   {base.Add(2*time.Hour + 30*time.Minute).Format(time.RFC3339)},
+	})
+	require.Equal(t, http.StatusOK, code)
+	require.Len(t, page.Segments, 2)
+	assert.Equal(t, 3, page.Segments[0].State)
+	assert.Equal(t, base, page.Segments[1].Start.UTC())
+
+	code, _ = get("/api/machines/11/history", url.Values{"from": {"yesterday"}})
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = get("/api/machines/99/history", nil)
+	assert.Equal(t, http.StatusNotFound, code)
+}
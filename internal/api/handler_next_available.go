@@ -0,0 +1,195 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"laundry-status-backend/config"
+	"laundry-status-backend/internal/model"
+	"laundry-status-backend/internal/mw"
+	"laundry-status-backend/internal/stats"
+	"laundry-status-backend/internal/store"
+)
+
+// biasHistoryWeeks is how far back finished sessions are used to estimate prediction bias.
+const biasHistoryWeeks = 4
+
+type nextAvailableMachine struct {
+	MachineID   int64  `json:"machineId"`
+	DisplayName string `json:"displayName"`
+	Floor       int    `json:"floor"`
+	State       int    `json:"state"`
+	Message     string `json:"message"`
+	IsAvailable bool   `json:"isAvailable"`
+	// PredictedAt is the upstream prediction, ExpectedAt the bias-corrected
+	// estimate. Both are null for busy states without a finish time, e.g. faults.
+	PredictedAt *time.Time `json:"predictedAt"`
+	ExpectedAt  *time.Time `json:"expectedAt"`
+	Overdue     bool       `json:"overdue"` // Past the corrected estimate but not yet free
+
+	LastConfirmedAt *time.Time `json:"lastConfirmedAt"` // Last scrape that included the machine
+}
+
+type nextAvailableResponse struct {
+	DormID       int64                  `json:"dormId"`
+	Floor        int                    `json:"floor"`
+	Floors       []int                  `json:"floors"`
+	GeneratedAt  time.Time              `json:"generatedAt"`
+	BiasSeconds  int                    `json:"biasSeconds"`
+	BiasSamples  int                    `json:"biasSamples"`
+	AvailableNow int                    `json:"availableNow"`
+	WaitSeconds  *int                   `json:"waitSeconds"` // null when no machine has a finish time
+	Earliest     *nextAvailableMachine  `json:"earliest"`
+	Machines     []nextAvailableMachine `json:"machines"`
+	Freshness    freshnessResponse      `json:"freshness"`
+}
+
+// GetNextAvailable handles the GET /api/dorms/{dorm_id}/floors/{floor}/next-available
+// request. ?radius= also considers the floors within that distance.
+func GetNextAvailable(db *gorm.DB, cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		dormID, err := strconv.ParseInt(c.Param("dorm_id"), 10, 64)
+		if err != nil {
+			mw.AbortWithError(c, http.StatusBadRequest, mw.CodeInvalidParameter, "Invalid dorm ID")
+			return
+		}
+		floor, err := strconv.Atoi(c.Param("floor"))
+		if err != nil {
+			mw.AbortWithError(c, http.StatusBadRequest, mw.CodeInvalidParameter, "Invalid floor")
+			return
+		}
+		radius, ok := intQuery(c, "radius", 0, 0, maxRecommendationFloorRange)
+		if !ok {
+			return
+		}
+
+		if !dormExists(c, db, dormID) {
+			return
+		}
+
+		floors := make([]int, 0, 2*radius+1)
+		for f := floor - radius; f <= floor+radius; f++ {
+			floors = append(floors, f)
+		}
+
+		machineQuery := db.Scopes(store.VisibleMachines).Where("dorm_id = ? AND floor IN ?", dormID, floors)
+		kind := c.Query("kind")
+		if kind != "" {
+			machineQuery = machineQuery.Where("kind = ?", kind)
+		}
+		var machines []model.Machine
+		if err := machineQuery.Order("floor, seq").Find(&machines).Error; err != nil {
+			mw.AbortWithError(c, http.StatusInternalServerError, mw.CodeInternal, "Failed to retrieve machines")
+			return
+		}
+		machineIDs := make([]int64, len(machines))
+		for i, m := range machines {
+			machineIDs[i] = m.ID
+		}
+		var opens []model.OccupancyOpen
+		if err := db.Where("machine_id IN ?", machineIDs).Find(&opens).Error; err != nil {
+			mw.AbortWithError(c, http.StatusInternalServerError, mw.CodeInternal, "Failed to retrieve machine status")
+			return
+		}
+		openByMachine := make(map[int64]model.OccupancyOpen, len(opens))
+		for _, o := range opens {
+			openByMachine[o.MachineID] = o
+		}
+
+		now := time.Now().UTC()
+		freshness, ok := loadFreshness(c, db, cfg.Scraper.StaleAfter, now)
+		if !ok {
+			return
+		}
+		bias, err := stats.PredictionBias(c.Request.Context(), db, stats.Query{
+			DormID:   dormID,
+			Kind:     kind,
+			Statuses: cfg.Scraper.StateOccupiedValues,
+			From:     now.Add(-biasHistoryWeeks * 7 * 24 * time.Hour),
+			To:       now,
+		})
+		if err != nil {
+			slog.WarnContext(c.Request.Context(), "Error estimating prediction bias", "dorm_id", dormID, "error", err)
+			mw.AbortWithError(c, http.StatusInternalServerError, mw.CodeInternal, "Failed to estimate availability")
+			return
+		}
+
+		response := nextAvailableResponse{
+			DormID:      dormID,
+			Floor:       floor,
+			Floors:      floors,
+			GeneratedAt: now,
+			BiasSeconds: int(bias.Median.Seconds()),
+			BiasSamples: bias.Samples,
+			Machines:    make([]nextAvailableMachine, 0, len(machines)),
+			Freshness:   freshness,
+		}
+
+		for _, m := range machines {
+			entry := nextAvailableMachine{
+				MachineID:       m.ID,
+				DisplayName:     m.DisplayName,
+				Floor:           m.Floor,
+				LastConfirmedAt: m.LastConfirmedAt,
+			}
+			open, busy := openByMachine[m.ID]
+			switch {
+			case !busy:
+				entry.State = 1 // Default to configured idle status
+				entry.Message = "空闲"
+				entry.IsAvailable = true
+				entry.ExpectedAt = &now
+				response.AvailableNow++
+			case open.TimeRemaining > 0:
+				entry.State = open.Status
+				entry.Message = open.Message
+				predicted := open.ObservedAt.Add(time.Duration(open.TimeRemaining) * time.Second)
+				expected := predicted.Add(bias.Median)
+				if expected.Before(now) {
+					expected = now
+					entry.Overdue = true
+				}
+				entry.PredictedAt = &predicted
+				entry.ExpectedAt = &expected
+			default:
+				// 故障等无预计结束时间的状态，无法估计
+				entry.State = open.Status
+				entry.Message = open.Message
+			}
+			response.Machines = append(response.Machines, entry)
+		}
+
+		// 空闲的在前，其次按预计空出时间，无法估计的排最后；同一时间优先本层
+		sort.SliceStable(response.Machines, func(i, j int) bool {
+			a, b := response.Machines[i], response.Machines[j]
+			if (a.ExpectedAt == nil) != (b.ExpectedAt == nil) {
+				return b.ExpectedAt == nil
+			}
+			if a.ExpectedAt != nil && !a.ExpectedAt.Equal(*b.ExpectedAt) {
+				return a.ExpectedAt.Before(*b.ExpectedAt)
+			}
+			return abs(a.Floor-floor) < abs(b.Floor-floor)
+		})
+
+		if len(response.Machines) > 0 && response.Machines[0].ExpectedAt != nil {
+			earliest := response.Machines[0]
+			wait := int(earliest.ExpectedAt.Sub(now).Seconds())
+			response.Earliest = &earliest
+			response.WaitSeconds = &wait
+		}
+		mw.Respond(c, http.StatusOK, response)
+	}
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
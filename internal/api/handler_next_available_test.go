@@ -0,0 +1,79 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"laundry-status-backend/config"
+	"laundry-status-backend/internal/model"
+)
+
+func TestGetNextAvailable(t *testing.T) {
+	testDB := newTestDB(t)
+	now := time.Now().UTC()
+	require.NoError(t, testDB.Create(&model.Dorm{ID: 1, Name: "东3"}).Error)
+	require.NoError(t, testDB.Create(&[]model.Machine{
+		{ID: 11, DormID: 1, DisplayName: "东3#3-1", Floor: 3, Seq: 1},
+		{ID: 12, DormID: 1, DisplayName: "东3#3-2", Floor: 3, Seq: 2},
+		{ID: 13, DormID: 1, DisplayName: "东3#4-1", Floor: 4, Seq: 1},
+		{ID: 14, DormID: 1, DisplayName: "东3#5-1", Floor: 5, Seq: 1},
+	}).Error)
+	require.NoError(t, testDB.Create(&[]model.OccupancyOpen{
+		{MachineID: 11, Status: 2, Message: "使用中", ObservedAt: now.Add(-10 * time.Minute), TimeRemaining: 40 * 60},
+		{MachineID: 12, Status: 3, Message: "故障", ObservedAt: now.Add(-time.Hour)},
+		{MachineID: 13, Status: 2, Message: "使用中", ObservedAt: now.Add(-10 * time.Minute), TimeRemaining: 20 * 60},
+	}).Error)
+
+	cfg := &config.Config{Scraper: config.ScraperConfig{StateOccupiedValues: []int{2}}}
+	r := gin.New()
+	r.GET("/api/dorms/:dorm_id/floors/:floor/next-available", GetNextAvailable(testDB, cfg))
+
+	get := func(path string) (int, nextAvailableResponse) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		r.ServeHTTP(w, req)
+		var resp nextAvailableResponse
+		if w.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		}
+		return w.Code, resp
+	}
+
+	code, resp := get("/api/dorms/1/floors/3/next-available")
+	require.Equal(t, http.StatusOK, code)
+	assert.Zero(t, resp.AvailableNow)
+	require.NotNil(t, resp.Earliest)
+	assert.Equal(t, int64(11), resp.Earliest.MachineID)
+	require.NotNil(t, resp.WaitSeconds)
+	assert.InDelta(t, 30*60, *resp.WaitSeconds, 5)
+	require.Len(t, resp.Machines, 2)
+	assert.Nil(t, resp.Machines[1].ExpectedAt, "faulty machine has no estimate")
+	assert.Equal(t, "故障", resp.Machines[1].Message)
+
+	// A nearby floor frees up sooner; a free machine two floors up wins outright.
+	_, resp = get("/api/dorms/1/floors/3/next-available?radius=1")
+	assert.Equal(t, int64(13), resp.Earliest.MachineID)
+	_, resp = get("/api/dorms/1/floors/3/next-available?radius=2")
+	assert.Equal(t, 1, resp.AvailableNow)
+	assert.Equal(t, int64(14), resp.Earliest.MachineID)
+	assert.Equal(t, 0, *resp.WaitSeconds)
+
+	// Only faulty machines: no estimate at all.
+	require.NoError(t, testDB.Delete(&model.OccupancyOpen{}, 11).Error)
+	require.NoError(t, testDB.Create(&model.OccupancyOpen{MachineID: 11, Status: 3, ObservedAt: now}).Error)
+	_, resp = get("/api/dorms/1/floors/3/next-available")
+	assert.Nil(t, resp.Earliest)
+	assert.Nil(t, resp.WaitSeconds)
+
+	code, _ = get("/api/dorms/1/floors/x/next-available")
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = get("/api/dorms/9/floors/3/next-available")
+	assert.Equal(t, http.StatusNotFound, code)
+}
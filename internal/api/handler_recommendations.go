@@ -0,0 +1,210 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"laundry-status-backend/config"
+	"laundry-status-backend/internal/model"
+	"laundry-status-backend/internal/mw"
+	"laundry-status-backend/internal/stats"
+	"laundry-status-backend/internal/store"
+)
+
+const (
+	recommendationStep          = 15 * time.Minute
+	recommendationHistoryWeeks  = 4
+	defaultRecommendationLimit  = 5
+	maxRecommendationLimit      = 20
+	maxRecommendationFloorRange = 3
+)
+
+type recommendationWindow struct {
+	Start       time.Time `json:"start"`
+	End         time.Time `json:"end"`
+	Probability float64   `json:"probability"`
+	Confidence  float64   `json:"confidence"`
+}
+
+type recommendationsResponse struct {
+	DormID          int64                  `json:"dormId"`
+	Floors          []int                  `json:"floors"` // null when the whole dorm is considered
+	DurationSeconds int                    `json:"durationSeconds"`
+	WithinSeconds   int                    `json:"withinSeconds"`
+	GeneratedAt     time.Time              `json:"generatedAt"`
+	Machines        int                    `json:"machines"`
+	Recommendations []recommendationWindow `json:"recommendations"`
+}
+
+// GetRecommendations handles the GET /api/dorms/{dorm_id}/recommendations request.
+// With ?floor= only that floor and the floors within ?radius= (default 1) are
+// considered.
+func GetRecommendations(db *gorm.DB, cfg *config.Config) gin.HandlerFunc {
+	loc := loadLocation(cfg.Scraper.Timezone)
+
+	return func(c *gin.Context) {
+		dormID, err := strconv.ParseInt(c.Param("dorm_id"), 10, 64)
+		if err != nil {
+			mw.AbortWithError(c, http.StatusBadRequest, mw.CodeInvalidParameter, "Invalid dorm ID")
+			return
+		}
+
+		duration, ok := durationQuery(c, "duration", 45*time.Minute, time.Minute, 6*time.Hour)
+		if !ok {
+			return
+		}
+		within, ok := durationQuery(c, "within", 24*time.Hour, duration, 7*24*time.Hour)
+		if !ok {
+			return
+		}
+		limit, ok := intQuery(c, "limit", defaultRecommendationLimit, 1, maxRecommendationLimit)
+		if !ok {
+			return
+		}
+		radius, ok := intQuery(c, "radius", 1, 0, maxRecommendationFloorRange)
+		if !ok {
+			return
+		}
+		var floors []int
+		if raw := c.Query("floor"); raw != "" {
+			floor, err := strconv.Atoi(raw)
+			if err != nil {
+				mw.AbortWithError(c, http.StatusBadRequest, mw.CodeInvalidParameter, "Invalid floor")
+				return
+			}
+			for f := floor - radius; f <= floor+radius; f++ {
+				floors = append(floors, f)
+			}
+		}
+
+		if !dormExists(c, db, dormID) {
+			return
+		}
+
+		query := stats.Query{
+			DormID:    dormID,
+			Kind:      c.Query("kind"),
+			Floors:    floors,
+			Statuses:  cfg.Scraper.StateOccupiedValues,
+			Location:  loc,
+			Timescale: cfg.Database.EnableTimescale,
+		}
+
+		machines, err := currentMachineStates(db, query)
+		if err != nil {
+			slog.ErrorContext(c.Request.Context(), "Error loading machine states", "dorm_id", dormID, "error", err)
+			mw.AbortWithError(c, http.StatusInternalServerError, mw.CodeInternal, "Failed to retrieve machines")
+			return
+		}
+
+		now := time.Now().UTC()
+		query.To = now.Truncate(time.Hour)
+		query.From = query.To.Add(-recommendationHistoryWeeks * 7 * 24 * time.Hour)
+		query.RawSince = rawHistorySince(cfg, now)
+		util, err := stats.DormUtilization(c.Request.Context(), db, query)
+		if err != nil {
+			slog.ErrorContext(c.Request.Context(), "Error computing utilization", "dorm_id", dormID, "error", err)
+			mw.AbortWithError(c, http.StatusInternalServerError, mw.CodeInternal, "Failed to compute recommendations")
+			return
+		}
+
+		windows := stats.Recommend(machines, util, stats.RecommendOptions{
+			Now:      now,
+			Duration: duration,
+			Within:   within,
+			Step:     recommendationStep,
+			Limit:    limit,
+			Location: loc,
+		})
+
+		response := recommendationsResponse{
+			DormID:          dormID,
+			Floors:          floors,
+			DurationSeconds: int(duration.Seconds()),
+			WithinSeconds:   int(within.Seconds()),
+			GeneratedAt:     now,
+			Machines:        len(machines),
+			Recommendations: make([]recommendationWindow, 0, len(windows)),
+		}
+		for _, w := range windows {
+			response.Recommendations = append(response.Recommendations, recommendationWindow(w))
+		}
+		mw.Respond(c, http.StatusOK, response)
+	}
+}
+
+// currentMachineStates loads the live state of the machines selected by q.
+func currentMachineStates(db *gorm.DB, q stats.Query) ([]stats.MachineState, error) {
+	machineQuery := db.Scopes(store.VisibleMachines).Where("dorm_id = ?", q.DormID)
+	if q.Kind != "" {
+		machineQuery = machineQuery.Where("kind = ?", q.Kind)
+	}
+	if len(q.Floors) > 0 {
+		machineQuery = machineQuery.Where("floor IN ?", q.Floors)
+	}
+	var machines []model.Machine
+	if err := machineQuery.Find(&machines).Error; err != nil {
+		return nil, err
+	}
+
+	machineIDs := make([]int64, len(machines))
+	for i, m := range machines {
+		machineIDs[i] = m.ID
+	}
+	var opens []model.OccupancyOpen
+	if err := db.Where("machine_id IN ?", machineIDs).Find(&opens).Error; err != nil {
+		return nil, err
+	}
+	openByMachine := make(map[int64]model.OccupancyOpen, len(opens))
+	for _, o := range opens {
+		openByMachine[o.MachineID] = o
+	}
+
+	states := make([]stats.MachineState, 0, len(machines))
+	for _, m := range machines {
+		state := stats.MachineState{MachineID: m.ID, Floor: m.Floor, Available: true}
+		if open, ok := openByMachine[m.ID]; ok {
+			state.Available = false
+			if open.TimeRemaining > 0 {
+				freeAt := open.ObservedAt.Add(time.Duration(open.TimeRemaining) * time.Second)
+				state.FreeAt = &freeAt
+			}
+		}
+		states = append(states, state)
+	}
+	return states, nil
+}
+
+// durationQuery parses a Go duration query parameter such as "45m", writing a
+// 400 response and returning false when it is invalid or out of range.
+func durationQuery(c *gin.Context, name string, def, min, max time.Duration) (time.Duration, bool) {
+	raw := c.Query(name)
+	if raw == "" {
+		return def, true
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil || d < min || d > max {
+		mw.AbortWithError(c, http.StatusBadRequest, mw.CodeInvalidParameter, "'"+name+"' must be a duration between "+min.String()+" and "+max.String())
+		return 0, false
+	}
+	return d, true
+}
+
+// intQuery parses an integer query parameter within [min, max].
+func intQuery(c *gin.Context, name string, def, min, max int) (int, bool) {
+	raw := c.Query(name)
+	if raw == "" {
+		return def, true
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < min || n > max {
+		mw.AbortWithError(c, http.StatusBadRequest, mw.CodeInvalidParameter, "'"+name+"' must be between "+strconv.Itoa(min)+" and "+strconv.Itoa(max))
+		return 0, false
+	}
+	return n, true
+}
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"laundry-status-backend/config"
+	"laundry-status-backend/internal/model"
+)
+
+func TestGetRecommendations(t *testing.T) {
+	testDB := newTestDB(t)
+	require.NoError(t, testDB.Create(&model.Dorm{ID: 1, Name: "东3"}).Error)
+	require.NoError(t, testDB.Create(&[]model.Machine{
+		{ID: 11, DormID: 1, Floor: 3, Kind: model.MachineKindWasher},
+		{ID: 12, DormID: 1, Floor: 4, Kind: model.MachineKindWasher},
+		{ID: 13, DormID: 1, Floor: 6, Kind: model.MachineKindWasher},
+	}).Error)
+	require.NoError(t, testDB.Create(&model.OccupancyOpen{
+		MachineID: 11, Status: 2, ObservedAt: time.Now().UTC(), TimeRemaining: 1800,
+	}).Error)
+
+	cfg := &config.Config{Scraper: config.ScraperConfig{StateOccupiedValues: []int{2}}}
+	r := gin.New()
+	r.GET("/api/dorms/:dorm_id/recommendations", GetRecommendations(testDB, cfg))
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := serve("/api/dorms/1/recommendations?duration=30m&within=3h&floor=3&limit=2")
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp recommendationsResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, []int{2, 3, 4}, resp.Floors)
+	assert.Equal(t, 2, resp.Machines, "floor 6 is out of range")
+	assert.Equal(t, 1800, resp.DurationSeconds)
+	require.Len(t, resp.Recommendations, 2)
+	for _, rec := range resp.Recommendations {
+		assert.Equal(t, 30*time.Minute, rec.End.Sub(rec.Start))
+		assert.False(t, rec.End.After(resp.GeneratedAt.Add(3*time.Hour)))
+	}
+
+	assert.Equal(t, http.StatusBadRequest, serve("/api/dorms/1/recommendations?duration=soon").Code)
+	assert.Equal(t, http.StatusBadRequest, serve("/api/dorms/1/recommendations?duration=2h&within=1h").Code)
+	assert.Equal(t, http.StatusNotFound, serve("/api/dorms/9/recommendations").Code)
+}
@@ -0,0 +1,224 @@
+package api
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"laundry-status-backend/config"
+	"laundry-status-backend/internal/model"
+	"laundry-status-backend/internal/mw"
+	"laundry-status-backend/internal/search"
+	"laundry-status-backend/internal/store"
+)
+
+const (
+	defaultSearchLimit = 50
+	maxSearchLimit     = 200
+	maxSearchFloor     = 200
+)
+
+type machineSearchResult struct {
+	MachineID     int64      `json:"machineId"`
+	DisplayName   string     `json:"displayName"`
+	DormID        int64      `json:"dormId"`
+	DormName      string     `json:"dormName"`
+	Floor         int        `json:"floor"`
+	Kind          string     `json:"kind"`
+	State         int        `json:"state"`
+	IsAvailable   bool       `json:"isAvailable"`
+	Message       string     `json:"message"`
+	TimeRemaining int        `json:"timeRemaining"`
+	FinishTime    *time.Time `json:"finishTime"`
+
+	LastConfirmedAt *time.Time `json:"lastConfirmedAt"` // Last scrape that included the machine
+
+	score        int
+	dormDistance int
+	floorDist    int
+}
+
+type machineSearchResponse struct {
+	Query     string                `json:"query"`
+	Total     int                   `json:"total"` // Matches before the limit is applied
+	Machines  []machineSearchResult `json:"machines"`
+	Freshness freshnessResponse     `json:"freshness"`
+}
+
+// SearchMachines handles the GET /api/machines/search request. q is matched
+// fuzzily against dorm and display names, including their pinyin and pinyin
+// initials; dorm and floor only rank results by closeness, while available,
+// kind, floor_min and floor_max filter them.
+func SearchMachines(db *gorm.DB, cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		terms := search.Terms(c.Query("q"))
+
+		var availableOnly bool
+		if raw := c.Query("available"); raw != "" {
+			v, err := strconv.ParseBool(raw)
+			if err != nil {
+				mw.AbortWithError(c, http.StatusBadRequest, mw.CodeInvalidParameter, "'available' must be true or false")
+				return
+			}
+			availableOnly = v
+		}
+		limit, ok := intQuery(c, "limit", defaultSearchLimit, 1, maxSearchLimit)
+		if !ok {
+			return
+		}
+
+		machineQuery := db.Preload("Dorm").Scopes(store.VisibleMachines)
+		if kind := c.Query("kind"); kind != "" {
+			machineQuery = machineQuery.Where("kind = ?", kind)
+		}
+		if c.Query("floor_min") != "" {
+			floorMin, ok := intQuery(c, "floor_min", 0, -maxSearchFloor, maxSearchFloor)
+			if !ok {
+				return
+			}
+			machineQuery = machineQuery.Where("floor >= ?", floorMin)
+		}
+		if c.Query("floor_max") != "" {
+			floorMax, ok := intQuery(c, "floor_max", 0, -maxSearchFloor, maxSearchFloor)
+			if !ok {
+				return
+			}
+			machineQuery = machineQuery.Where("floor <= ?", floorMax)
+		}
+
+		// Reference point for ranking
+		var near *model.Dorm
+		if raw := c.Query("dorm"); raw != "" {
+			dormID, err := strconv.ParseInt(raw, 10, 64)
+			if err != nil {
+				mw.AbortWithError(c, http.StatusBadRequest, mw.CodeInvalidParameter, "Invalid dorm ID")
+				return
+			}
+			var dorm model.Dorm
+			if err := db.First(&dorm, dormID).Error; err == gorm.ErrRecordNotFound {
+				mw.AbortWithError(c, http.StatusNotFound, mw.CodeDormNotFound, "Dorm not found")
+				return
+			} else if err != nil {
+				mw.AbortWithError(c, http.StatusInternalServerError, mw.CodeInternal, "Failed to retrieve dorm")
+				return
+			}
+			near = &dorm
+		}
+		nearFloor, hasFloor := 0, c.Query("floor") != ""
+		if hasFloor {
+			if nearFloor, ok = intQuery(c, "floor", 0, -maxSearchFloor, maxSearchFloor); !ok {
+				return
+			}
+		}
+
+		var machines []model.Machine
+		if err := machineQuery.Find(&machines).Error; err != nil {
+			mw.AbortWithError(c, http.StatusInternalServerError, mw.CodeInternal, "Failed to retrieve machines")
+			return
+		}
+		machineIDs := make([]int64, len(machines))
+		for i, m := range machines {
+			machineIDs[i] = m.ID
+		}
+		var opens []model.OccupancyOpen
+		if err := db.Where("machine_id IN ?", machineIDs).Find(&opens).Error; err != nil {
+			mw.AbortWithError(c, http.StatusInternalServerError, mw.CodeInternal, "Failed to retrieve machine status")
+			return
+		}
+		openByMachine := make(map[int64]model.OccupancyOpen, len(opens))
+		for _, o := range opens {
+			openByMachine[o.MachineID] = o
+		}
+
+		freshness, ok := loadFreshness(c, db, cfg.Scraper.StaleAfter, time.Now().UTC())
+		if !ok {
+			return
+		}
+
+		results := make([]machineSearchResult, 0)
+		for _, m := range machines {
+			score := search.ScoreNone
+			if len(terms) > 0 {
+				// 宿舍名和机器名可以混合匹配，如 "d3 1-2"
+				if score = search.MatchAll(terms, search.KeyOf(m.Dorm.Name), search.KeyOf(m.DisplayName)); score == search.ScoreNone {
+					continue
+				}
+			}
+			open, busy := openByMachine[m.ID]
+			if availableOnly && busy {
+				continue
+			}
+
+			result := machineSearchResult{
+				MachineID:   m.ID,
+				DisplayName: m.DisplayName,
+				DormID:      m.DormID,
+				DormName:    m.Dorm.Name,
+				Floor:       m.Floor,
+				Kind:        m.Kind,
+				State:       1, // Default to configured idle status
+				IsAvailable: !busy,
+				Message:     "空闲",
+
+				LastConfirmedAt: m.LastConfirmedAt,
+				score:           score,
+			}
+			if busy {
+				result.State = open.Status
+				result.Message = open.Message
+				result.TimeRemaining = open.TimeRemaining
+				if open.TimeRemaining > 0 {
+					ft := open.ObservedAt.Add(time.Duration(open.TimeRemaining) * time.Second)
+					result.FinishTime = &ft
+				}
+			}
+			if near != nil {
+				result.dormDistance = search.DormDistance(near.Name, m.Dorm.Name)
+				if m.DormID == near.ID {
+					result.dormDistance = 0
+				}
+			}
+			if hasFloor {
+				result.floorDist = abs(m.Floor - nearFloor)
+			}
+			results = append(results, result)
+		}
+
+		// 空闲优先，其次离参考宿舍/楼层近的，再按预计结束时间和匹配程度
+		sort.SliceStable(results, func(i, j int) bool {
+			a, b := results[i], results[j]
+			if a.IsAvailable != b.IsAvailable {
+				return a.IsAvailable
+			}
+			if a.dormDistance != b.dormDistance {
+				return a.dormDistance < b.dormDistance
+			}
+			if a.floorDist != b.floorDist {
+				return a.floorDist < b.floorDist
+			}
+			if (a.FinishTime == nil) != (b.FinishTime == nil) {
+				return b.FinishTime == nil
+			}
+			if a.FinishTime != nil && !a.FinishTime.Equal(*b.FinishTime) {
+				return a.FinishTime.Before(*b.FinishTime)
+			}
+			if a.score != b.score {
+				return a.score > b.score
+			}
+			if a.DormName != b.DormName {
+				return a.DormName < b.DormName
+			}
+			return a.DisplayName < b.DisplayName
+		})
+
+		response := machineSearchResponse{Query: c.Query("q"), Total: len(results), Machines: results, Freshness: freshness}
+		if len(results) > limit {
+			response.Machines = results[:limit]
+		}
+		mw.Respond(c, http.StatusOK, response)
+	}
+}
@@ -0,0 +1,74 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"laundry-status-backend/config"
+	"laundry-status-backend/internal/model"
+)
+
+func TestSearchMachines(t *testing.T) {
+	testDB := newTestDB(t)
+	now := time.Now().UTC()
+	require.NoError(t, testDB.Create(&[]model.Dorm{{ID: 1, Name: "东3"}, {ID: 2, Name: "东5"}, {ID: 3, Name: "西1"}}).Error)
+	require.NoError(t, testDB.Create(&[]model.Machine{
+		{ID: 11, DormID: 1, DisplayName: "东3#1-1", Floor: 1, Kind: model.MachineKindWasher},
+		{ID: 12, DormID: 1, DisplayName: "东3#4-1", Floor: 4, Kind: model.MachineKindWasher},
+		{ID: 21, DormID: 2, DisplayName: "东5#1-1", Floor: 1, Kind: model.MachineKindWasher},
+		{ID: 22, DormID: 2, DisplayName: "东5#2-烘干", Floor: 2, Kind: model.MachineKindDryer},
+		{ID: 31, DormID: 3, DisplayName: "西1#1-1", Floor: 1, Kind: model.MachineKindWasher},
+		{ID: 32, DormID: 3, DisplayName: "西1#1-2", Floor: 1, Kind: model.MachineKindWasher},
+	}).Error)
+	require.NoError(t, testDB.Create(&model.MachineOverride{MachineID: 32, Hidden: true}).Error)
+	require.NoError(t, testDB.Create(&model.OccupancyOpen{MachineID: 11, ObservedAt: now, Status: 2, Message: "使用中", TimeRemaining: 600}).Error)
+
+	r := gin.New()
+	r.GET("/api/machines/search", SearchMachines(testDB, &config.Config{}))
+	get := func(query string) (int, machineSearchResponse) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/api/machines/search?"+query, nil)
+		r.ServeHTTP(w, req)
+		var resp machineSearchResponse
+		if w.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		}
+		return w.Code, resp
+	}
+	ids := func(resp machineSearchResponse) []int64 {
+		var ids []int64
+		for _, m := range resp.Machines {
+			ids = append(ids, m.MachineID)
+		}
+		return ids
+	}
+
+	code, resp := get("q=d&dorm=1&floor=1")
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []int64{12, 21, 22, 11}, ids(resp),
+		"available machines first, then by dorm and floor distance")
+	assert.Equal(t, "东3", resp.Machines[0].DormName)
+	require.NotNil(t, resp.Machines[3].FinishTime)
+
+	_, resp = get("q=x1")
+	assert.Equal(t, []int64{31}, ids(resp), "pinyin initials match, hidden machines do not")
+
+	_, resp = get("available=true&kind=washer&floor_max=1&limit=1")
+	assert.Equal(t, 2, resp.Total)
+	assert.Equal(t, []int64{21}, ids(resp))
+
+	_, resp = get("q=hg")
+	assert.Equal(t, []int64{22}, ids(resp))
+
+	code, _ = get("dorm=99")
+	assert.Equal(t, http.StatusNotFound, code)
+	code, _ = get("available=maybe")
+	assert.Equal(t, http.StatusBadRequest, code)
+}
@@ -0,0 +1,142 @@
+package api
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"laundry-status-backend/config"
+	"laundry-status-backend/internal/model"
+	"laundry-status-backend/internal/mw"
+	"laundry-status-backend/internal/stats"
+)
+
+const (
+	defaultUtilizationWeeks = 4
+	maxUtilizationWeeks     = 26
+)
+
+type utilizationSlot struct {
+	DayOfWeek   int     `json:"dayOfWeek"` // 0 = Sunday
+	Hour        int     `json:"hour"`
+	Utilization float64 `json:"utilization"`
+}
+
+type utilizationResponse struct {
+	DormID   int64             `json:"dormId"`
+	Kind     string            `json:"kind,omitempty"`
+	Weeks    int               `json:"weeks"`
+	From     time.Time         `json:"from"`
+	To       time.Time         `json:"to"`
+	Timezone string            `json:"timezone"`
+	Machines int64             `json:"machines"`
+	Slots    []utilizationSlot `json:"slots"`
+}
+
+// GetUtilization handles the GET /api/dorms/{dorm_id}/stats/utilization request.
+// It reports, per hour-of-week, the average fraction of machines occupied over
+// the last complete weeks.
+func GetUtilization(db *gorm.DB, cfg *config.Config) gin.HandlerFunc {
+	loc := loadLocation(cfg.Scraper.Timezone)
+
+	return func(c *gin.Context) {
+		dormID, err := strconv.ParseInt(c.Param("dorm_id"), 10, 64)
+		if err != nil {
+			mw.AbortWithError(c, http.StatusBadRequest, mw.CodeInvalidParameter, "Invalid dorm ID")
+			return
+		}
+
+		weeks := defaultUtilizationWeeks
+		if raw := c.Query("weeks"); raw != "" {
+			weeks, err = strconv.Atoi(raw)
+			if err != nil || weeks <= 0 || weeks > maxUtilizationWeeks {
+				mw.AbortWithError(c, http.StatusBadRequest, mw.CodeInvalidParameter, "'weeks' must be between 1 and "+strconv.Itoa(maxUtilizationWeeks))
+				return
+			}
+		}
+
+		if !dormExists(c, db, dormID) {
+			return
+		}
+
+		// 只统计已经完整结束的小时
+		to := time.Now().UTC().Truncate(time.Hour)
+		from := to.Add(-time.Duration(weeks) * 7 * 24 * time.Hour)
+		kind := c.Query("kind")
+
+		util, err := stats.DormUtilization(c.Request.Context(), db, stats.Query{
+			DormID:    dormID,
+			Kind:      kind,
+			Statuses:  cfg.Scraper.StateOccupiedValues,
+			From:      from,
+			To:        to,
+			Location:  loc,
+			Timescale: cfg.Database.EnableTimescale,
+			RawSince:  rawHistorySince(cfg, time.Now().UTC()),
+		})
+		if err != nil {
+			slog.ErrorContext(c.Request.Context(), "Error computing utilization", "dorm_id", dormID, "error", err)
+			mw.AbortWithError(c, http.StatusInternalServerError, mw.CodeInternal, "Failed to compute utilization")
+			return
+		}
+
+		response := utilizationResponse{
+			DormID:   dormID,
+			Kind:     kind,
+			Weeks:    weeks,
+			From:     from,
+			To:       to,
+			Timezone: loc.String(),
+			Machines: util.Machines,
+			Slots:    make([]utilizationSlot, 0, stats.HoursPerWeek),
+		}
+		for slot, fraction := range util.Slots {
+			response.Slots = append(response.Slots, utilizationSlot{
+				DayOfWeek:   slot / 24,
+				Hour:        slot % 24,
+				Utilization: fraction,
+			})
+		}
+		mw.Respond(c, http.StatusOK, response)
+	}
+}
+
+// dormExists writes a 404 (or 500) response and returns false when the dorm
+// cannot be found.
+func dormExists(c *gin.Context, db *gorm.DB, dormID int64) bool {
+	var dorm model.Dorm
+	err := db.Select("id").First(&dorm, dormID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		mw.AbortWithError(c, http.StatusNotFound, mw.CodeDormNotFound, "Dorm not found")
+		return false
+	}
+	if err != nil {
+		mw.AbortWithError(c, http.StatusInternalServerError, mw.CodeInternal, "Failed to retrieve dorm")
+		return false
+	}
+	return true
+}
+
+// loadLocation returns the configured timezone, falling back to UTC.
+func loadLocation(name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		slog.Warn("Invalid timezone, using UTC", "timezone", name, "error", err)
+		return time.UTC
+	}
+	return loc
+}
+
+// rawHistorySince returns the retention cutoff of raw occupancy history at
+// now, or zero if raw history is kept forever.
+func rawHistorySince(cfg *config.Config, now time.Time) time.Time {
+	if days := cfg.Database.Retention.HistoryDays; days > 0 {
+		return now.Add(-time.Duration(days) * 24 * time.Hour)
+	}
+	return time.Time{}
+}
@@ -0,0 +1,46 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"laundry-status-backend/config"
+	"laundry-status-backend/internal/model"
+)
+
+func TestGetUtilization(t *testing.T) {
+	testDB := newTestDB(t)
+	require.NoError(t, testDB.Create(&model.Dorm{ID: 1, Name: "东3"}).Error)
+	require.NoError(t, testDB.Create(&model.Machine{ID: 11, DormID: 1, Floor: 1, Kind: model.MachineKindWasher}).Error)
+
+	cfg := &config.Config{Scraper: config.ScraperConfig{Timezone: "Asia/Shanghai", StateOccupiedValues: []int{2}}}
+	r := gin.New()
+	r.GET("/api/dorms/:dorm_id/stats/utilization", GetUtilization(testDB, cfg))
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := serve("/api/dorms/1/stats/utilization?weeks=2")
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp utilizationResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, 2, resp.Weeks)
+	assert.Equal(t, "Asia/Shanghai", resp.Timezone)
+	assert.Equal(t, int64(1), resp.Machines)
+	require.Len(t, resp.Slots, 168)
+	assert.Equal(t, 6, resp.Slots[167].DayOfWeek)
+	assert.Equal(t, 23, resp.Slots[167].Hour)
+
+	assert.Equal(t, http.StatusBadRequest, serve("/api/dorms/1/stats/utilization?weeks=0").Code)
+	assert.Equal(t, http.StatusNotFound, serve("/api/dorms/9/stats/utilization").Code)
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"log/slog"
 	"net/http"
 	"strconv"
 	"time"
@@ -8,23 +9,37 @@ import (
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 
+	"laundry-status-backend/config"
 	"laundry-status-backend/internal/model"
+	"laundry-status-backend/internal/mw"
+	"laundry-status-backend/internal/store"
 )
 
 // GetMachineStatus handles the GET /api/dorms/{dorm_id}/machines request.
-func GetMachineStatus(db *gorm.DB) gin.HandlerFunc {
+func GetMachineStatus(db *gorm.DB, cfg *config.Config) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		dormID, err := strconv.ParseInt(c.Param("dorm_id"), 10, 64)
 		if err != nil {
-			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid dorm ID"})
+			mw.AbortWithError(c, http.StatusBadRequest, mw.CodeInvalidParameter, "Invalid dorm ID")
+			return
+		}
+
+		// Optional machine kind filter, e.g. ?kind=dryer
+		machines := db.Preload("Dorm").Scopes(store.VisibleMachines).Where("dorm_id = ?", dormID)
+		if kind := c.Query("kind"); kind != "" {
+			machines = machines.Where("kind = ?", kind)
+		}
+
+		freshness, ok := loadFreshness(c, db, cfg.Scraper.StaleAfter, time.Now().UTC())
+		if !ok {
 			return
 		}
 
 		atParam := c.Query("at")
 		if atParam == "" {
-			getCurrentStatus(c, db, dormID)
+			getCurrentStatus(c, db, machines, freshness)
 		} else {
-			getHistoricalStatus(c, db, dormID, atParam)
+			getHistoricalStatus(c, db, machines, atParam, cfg.Database.EnableTimescale)
 		}
 	}
 }
@@ -40,10 +55,48 @@ type machineStatusResponse struct {
 	ObservedAt    time.Time  `json:"observedAt"`
 }
 
-func getCurrentStatus(c *gin.Context, db *gorm.DB, dormID int64) {
+// machineStatusResponseV2 is machineStatusResponse in the /api/v2 format,
+// which names the machine fields like the search results do.
+type machineStatusResponseV2 struct {
+	MachineID       int64      `json:"machineId"`
+	DisplayName     string     `json:"displayName"`
+	DormID          int64      `json:"dormId"`
+	DormName        string     `json:"dormName"`
+	FloorCode       string     `json:"floorCode"`
+	Floor           int        `json:"floor"`
+	Seq             int        `json:"seq"`
+	Kind            string     `json:"kind"`
+	LastConfirmedAt *time.Time `json:"lastConfirmedAt"`
+	State           int        `json:"state"`
+	IsAvailable     bool       `json:"isAvailable"`
+	Message         string     `json:"message"`
+	TimeRemaining   int        `json:"timeRemaining"`
+	FinishTime      *time.Time `json:"finishTime"`
+	ObservedAt      time.Time  `json:"observedAt"`
+}
+
+// respondMachineStatus writes the status list in the request's API format.
+func respondMachineStatus(c *gin.Context, response []machineStatusResponse) {
+	if mw.Version(c) < 2 {
+		c.JSON(http.StatusOK, response)
+		return
+	}
+	v2 := make([]machineStatusResponseV2, len(response))
+	for i, r := range response {
+		v2[i] = machineStatusResponseV2{
+			MachineID: r.ID, DisplayName: r.DisplayName, DormID: r.DormID, DormName: r.Dorm.Name,
+			FloorCode: r.FloorCode, Floor: r.Floor, Seq: r.Seq, Kind: r.Kind, LastConfirmedAt: r.LastConfirmedAt,
+			State: r.State, IsAvailable: r.IsAvailable, Message: r.Message,
+			TimeRemaining: r.TimeRemaining, FinishTime: r.FinishTime, ObservedAt: r.ObservedAt,
+		}
+	}
+	mw.Respond(c, http.StatusOK, v2)
+}
+
+func getCurrentStatus(c *gin.Context, db *gorm.DB, machineQuery *gorm.DB, freshness freshnessResponse) {
 	var machines []model.Machine
-	if err := db.Preload("Dorm").Where("dorm_id = ?", dormID).Find(&machines).Error; err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve machines"})
+	if err := machineQuery.Find(&machines).Error; err != nil {
+		mw.AbortWithError(c, http.StatusInternalServerError, mw.CodeInternal, "Failed to retrieve machines")
 		return
 	}
 
@@ -79,7 +132,7 @@ func getCurrentStatus(c *gin.Context, db *gorm.DB, dormID int64) {
 				ObservedAt:    status.ObservedAt,
 			})
 		} else {
-			// Machine is idle
+			// Machine is idle, as of the last scrape that saw it
 			response = append(response, machineStatusResponse{
 				Machine:       machine,
 				State:         1, // Default to configured idle status
@@ -87,61 +140,67 @@ func getCurrentStatus(c *gin.Context, db *gorm.DB, dormID int64) {
 				Message:       "空闲",
 				TimeRemaining: 0,
 				FinishTime:    nil,
-				ObservedAt:    time.Now().UTC(),
+				ObservedAt:    confirmedAt(machine, freshness),
 			})
 		}
 	}
-	c.JSON(http.StatusOK, response)
+	respondMachineStatus(c, response)
 }
 
-func getHistoricalStatus(c *gin.Context, db *gorm.DB, dormID int64, atParam string) {
+func getHistoricalStatus(c *gin.Context, db *gorm.DB, machineQuery *gorm.DB, atParam string, timescale bool) {
 	at, err := time.Parse(time.RFC3339, atParam)
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid 'at' timestamp format. Use RFC3339."})
+		mw.AbortWithError(c, http.StatusBadRequest, mw.CodeInvalidParameter, "Invalid 'at' timestamp format. Use RFC3339.")
 		return
 	}
 
 	var machines []model.Machine
-	if err := db.Preload("Dorm").Where("dorm_id = ?", dormID).Find(&machines).Error; err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve machines"})
+	if err := machineQuery.Find(&machines).Error; err != nil {
+		mw.AbortWithError(c, http.StatusInternalServerError, mw.CodeInternal, "Failed to retrieve machines")
 		return
 	}
 
-	var response []machineStatusResponse
+	machineIDs := make([]int64, len(machines))
+	for i, m := range machines {
+		machineIDs[i] = m.ID
+	}
+	states, err := store.MachineStatesAt(c.Request.Context(), db, machineIDs, at, timescale)
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "Historical status lookup failed", "error", err)
+		mw.AbortWithError(c, http.StatusInternalServerError, mw.CodeInternal, "Database error during historical lookup")
+		return
+	}
+
+	response := make([]machineStatusResponse, 0, len(machines))
 	for _, machine := range machines {
-		var history model.OccupancyHistory
-		// Find the last record before or at the given time
-		err := db.Preload("Dorm").Where("machine_id = ? AND observed_at <= ?", machine.ID, at).
-			Order("observed_at DESC").
-			First(&history).Error
-
-		if err == gorm.ErrRecordNotFound {
-			continue // No historical record for this machine at the given time
-		}
-		if err != nil {
-			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Database error during historical lookup"})
-			return
+		state, ok := states[machine.ID]
+		if !ok {
+			// No state covers the instant, so the machine was idle
+			response = append(response, machineStatusResponse{
+				Machine:     machine,
+				State:       1,
+				IsAvailable: true,
+				Message:     "空闲",
+				ObservedAt:  at,
+			})
+			continue
 		}
 
-		var finishTime *time.Time
 		var timeRemaining int
-		// If there is a predicted end time, calculate duration and set finish time.
-		if !history.PeriodEnd.IsZero() && history.PeriodEnd.After(history.PeriodStart) {
-			finishTime = &history.PeriodEnd
-			timeRemaining = int(history.PeriodEnd.Sub(history.PeriodStart).Seconds())
+		if state.PredictedAt != nil && state.PredictedAt.After(at) {
+			timeRemaining = int(state.PredictedAt.Sub(at).Seconds())
 		}
-
 		response = append(response, machineStatusResponse{
-			Machine:     machine,
-			State:       history.Status,
-			IsAvailable: history.Status == 1, // 1 is idle status
-			Message:     history.Message,
-			// For consistency with getCurrentStatus, ObservedAt should be the start of the state.
+			Machine:       machine,
+			State:         state.Status,
+			IsAvailable:   false, // Only idle machines are available, and idle states are not recorded.
+			Message:       state.Message,
 			TimeRemaining: timeRemaining,
-			FinishTime:    finishTime,
-			ObservedAt:    history.PeriodStart, // Show the start time of the state
+			FinishTime:    state.PredictedAt,
+			// For consistency with getCurrentStatus, ObservedAt is the start of the state.
+			ObservedAt: state.StartedAt,
 		})
 	}
 
-	c.JSON(http.StatusOK, response)
+	respondMachineStatus(c, response)
 }
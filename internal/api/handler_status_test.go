@@ -0,0 +1,105 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"laundry-status-backend/config"
+	"laundry-status-backend/internal/model"
+)
+
+func TestGetMachineStatus_At(t *testing.T) {
+	testDB := newTestDB(t)
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	require.NoError(t, testDB.Create(&model.Dorm{ID: 1, Name: "东3"}).Error)
+	require.NoError(t, testDB.Create(&[]model.Machine{
+		{ID: 11, DormID: 1, DisplayName: "东3#1-1", Floor: 1},
+		{ID: 12, DormID: 1, DisplayName: "东3#1-2", Floor: 1},
+		{ID: 13, DormID: 1, DisplayName: "东3#1-3", Floor: 1},
+		{ID: 14, DormID: 1, DisplayName: "东3#1-4", Floor: 1},
+		{ID: 15, DormID: 1, DisplayName: "东3#1-5", Floor: 1},
+	}).Error)
+	require.NoError(t, testDB.Create(&[]model.OccupancyHistory{
+		// 11 ran 11:40-12:10 with a prediction until 12:20
+		{MachineID: 11, Status: 2, Message: "使用中", PeriodStart: at.Add(-20 * time.Minute), PeriodEnd: at.Add(20 * time.Minute), ObservedAt: at.Add(10 * time.Minute)},
+		// 12 finished before the instant
+		{MachineID: 12, Status: 2, Message: "使用中", PeriodStart: at.Add(-time.Hour), PeriodEnd: at.Add(-20 * time.Minute), ObservedAt: at.Add(-10 * time.Minute)},
+		// 13 was faulty across the instant, without a prediction
+		{MachineID: 13, Status: 3, Message: "故障", PeriodStart: at.Add(-time.Hour), PeriodEnd: at.Add(time.Hour), ObservedAt: at.Add(time.Hour)},
+	}).Error)
+	require.NoError(t, testDB.Create(&[]model.OccupancyOpen{
+		// 14 started before the instant and is still running
+		{MachineID: 14, Status: 2, Message: "使用中", ObservedAt: at.Add(-5 * time.Minute), TimeRemaining: 1800},
+		// 12 started again after the instant
+		{MachineID: 12, Status: 2, Message: "使用中", ObservedAt: at.Add(time.Minute), TimeRemaining: 1800},
+	}).Error)
+
+	r := gin.New()
+	r.GET("/api/dorms/:dorm_id/machines", GetMachineStatus(testDB, &config.Config{}))
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/api/dorms/1/machines?at="+at.Format(time.RFC3339), nil)
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var resp []machineStatusResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp, 5, "every machine is reported")
+	byID := make(map[int64]machineStatusResponse)
+	for _, m := range resp {
+		byID[m.ID] = m
+	}
+
+	assert.Equal(t, 2, byID[11].State)
+	assert.Equal(t, 1200, byID[11].TimeRemaining)
+	require.NotNil(t, byID[11].FinishTime)
+	assert.True(t, at.Add(20*time.Minute).Equal(*byID[11].FinishTime))
+	assert.True(t, at.Add(-20*time.Minute).Equal(byID[11].ObservedAt))
+
+	assert.True(t, byID[12].IsAvailable, "idle between two sessions")
+	assert.Equal(t, 3, byID[13].State)
+	assert.Nil(t, byID[13].FinishTime)
+
+	assert.Equal(t, 2, byID[14].State, "open records that started before the instant count")
+	assert.Equal(t, 1500, byID[14].TimeRemaining)
+
+	assert.True(t, byID[15].IsAvailable, "machines without history are idle")
+	assert.Equal(t, "空闲", byID[15].Message)
+}
+
+func TestGetMachineStatus_Freshness(t *testing.T) {
+	testDB := newTestDB(t)
+	confirmed := time.Now().UTC().Add(-time.Hour).Truncate(time.Second)
+	require.NoError(t, testDB.Create(&model.Dorm{ID: 1, Name: "东3"}).Error)
+	require.NoError(t, testDB.Create(&[]model.Machine{
+		{ID: 11, DormID: 1, DisplayName: "东3#1-1", LastConfirmedAt: &confirmed},
+		{ID: 12, DormID: 1, DisplayName: "东3#1-2"},
+	}).Error)
+	lastScrape := confirmed.Add(time.Minute)
+	require.NoError(t, testDB.Create(&model.ScraperState{ID: model.ScraperStateID, LastAttemptAt: lastScrape, LastSuccessAt: &lastScrape}).Error)
+
+	cfg := &config.Config{}
+	cfg.Scraper.StaleAfter = 3 * time.Minute
+	r := gin.New()
+	r.GET("/api/dorms/:dorm_id/machines", GetMachineStatus(testDB, cfg))
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/api/dorms/1/machines", nil)
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	assert.Equal(t, "true", w.Header().Get("X-Data-Stale"), "the last scrape is an hour old")
+	assert.Equal(t, lastScrape.Format(time.RFC3339), w.Header().Get("X-Last-Scrape-At"))
+
+	var resp []machineStatusResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp, 2)
+	assert.True(t, confirmed.Equal(resp[0].ObservedAt), "idle machines are as old as their last confirmation")
+	assert.True(t, lastScrape.Equal(resp[1].ObservedAt))
+}
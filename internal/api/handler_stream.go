@@ -0,0 +1,147 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"laundry-status-backend/internal/live"
+	"laundry-status-backend/internal/mw"
+	"laundry-status-backend/internal/store"
+)
+
+// streamRetryMillis is the reconnection delay suggested to EventSource clients.
+const streamRetryMillis = 5000
+
+// machineChangeResponse is one machine's new state in a live update.
+type machineChangeResponse struct {
+	MachineID     int64      `json:"machineId"`
+	Floor         int        `json:"floor"`
+	State         int        `json:"state"`
+	IsAvailable   bool       `json:"isAvailable"`
+	Message       string     `json:"message"`
+	TimeRemaining int        `json:"timeRemaining"`
+	FinishTime    *time.Time `json:"finishTime"`
+	ObservedAt    time.Time  `json:"observedAt"`
+}
+
+type dormChangesResponse struct {
+	DormID     int64                   `json:"dormId"`
+	ObservedAt time.Time               `json:"observedAt"`
+	Machines   []machineChangeResponse `json:"machines"`
+}
+
+func newMachineChangeResponse(c store.MachineChange) machineChangeResponse {
+	var finishTime *time.Time
+	if c.TimeRemaining > 0 {
+		ft := c.ObservedAt.Add(time.Duration(c.TimeRemaining) * time.Second)
+		finishTime = &ft
+	}
+	return machineChangeResponse{
+		MachineID:     c.MachineID,
+		Floor:         c.Floor,
+		State:         c.Status,
+		IsAvailable:   c.Idle,
+		Message:       c.Message,
+		TimeRemaining: c.TimeRemaining,
+		FinishTime:    finishTime,
+		ObservedAt:    c.ObservedAt,
+	}
+}
+
+func newDormChangesResponse(e live.Event) dormChangesResponse {
+	machines := make([]machineChangeResponse, len(e.Changes))
+	for i, c := range e.Changes {
+		machines[i] = newMachineChangeResponse(c)
+	}
+	return dormChangesResponse{DormID: e.DormID, ObservedAt: e.At, Machines: machines}
+}
+
+// StreamDorm handles the GET /api/dorms/{dorm_id}/stream request, a
+// Server-Sent Events stream of the dorm's machine state changes. Clients
+// resuming with Last-Event-ID get the missed events replayed, or a "reset"
+// event when they are no longer available and the state must be reloaded.
+func StreamDorm(db *gorm.DB, events *live.Broker, heartbeat time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		dormID, err := strconv.ParseInt(c.Param("dorm_id"), 10, 64)
+		if err != nil {
+			mw.AbortWithError(c, http.StatusBadRequest, mw.CodeInvalidParameter, "Invalid dorm ID")
+			return
+		}
+
+		// EventSource 重连时带 Last-Event-ID 头，也允许用查询参数传入
+		lastEventID := c.GetHeader("Last-Event-ID")
+		if lastEventID == "" {
+			lastEventID = c.Query("lastEventId")
+		}
+		var lastID uint64
+		if lastEventID != "" {
+			if lastID, err = strconv.ParseUint(lastEventID, 10, 64); err != nil {
+				mw.AbortWithError(c, http.StatusBadRequest, mw.CodeInvalidParameter, "Invalid Last-Event-ID")
+				return
+			}
+		}
+
+		if !dormExists(c, db, dormID) {
+			return
+		}
+
+		sub, replay, complete, err := events.Subscribe(lastID, func(e live.Event) bool {
+			return e.DormID == dormID
+		})
+		if err != nil {
+			mw.AbortWithError(c, http.StatusServiceUnavailable, mw.CodeUnavailable, "Server is shutting down")
+			return
+		}
+		defer sub.Close()
+
+		header := c.Writer.Header()
+		header.Set("Content-Type", "text/event-stream")
+		header.Set("Cache-Control", "no-cache")
+		header.Set("Connection", "keep-alive")
+		header.Set("X-Accel-Buffering", "no") // Disable proxy buffering, e.g. nginx
+		c.Status(http.StatusOK)
+
+		w := c.Writer
+		fmt.Fprintf(w, "retry: %d\n\n", streamRetryMillis)
+		if !complete {
+			fmt.Fprintf(w, "id: %d\nevent: reset\ndata: {}\n\n", sub.Head())
+		} else {
+			for _, e := range replay {
+				writeChangesEvent(w, e)
+			}
+		}
+		w.Flush()
+
+		ticker := time.NewTicker(heartbeat)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-c.Request.Context().Done():
+				return
+			case e, ok := <-sub.Events():
+				if !ok {
+					// Dropped for falling behind, or shutting down; the client reconnects.
+					return
+				}
+				writeChangesEvent(w, e)
+				w.Flush()
+			case <-ticker.C:
+				io.WriteString(w, ": ping\n\n")
+				w.Flush()
+			}
+		}
+	}
+}
+
+func writeChangesEvent(w io.Writer, e live.Event) {
+	data, _ := json.Marshal(newDormChangesResponse(e))
+	fmt.Fprintf(w, "id: %d\nevent: changes\ndata: %s\n\n", e.ID, data)
+}
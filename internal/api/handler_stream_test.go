@@ -0,0 +1,134 @@
+package api
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"laundry-status-backend/internal/live"
+	"laundry-status-backend/internal/model"
+	"laundry-status-backend/internal/mw"
+	"laundry-status-backend/internal/store"
+)
+
+// readEvent reads one SSE message, skipping comments, as field -> value.
+func readEvent(t *testing.T, r *bufio.Reader) map[string]string {
+	fields := make(map[string]string)
+	for {
+		line, err := r.ReadString('\n')
+		require.NoError(t, err)
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			if len(fields) > 0 {
+				return fields
+			}
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			fields["comment"] = line
+			continue
+		}
+		name, value, _ := strings.Cut(line, ": ")
+		fields[name] = value
+	}
+}
+
+func TestStreamDorm(t *testing.T) {
+	testDB := newTestDB(t)
+	require.NoError(t, testDB.Create(&model.Dorm{ID: 1, Name: "东3"}).Error)
+
+	events := live.NewBroker(16, 4)
+	r := gin.New()
+	r.GET("/api/dorms/:dorm_id/stream", mw.ConnLimit(1), StreamDorm(testDB, events, 50*time.Millisecond))
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/api/dorms/1/stream")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	body := bufio.NewReader(resp.Body)
+	assert.Equal(t, "5000", readEvent(t, body)["retry"])
+
+	// A second concurrent stream from the same client is refused.
+	second, err := http.Get(server.URL + "/api/dorms/1/stream")
+	require.NoError(t, err)
+	second.Body.Close()
+	assert.Equal(t, http.StatusTooManyRequests, second.StatusCode)
+
+	// Heartbeats keep idle connections alive.
+	assert.Equal(t, ": ping", readEvent(t, body)["comment"])
+
+	observed := time.Now().UTC()
+	events.Publish(observed, []store.MachineChange{
+		{MachineID: 21, DormID: 2, Idle: true},
+		{MachineID: 11, DormID: 1, Floor: 3, Status: 2, Message: "使用中", TimeRemaining: 600, ObservedAt: observed},
+	})
+	event := readEvent(t, body)
+	for event["event"] == "" {
+		event = readEvent(t, body) // Skip heartbeats
+	}
+	assert.Equal(t, "changes", event["event"])
+	assert.Equal(t, "2", event["id"], "events are numbered across dorms")
+	assert.Contains(t, event["data"], `"machineId":11`)
+	assert.Contains(t, event["data"], `"isAvailable":false`)
+	assert.NotContains(t, event["data"], `"machineId":21`)
+
+	// Shutting the broker down ends the stream.
+	events.Close()
+	_, err = io.ReadAll(body)
+	assert.NoError(t, err)
+}
+
+func TestStreamDorm_Resume(t *testing.T) {
+	testDB := newTestDB(t)
+	require.NoError(t, testDB.Create(&model.Dorm{ID: 1, Name: "东3"}).Error)
+
+	events := live.NewBroker(2, 4)
+	defer events.Close()
+	for i := 0; i < 4; i++ {
+		events.Publish(time.Now(), []store.MachineChange{{MachineID: 11, DormID: 1}})
+	}
+
+	r := gin.New()
+	r.GET("/api/dorms/:dorm_id/stream", StreamDorm(testDB, events, time.Minute))
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	open := func(lastEventID string) (*http.Response, *bufio.Reader) {
+		req, _ := http.NewRequest(http.MethodGet, server.URL+"/api/dorms/1/stream", nil)
+		req.Header.Set("Last-Event-ID", lastEventID)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		body := bufio.NewReader(resp.Body)
+		readEvent(t, body) // retry
+		return resp, body
+	}
+
+	resp, body := open("3")
+	event := readEvent(t, body)
+	assert.Equal(t, "4", event["id"])
+	assert.Equal(t, "changes", event["event"])
+	resp.Body.Close()
+
+	resp, body = open("1")
+	event = readEvent(t, body)
+	assert.Equal(t, "reset", event["event"], "event 2 was evicted")
+	assert.Equal(t, "4", event["id"])
+	resp.Body.Close()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/api/dorms/9/stream", nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -1,10 +1,12 @@
 package api
 
 import (
+	"log/slog"
 	"net/http"
 	"strings"
 
 	"laundry-status-backend/internal/model"
+	"laundry-status-backend/internal/mw"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -18,11 +20,26 @@ type putSubscriptionRequest struct {
 	SubscribedMachines []int64 `json:"subscribed_machines"`
 }
 
+// putSubscriptionRequestV2 is putSubscriptionRequest with /api/v2 field names.
+type putSubscriptionRequestV2 struct {
+	Endpoint           string  `json:"endpoint" binding:"required"`
+	P256DH             string  `json:"p256dh" binding:"required"`
+	Auth               string  `json:"auth" binding:"required"`
+	SubscribedMachines []int64 `json:"subscribedMachines"`
+}
+
 // PutSubscription handles the creation or replacement of a subscription.
 func (h *Handler) PutSubscription(c *gin.Context) {
 	var req putSubscriptionRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if mw.Version(c) >= 2 {
+		var reqV2 putSubscriptionRequestV2
+		if err := c.ShouldBindJSON(&reqV2); err != nil {
+			abortInvalidBody(c, err)
+			return
+		}
+		req = putSubscriptionRequest(reqV2)
+	} else if err := c.ShouldBindJSON(&req); err != nil {
+		abortInvalidBody(c, err)
 		return
 	}
 
@@ -55,7 +72,8 @@ func (h *Handler) PutSubscription(c *gin.Context) {
 	})
 
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		slog.ErrorContext(c.Request.Context(), "Error saving subscription", "error", err)
+		mw.AbortWithError(c, http.StatusInternalServerError, mw.CodeInternal, "Failed to save subscription")
 		return
 	}
 
@@ -70,12 +88,13 @@ type deleteSubscriptionRequest struct {
 func (h *Handler) DeleteSubscription(c *gin.Context) {
 	var req deleteSubscriptionRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		abortInvalidBody(c, err)
 		return
 	}
 
 	if err := h.store.DB().Delete(&model.PushSubscription{Endpoint: req.Endpoint}).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		slog.ErrorContext(c.Request.Context(), "Error deleting subscription", "error", err)
+		mw.AbortWithError(c, http.StatusInternalServerError, mw.CodeInternal, "Failed to delete subscription")
 		return
 	}
 
@@ -95,16 +114,17 @@ func rawQueryParam(rawQuery, key string) (string, bool) {
 func (h *Handler) GetSubscription(c *gin.Context) {
 	raw, ok := rawQueryParam(c.Request.URL.RawQuery, "endpoint")
 	if !ok || raw == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "endpoint is required"})
+		mw.AbortWithError(c, http.StatusBadRequest, mw.CodeInvalidParameter, "endpoint is required")
 		return
 	}
 
 	var subscription model.PushSubscription
 	if err := h.store.DB().Preload("Machines").First(&subscription, "endpoint = ?", raw).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "subscription not found"})
+			mw.AbortWithError(c, http.StatusNotFound, mw.CodeSubscriptionNotFound, "subscription not found")
 		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			slog.ErrorContext(c.Request.Context(), "Error retrieving subscription", "error", err)
+			mw.AbortWithError(c, http.StatusInternalServerError, mw.CodeInternal, "Failed to retrieve subscription")
 		}
 		return
 	}
@@ -114,5 +134,9 @@ func (h *Handler) GetSubscription(c *gin.Context) {
 		machineIDs[i] = machine.ID
 	}
 
+	if mw.Version(c) >= 2 {
+		mw.Respond(c, http.StatusOK, gin.H{"subscribedMachines": machineIDs})
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{"subscribed_machines": machineIDs})
 }
@@ -4,14 +4,20 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+
+	"laundry-status-backend/internal/mw"
 )
 
 // GetVAPIDPublicKey returns the VAPID public key to the client.
 func (h *Handler) GetVAPIDPublicKey(c *gin.Context) {
 	if h.webpush == nil || h.webpush.VAPIDPublicKey == "" {
-		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "vapid keys are not configured"})
+		mw.AbortWithError(c, http.StatusServiceUnavailable, mw.CodeUnavailable, "vapid keys are not configured")
 		return
 	}
 
+	if mw.Version(c) >= 2 {
+		mw.Respond(c, http.StatusOK, gin.H{"publicKey": h.webpush.VAPIDPublicKey})
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{"public_key": h.webpush.VAPIDPublicKey})
 }
\ No newline at end of file
@@ -0,0 +1,338 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"gorm.io/gorm"
+
+	"laundry-status-backend/internal/live"
+	"laundry-status-backend/internal/model"
+	"laundry-status-backend/internal/mw"
+	"laundry-status-backend/internal/store"
+)
+
+const (
+	wsWriteTimeout    = 10 * time.Second
+	wsMaxMessageBytes = 4096
+	// wsMaxTopics bounds the dorms, floors and machines one connection may watch.
+	wsMaxTopics = 64
+	// wsCommandBuffer bounds client messages awaiting a reply; a client that
+	// floods faster than its replies are written is disconnected.
+	wsCommandBuffer = 8
+)
+
+var wsUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 4096,
+	// The data is public and read-only, so cross-origin pages may subscribe too.
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// wsFloor identifies one floor of a dorm.
+type wsFloor struct {
+	DormID int64 `json:"dormId"`
+	Floor  int   `json:"floor"`
+}
+
+// wsClientMessage is a message sent by the client: "subscribe",
+// "unsubscribe" or "ping". ID is echoed back in the reply.
+type wsClientMessage struct {
+	Type     string    `json:"type"`
+	ID       string    `json:"id,omitempty"`
+	Dorms    []int64   `json:"dorms,omitempty"`
+	Floors   []wsFloor `json:"floors,omitempty"`
+	Machines []int64   `json:"machines,omitempty"`
+}
+
+// wsMachineState is a machine's full current state in a snapshot.
+type wsMachineState struct {
+	machineChangeResponse
+	DormID      int64  `json:"dormId"`
+	DisplayName string `json:"displayName"`
+	Kind        string `json:"kind"`
+}
+
+// wsServerMessage is a message sent to the client: "snapshot" (the reply to
+// subscribe), "unsubscribed", "diff", "pong" or "error".
+type wsServerMessage struct {
+	Type       string                  `json:"type"`
+	ID         string                  `json:"id,omitempty"`
+	EventID    uint64                  `json:"eventId,omitempty"`
+	DormID     int64                   `json:"dormId,omitempty"`
+	ObservedAt *time.Time              `json:"observedAt,omitempty"`
+	Snapshot   []wsMachineState        `json:"snapshot,omitempty"`
+	Machines   []machineChangeResponse `json:"machines,omitempty"`
+	Error      string                  `json:"error,omitempty"`
+}
+
+// wsTopics is the set of targets a connection is subscribed to. It is read
+// by the broker while publishing, so it guards itself.
+type wsTopics struct {
+	mu       sync.RWMutex
+	dorms    map[int64]bool
+	floors   map[wsFloor]bool
+	machines map[int64]bool
+}
+
+func newWSTopics() *wsTopics {
+	return &wsTopics{
+		dorms:    make(map[int64]bool),
+		floors:   make(map[wsFloor]bool),
+		machines: make(map[int64]bool),
+	}
+}
+
+func (t *wsTopics) size() int {
+	return len(t.dorms) + len(t.floors) + len(t.machines)
+}
+
+// add subscribes to the targets of msg, reporting false if that would
+// exceed wsMaxTopics.
+func (t *wsTopics) add(msg wsClientMessage) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.size()+len(msg.Dorms)+len(msg.Floors)+len(msg.Machines) > wsMaxTopics {
+		return false
+	}
+	for _, id := range msg.Dorms {
+		t.dorms[id] = true
+	}
+	for _, f := range msg.Floors {
+		t.floors[f] = true
+	}
+	for _, id := range msg.Machines {
+		t.machines[id] = true
+	}
+	return true
+}
+
+func (t *wsTopics) remove(msg wsClientMessage) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, id := range msg.Dorms {
+		delete(t.dorms, id)
+	}
+	for _, f := range msg.Floors {
+		delete(t.floors, f)
+	}
+	for _, id := range msg.Machines {
+		delete(t.machines, id)
+	}
+}
+
+// filter returns the changes of e the connection is subscribed to.
+func (t *wsTopics) filter(e live.Event) []store.MachineChange {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	var matched []store.MachineChange
+	for _, c := range e.Changes {
+		if t.dorms[c.DormID] || t.floors[wsFloor{c.DormID, c.Floor}] || t.machines[c.MachineID] {
+			matched = append(matched, c)
+		}
+	}
+	return matched
+}
+
+// SubscribeWS handles the GET /api/ws request. After upgrading, clients
+// subscribe to dorms, floors and machines; each subscription is answered with
+// a snapshot of the current states, followed by a diff after every scrape
+// cycle that changes one of them. Clients that fall behind are disconnected.
+func SubscribeWS(db *gorm.DB, events *live.Broker, heartbeat time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		topics := newWSTopics()
+		sub, _, _, err := events.Subscribe(0, func(e live.Event) bool {
+			return len(topics.filter(e)) > 0
+		})
+		if err != nil {
+			mw.AbortWithError(c, http.StatusServiceUnavailable, mw.CodeUnavailable, "Server is shutting down")
+			return
+		}
+		defer sub.Close()
+
+		conn, err := wsUpgrader.Upgrade(c.Writer, c.Request, nil)
+		if err != nil {
+			return // The upgrader has already replied
+		}
+		defer conn.Close()
+
+		// 读协程只负责解析消息，所有写操作都在本协程完成，保证快照先于后续增量
+		commands := make(chan wsClientMessage, wsCommandBuffer)
+		readDone := make(chan struct{})
+		go func() {
+			defer close(readDone)
+			readWSMessages(conn, commands, 2*heartbeat+wsWriteTimeout)
+		}()
+
+		ticker := time.NewTicker(heartbeat)
+		defer ticker.Stop()
+
+		write := func(msg any) bool {
+			conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+			return conn.WriteJSON(msg) == nil
+		}
+		closeWith := func(code int, reason string) {
+			deadline := time.Now().Add(wsWriteTimeout)
+			conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), deadline)
+		}
+
+		for {
+			select {
+			case <-readDone:
+				return
+			case cmd, ok := <-commands:
+				if !ok {
+					closeWith(websocket.ClosePolicyViolation, "too many pending messages")
+					return
+				}
+				if !write(handleWSCommand(c.Request.Context(), db, topics, cmd)) {
+					return
+				}
+			case e, ok := <-sub.Events():
+				if !ok {
+					// 消费过慢被断开，或服务正在关闭；客户端重连后会重新拿到快照
+					closeWith(websocket.CloseTryAgainLater, "reconnect to resynchronise")
+					return
+				}
+				changes := topics.filter(e)
+				if len(changes) == 0 {
+					continue
+				}
+				at := e.At
+				msg := wsServerMessage{Type: "diff", EventID: e.ID, DormID: e.DormID, ObservedAt: &at}
+				for _, change := range changes {
+					msg.Machines = append(msg.Machines, newMachineChangeResponse(change))
+				}
+				if !write(msg) {
+					return
+				}
+			case <-ticker.C:
+				if conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteTimeout)) != nil {
+					return
+				}
+			}
+		}
+	}
+}
+
+// readWSMessages forwards client messages until the connection fails or goes
+// quiet for longer than idle. commands is closed if the client floods it.
+func readWSMessages(conn *websocket.Conn, commands chan<- wsClientMessage, idle time.Duration) {
+	conn.SetReadLimit(wsMaxMessageBytes)
+	conn.SetReadDeadline(time.Now().Add(idle))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(idle))
+	})
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		conn.SetReadDeadline(time.Now().Add(idle))
+
+		var msg wsClientMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			msg = wsClientMessage{Type: "malformed"}
+		}
+		select {
+		case commands <- msg:
+		default:
+			close(commands)
+			return
+		}
+	}
+}
+
+func handleWSCommand(ctx context.Context, db *gorm.DB, topics *wsTopics, cmd wsClientMessage) wsServerMessage {
+	switch cmd.Type {
+	case "ping":
+		return wsServerMessage{Type: "pong", ID: cmd.ID}
+	case "unsubscribe":
+		topics.remove(cmd)
+		return wsServerMessage{Type: "unsubscribed", ID: cmd.ID}
+	case "subscribe":
+		if !topics.add(cmd) {
+			return wsServerMessage{Type: "error", ID: cmd.ID, Error: "too many subscriptions"}
+		}
+		snapshot, err := wsSnapshot(db, cmd)
+		if err != nil {
+			slog.ErrorContext(ctx, "Error building websocket snapshot", "error", err)
+			return wsServerMessage{Type: "error", ID: cmd.ID, Error: "failed to load machine states"}
+		}
+		return wsServerMessage{Type: "snapshot", ID: cmd.ID, Snapshot: snapshot}
+	case "malformed":
+		return wsServerMessage{Type: "error", Error: "malformed message"}
+	default:
+		return wsServerMessage{Type: "error", ID: cmd.ID, Error: "unknown message type"}
+	}
+}
+
+// wsSnapshot loads the current state of every machine targeted by msg.
+func wsSnapshot(db *gorm.DB, msg wsClientMessage) ([]wsMachineState, error) {
+	var conds []*gorm.DB
+	if len(msg.Dorms) > 0 {
+		conds = append(conds, db.Where("dorm_id IN ?", msg.Dorms))
+	}
+	for _, f := range msg.Floors {
+		conds = append(conds, db.Where("dorm_id = ? AND floor = ?", f.DormID, f.Floor))
+	}
+	if len(msg.Machines) > 0 {
+		conds = append(conds, db.Where("id IN ?", msg.Machines))
+	}
+	if len(conds) == 0 {
+		return nil, nil
+	}
+
+	where := conds[0]
+	for _, cond := range conds[1:] {
+		where = where.Or(cond)
+	}
+	var machines []model.Machine
+	if err := db.Scopes(store.VisibleMachines).Where(where).Order("dorm_id, floor, seq").Find(&machines).Error; err != nil {
+		return nil, err
+	}
+
+	machineIDs := make([]int64, len(machines))
+	for i, m := range machines {
+		machineIDs[i] = m.ID
+	}
+	var opens []model.OccupancyOpen
+	if err := db.Where("machine_id IN ?", machineIDs).Find(&opens).Error; err != nil {
+		return nil, err
+	}
+	openByMachine := make(map[int64]model.OccupancyOpen, len(opens))
+	for _, o := range opens {
+		openByMachine[o.MachineID] = o
+	}
+
+	now := time.Now().UTC()
+	states := make([]wsMachineState, 0, len(machines))
+	for _, m := range machines {
+		change := store.MachineChange{MachineID: m.ID, DormID: m.DormID, Floor: m.Floor}
+		if open, ok := openByMachine[m.ID]; ok {
+			change.Status = open.Status
+			change.Message = open.Message
+			change.TimeRemaining = open.TimeRemaining
+			change.ObservedAt = open.ObservedAt
+		} else {
+			change.Status = 1 // Default to configured idle status
+			change.Message = "空闲"
+			change.ObservedAt = now
+			change.Idle = true
+		}
+		states = append(states, wsMachineState{
+			machineChangeResponse: newMachineChangeResponse(change),
+			DormID:                m.DormID,
+			DisplayName:           m.DisplayName,
+			Kind:                  m.Kind,
+		})
+	}
+	return states, nil
+}
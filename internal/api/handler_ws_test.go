@@ -0,0 +1,87 @@
+package api
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"laundry-status-backend/internal/live"
+	"laundry-status-backend/internal/model"
+	"laundry-status-backend/internal/store"
+)
+
+func TestSubscribeWS(t *testing.T) {
+	testDB := newTestDB(t)
+	now := time.Now().UTC()
+	require.NoError(t, testDB.Create(&model.Dorm{ID: 1, Name: "东3"}).Error)
+	require.NoError(t, testDB.Create(&[]model.Machine{
+		{ID: 11, DormID: 1, DisplayName: "东3#3-1", Floor: 3, Seq: 1, Kind: model.MachineKindWasher},
+		{ID: 12, DormID: 1, DisplayName: "东3#3-2", Floor: 3, Seq: 2, Kind: model.MachineKindDryer},
+		{ID: 13, DormID: 1, DisplayName: "东3#4-1", Floor: 4, Seq: 1, Kind: model.MachineKindWasher},
+	}).Error)
+	require.NoError(t, testDB.Create(&model.OccupancyOpen{MachineID: 11, Status: 2, Message: "使用中", ObservedAt: now, TimeRemaining: 600}).Error)
+
+	events := live.NewBroker(16, 4)
+	r := gin.New()
+	r.GET("/api/ws", SubscribeWS(testDB, events, time.Minute))
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/api/ws", nil)
+	require.NoError(t, err)
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	roundTrip := func(msg wsClientMessage) wsServerMessage {
+		require.NoError(t, conn.WriteJSON(msg))
+		var reply wsServerMessage
+		require.NoError(t, conn.ReadJSON(&reply))
+		return reply
+	}
+
+	reply := roundTrip(wsClientMessage{Type: "subscribe", ID: "a", Floors: []wsFloor{{DormID: 1, Floor: 3}}})
+	assert.Equal(t, "snapshot", reply.Type)
+	assert.Equal(t, "a", reply.ID)
+	require.Len(t, reply.Snapshot, 2)
+	assert.Equal(t, int64(11), reply.Snapshot[0].MachineID)
+	assert.False(t, reply.Snapshot[0].IsAvailable)
+	assert.NotNil(t, reply.Snapshot[0].FinishTime)
+	assert.Equal(t, model.MachineKindDryer, reply.Snapshot[1].Kind)
+	assert.True(t, reply.Snapshot[1].IsAvailable)
+
+	reply = roundTrip(wsClientMessage{Type: "subscribe", ID: "b", Machines: []int64{13}})
+	require.Len(t, reply.Snapshot, 1)
+
+	// Only subscribed machines are part of a diff.
+	events.Publish(now, []store.MachineChange{
+		{MachineID: 11, DormID: 1, Floor: 3, Idle: true, Status: 1},
+		{MachineID: 21, DormID: 2, Floor: 3, Idle: true, Status: 1},
+		{MachineID: 13, DormID: 1, Floor: 4, Status: 2},
+	})
+	var diff wsServerMessage
+	require.NoError(t, conn.ReadJSON(&diff))
+	assert.Equal(t, "diff", diff.Type)
+	assert.Equal(t, uint64(1), diff.EventID)
+	require.Len(t, diff.Machines, 2)
+	assert.True(t, diff.Machines[0].IsAvailable)
+	assert.Equal(t, int64(13), diff.Machines[1].MachineID)
+
+	assert.Equal(t, "unsubscribed", roundTrip(wsClientMessage{Type: "unsubscribe", Machines: []int64{13}}).Type)
+	events.Publish(now, []store.MachineChange{{MachineID: 13, DormID: 1, Floor: 4}})
+	assert.Equal(t, "pong", roundTrip(wsClientMessage{Type: "ping", ID: "p"}).Type, "no diff for unsubscribed machines")
+
+	assert.Equal(t, "error", roundTrip(wsClientMessage{Type: "hello"}).Type)
+	tooMany := make([]int64, wsMaxTopics)
+	assert.Equal(t, "too many subscriptions", roundTrip(wsClientMessage{Type: "subscribe", Machines: tooMany}).Error)
+
+	// Shutting the broker down closes the connection with a close frame.
+	events.Close()
+	_, _, err = conn.ReadMessage()
+	assert.True(t, websocket.IsCloseError(err, websocket.CloseTryAgainLater), "got %v", err)
+}
@@ -0,0 +1,19 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"laundry-status-backend/internal/mw"
+)
+
+// abortInvalidBody answers a request whose JSON body failed to bind. /api has
+// always echoed the binding error; /api/v2 does not expose Go type names.
+func abortInvalidBody(c *gin.Context, err error) {
+	message := "Invalid request body"
+	if mw.Version(c) < 2 {
+		message = err.Error()
+	}
+	mw.AbortWithError(c, http.StatusBadRequest, mw.CodeInvalidBody, message)
+}
@@ -1,48 +1,192 @@
 package api
 
 import (
-	"time"
+	"log/slog"
+	"net/http"
+	"strings"
 
 	"github.com/SherClockHolmes/webpush-go"
+	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/gin-gonic/gin"
 	"github.com/patrickmn/go-cache"
 	"golang.org/x/time/rate"
+	"gorm.io/gorm"
 
+	"laundry-status-backend/config"
+	"laundry-status-backend/docs/openapi"
+	"laundry-status-backend/internal/live"
+	"laundry-status-backend/internal/metrics"
+	"laundry-status-backend/internal/model"
 	"laundry-status-backend/internal/mw"
+	"laundry-status-backend/internal/scraper"
 	"laundry-status-backend/internal/store"
 )
 
-// NewRouter creates and configures a new Gin router.
-func NewRouter(s store.Store, webpushOptions *webpush.Options) *gin.Engine {
-	r := gin.Default()
+// NewRouter creates and configures a new Gin router. Live streams are served
+// from events; scraperSvc, which may be nil, reports scraper health.
+func NewRouter(cfg *config.Config, s store.Store, webpushOptions *webpush.Options, events *live.Broker, scraperSvc *scraper.Service) *gin.Engine {
+	r := gin.New()
+	r.Use(mw.RequestID(), mw.AccessLog("/healthz", "/readyz", "/metrics"), mw.Recovery(), mw.Metrics())
+	configureClientIP(r, cfg.Server)
+
+	// Tests check every response against the spec, so handlers cannot drift
+	// from it unnoticed
+	spec := mustLoadSpec()
+	if gin.Mode() == gin.TestMode {
+		r.Use(mustOpenAPI(spec, mw.OpenAPIOptions{ValidateResponses: true}))
+	}
 
 	db := s.DB()
 	handler := NewHandler(s, webpushOptions)
 
 	// Initialize middleware
-	// Rate limit: 10 requests per second with a burst of 5
-	rateLimiter := mw.RateLimiter(rate.Limit(10), 5)
+	// Rate limits per client; subscription writes trigger push sends and
+	// get a much smaller budget of their own
+	rateLimiter := mw.RateLimiter(rate.Limit(cfg.Server.RateLimitPerSec), cfg.Server.RateLimitBurst)
+	subscriptionLimiter := mw.RateLimiter(rate.Limit(cfg.Server.SubscriptionRateLimitPerMin/60), cfg.Server.SubscriptionRateLimitBurst)
+
+	// Cache: entries are keyed by data version, so scrapes invalidate them
+	// before the configured TTL runs out
+	cacheStore := cache.New(cfg.Server.CacheTTL, 2*cfg.Server.CacheTTL)
+	caching := mw.Cache(cacheStore, cfg.Server.CacheTTL, scrapeVersions{events: events, interval: cfg.Scraper.Interval, staleAfter: cfg.Scraper.StaleAfter})
+
+	// Long-lived streams are capped per client instead of cached
+	streamLimit := mw.ConnLimit(cfg.Server.StreamMaxConnsPerIP)
 
-	// Cache: 5 minute default expiration, cleaned up every 10 minutes
-	cacheStore := cache.New(5*time.Minute, 10*time.Minute)
-	caching := mw.Cache(cacheStore, 5*time.Minute)
+	// Probes and metrics stay outside /api so they are never rate limited
+	r.GET("/healthz", Healthz)
+	r.GET("/readyz", Readyz(db, cfg))
+	r.GET("/metrics", gin.WrapH(metrics.Handler()))
 
-	// API group
+	// API groups: /api keeps its original response format, /api/v2 wraps
+	// responses in mw.Envelope. Both share one rate limit budget per client.
 	api := r.Group("/api")
 	api.Use(rateLimiter)
+	registerPublicRoutes(api, cfg, db, handler, caching, subscriptionLimiter, scraperSvc)
 	{
-		// GET /api/dorms
-		api.GET("/dorms", caching, GetDorms(db))
+		// GET /api/docs
+		api.GET("/docs", GetDocs)
+
+		// GET /api/docs/openapi.json
+		api.GET("/docs/openapi.json", GetSpec)
+
+		// Event streams have no envelope, so they are only served under /api
+
+		// GET /api/dorms/{dorm_id}/stream
+		api.GET("/dorms/:dorm_id/stream", streamLimit, StreamDorm(db, events, cfg.Server.StreamHeartbeat))
+
+		// GET /api/ws
+		api.GET("/ws", streamLimit, SubscribeWS(db, events, cfg.Server.StreamHeartbeat))
+	}
+
+	// Only /api/v2 rejects requests the spec does not allow: /api keeps the
+	// error messages its clients have seen so far
+	v2 := r.Group("/api/v2")
+	v2.Use(mw.APIVersion(2), rateLimiter, mustOpenAPI(spec, mw.OpenAPIOptions{ValidateRequests: true}))
+	registerPublicRoutes(v2, cfg, db, handler, caching, subscriptionLimiter, scraperSvc)
 
-		// GET /api/dorms/{dorm_id}/machines
-		api.GET("/dorms/:dorm_id/machines", caching, GetMachineStatus(db))
+	// Unknown /api/v2 routes get an envelope too; others keep gin's plain 404
+	markV2 := mw.APIVersion(2)
+	r.NoRoute(func(c *gin.Context) {
+		if strings.HasPrefix(c.Request.URL.Path, "/api/v2/") {
+			markV2(c)
+			mw.AbortWithError(c, http.StatusNotFound, mw.CodeNotFound, "route not found")
+		}
+	})
 
-		// Add these lines inside the api.Use(rateLimiter) block
-		api.GET("/subscriptions", handler.GetSubscription)
-		api.PUT("/subscriptions", handler.PutSubscription)
-		api.DELETE("/subscriptions", handler.DeleteSubscription)
-		api.GET("/vapid_public_key", handler.GetVAPIDPublicKey)
+	// Admin group, protected by API keys (see "laundryd apikey"). Reads need
+	// the viewer role, changes the operator role; every change is audited.
+	viewer, operator, adminOnly := requireRole(model.RoleViewer), requireRole(model.RoleOperator), requireRole(model.RoleAdmin)
+	admin := api.Group("/admin")
+	admin.Use(requireAPIKey(db), auditWrites(db), touchOnWrite(events))
+	{
+		admin.PATCH("/dorms/:dorm_id", operator, handler.RenameDorm)
+		admin.POST("/dorms/:dorm_id/merge", operator, handler.MergeDorm)
+		admin.GET("/dorms/:dorm_id/aliases", viewer, handler.GetDormAliases)
+		admin.POST("/dorms/:dorm_id/aliases", operator, handler.PutDormAlias)
+		admin.GET("/quarantine", viewer, handler.GetQuarantine)
+		admin.POST("/quarantine/:machine_id/promote", operator, handler.PromoteQuarantinedMachine)
+		admin.GET("/overrides", viewer, handler.GetMachineOverrides)
+		admin.GET("/machines/:machine_id/override", viewer, handler.GetMachineOverride)
+		admin.PUT("/machines/:machine_id/override", operator, handler.PutMachineOverride)
+		admin.DELETE("/machines/:machine_id/override", operator, handler.DeleteMachineOverride)
+		admin.POST("/scraper/run", operator, RunScraper(scraperSvc))
+		admin.POST("/scraper/pause", operator, PauseScraper(scraperSvc))
+		admin.POST("/scraper/resume", operator, ResumeScraper(scraperSvc))
+		admin.GET("/subscriptions", operator, handler.GetSubscriptions)
+		admin.GET("/config", adminOnly, GetConfig(cfg))
+		admin.GET("/audit", adminOnly, handler.GetAuditLog)
 	}
 
 	return r
 }
+
+// mustLoadSpec returns the embedded OpenAPI spec. It is part of the binary
+// and loaded by the tests, so failing to load it is a build defect.
+func mustLoadSpec() *openapi3.T {
+	spec, err := openapi.Load()
+	if err != nil {
+		panic(err)
+	}
+	return spec
+}
+
+func mustOpenAPI(spec *openapi3.T, opts mw.OpenAPIOptions) gin.HandlerFunc {
+	validate, err := mw.OpenAPI(spec, opts)
+	if err != nil {
+		panic(err)
+	}
+	return validate
+}
+
+// registerPublicRoutes registers the request/response endpoints served under
+// both /api and /api/v2.
+func registerPublicRoutes(g *gin.RouterGroup, cfg *config.Config, db *gorm.DB, handler *Handler, caching, subscriptionLimiter gin.HandlerFunc, scraperSvc *scraper.Service) {
+	// GET /dorms
+	g.GET("/dorms", caching, GetDorms(db, cfg))
+
+	// GET /dorms/{dorm_id}/machines
+	g.GET("/dorms/:dorm_id/machines", caching, GetMachineStatus(db, cfg))
+
+	// GET /dorms/{dorm_id}/stats/utilization
+	g.GET("/dorms/:dorm_id/stats/utilization", caching, GetUtilization(db, cfg))
+
+	// GET /dorms/{dorm_id}/recommendations
+	g.GET("/dorms/:dorm_id/recommendations", caching, GetRecommendations(db, cfg))
+
+	// GET /dorms/{dorm_id}/floors/{floor}/next-available
+	g.GET("/dorms/:dorm_id/floors/:floor/next-available", caching, GetNextAvailable(db, cfg))
+
+	// GET /machines/search
+	g.GET("/machines/search", caching, SearchMachines(db, cfg))
+
+	// GET /machines/{machine_id}/history
+	g.GET("/machines/:machine_id/history", caching, GetMachineHistory(db))
+
+	// GET /status
+	g.GET("/status", GetStatus(db, cfg, scraperSvc))
+
+	// Subscriptions
+	g.GET("/subscriptions", handler.GetSubscription)
+	g.PUT("/subscriptions", subscriptionLimiter, handler.PutSubscription)
+	g.DELETE("/subscriptions", subscriptionLimiter, handler.DeleteSubscription)
+	g.GET("/vapid_public_key", handler.GetVAPIDPublicKey)
+}
+
+// configureClientIP makes c.ClientIP read the configured header, but only
+// from requests sent by a trusted proxy; otherwise the peer address is used.
+func configureClientIP(r *gin.Engine, cfg config.ServerConfig) {
+	if cfg.RequestIPHeader == "" || len(cfg.TrustedProxies) == 0 {
+		r.ForwardedByClientIP = false
+		_ = r.SetTrustedProxies(nil)
+		return
+	}
+	r.ForwardedByClientIP = true
+	r.RemoteIPHeaders = []string{cfg.RequestIPHeader}
+	if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		// config.Load validates the list, so this only guards hand-built configs
+		slog.Warn("Invalid trusted proxies, ignoring client IP header", "header", cfg.RequestIPHeader, "error", err)
+		r.ForwardedByClientIP = false
+		_ = r.SetTrustedProxies(nil)
+	}
+}
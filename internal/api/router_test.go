@@ -0,0 +1,192 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SherClockHolmes/webpush-go"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"laundry-status-backend/config"
+	"laundry-status-backend/docs/openapi"
+	"laundry-status-backend/internal/live"
+	"laundry-status-backend/internal/model"
+	"laundry-status-backend/internal/mw"
+	"laundry-status-backend/internal/store"
+)
+
+// newTestRouter builds the full router on testDB with generous rate limits.
+func newTestRouter(t *testing.T, testDB *gorm.DB) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{
+		Server: config.ServerConfig{
+			RateLimitPerSec: 1000, RateLimitBurst: 1000,
+			SubscriptionRateLimitPerMin: 600, SubscriptionRateLimitBurst: 100,
+			CacheTTL: time.Minute, StreamMaxConnsPerIP: 1, StreamHeartbeat: time.Minute,
+		},
+		Scraper:  config.ScraperConfig{Interval: time.Minute, StaleAfter: 3 * time.Minute, Request: config.ScraperRequest{Headers: map[string]string{"Cookie": "session=s3cret"}}},
+		Database: config.DatabaseConfig{DSN: "postgres://user:pw@db/laundry"},
+		Push:     config.PushConfig{PublicKey: "public", PrivateKey: "vapid-secret"},
+	}
+	return NewRouter(cfg, store.NewGormStore(testDB, nil), &webpush.Options{}, live.NewBroker(16, 4), nil)
+}
+
+func TestConfigureClientIP(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	clientIP := func(cfg config.ServerConfig, remote, forwarded string) string {
+		r := gin.New()
+		configureClientIP(r, cfg)
+		var ip string
+		r.GET("/", func(c *gin.Context) { ip = c.ClientIP() })
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = remote + ":4321"
+		req.Header.Set("X-Forwarded-For", forwarded)
+		r.ServeHTTP(httptest.NewRecorder(), req)
+		return ip
+	}
+
+	proxied := config.ServerConfig{RequestIPHeader: "X-Forwarded-For", TrustedProxies: []string{"10.0.0.0/8"}}
+	assert.Equal(t, "203.0.113.7", clientIP(proxied, "10.1.2.3", "203.0.113.7"))
+	assert.Equal(t, "203.0.113.7", clientIP(proxied, "10.1.2.3", "198.51.100.1, 203.0.113.7"),
+		"only the hop appended by the trusted proxy is believed")
+	assert.Equal(t, "198.51.100.9", clientIP(proxied, "198.51.100.9", "203.0.113.7"),
+		"untrusted peers cannot spoof their address")
+	assert.Equal(t, "10.1.2.3", clientIP(config.ServerConfig{}, "10.1.2.3", "203.0.113.7"),
+		"without a configured header the peer address is used")
+}
+
+func TestAPIv2_Envelope(t *testing.T) {
+	testDB := newTestDB(t)
+	require.NoError(t, testDB.Create(&model.Dorm{ID: 1, Name: "东3"}).Error)
+	require.NoError(t, testDB.Create(&model.Machine{ID: 11, DormID: 1, DisplayName: "东3#1-1", Floor: 1, Kind: model.MachineKindWasher}).Error)
+	r := newTestRouter(t, testDB)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	decode := func(w *httptest.ResponseRecorder) (data any, meta map[string]any, apiErr *mw.ErrorBody) {
+		var env struct {
+			Data  any            `json:"data"`
+			Meta  map[string]any `json:"meta"`
+			Error *mw.ErrorBody  `json:"error"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &env), w.Body.String())
+		require.NotNil(t, env.Meta, "every envelope has a meta object")
+		return env.Data, env.Meta, env.Error
+	}
+
+	t.Run("data and meta", func(t *testing.T) {
+		w := do(http.MethodGet, "/api/v2/dorms/1/machines", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		data, meta, apiErr := decode(w)
+		assert.Nil(t, apiErr)
+		machines := data.([]any)
+		require.Len(t, machines, 1)
+		machine := machines[0].(map[string]any)
+		assert.EqualValues(t, 11, machine["machineId"])
+		assert.Equal(t, "东3", machine["dormName"])
+		assert.NotContains(t, machine, "ID", "machine fields are camelCase")
+		assert.Contains(t, meta, "freshness")
+
+		w = do(http.MethodGet, "/api/dorms/1/machines", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		var legacy []map[string]any
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &legacy))
+		assert.EqualValues(t, 11, legacy[0]["ID"], "/api keeps its format")
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		w := do(http.MethodGet, "/api/v2/dorms/abc/machines", "")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		_, _, apiErr := decode(w)
+		assert.Equal(t, &mw.ErrorBody{Code: mw.CodeInvalidParameter, Message: "Invalid path parameter 'dorm_id'"}, apiErr,
+			"/api/v2 checks parameters against the spec")
+		assert.JSONEq(t, `{"error":"Invalid dorm ID"}`, do(http.MethodGet, "/api/dorms/abc/machines", "").Body.String())
+
+		w = do(http.MethodPut, "/api/v2/subscriptions", `{"endpoint":"https://push.example/1"}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		_, _, apiErr = decode(w)
+		assert.Equal(t, &mw.ErrorBody{Code: mw.CodeInvalidBody, Message: "Invalid request body"}, apiErr,
+			"binding errors name Go types, so v2 does not echo them")
+
+		w = do(http.MethodGet, "/api/v2/subscriptions?endpoint=unknown", "")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		_, _, apiErr = decode(w)
+		assert.Equal(t, mw.CodeSubscriptionNotFound, apiErr.Code)
+
+		w = do(http.MethodGet, "/api/v2/no/such/route", "")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		_, _, apiErr = decode(w)
+		assert.Equal(t, mw.CodeNotFound, apiErr.Code)
+	})
+
+	t.Run("camelCase subscriptions", func(t *testing.T) {
+		w := do(http.MethodPut, "/api/v2/subscriptions", `{"endpoint":"https://push.example/2","p256dh":"k","auth":"a","subscribedMachines":[11]}`)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+		w = do(http.MethodGet, "/api/v2/subscriptions?endpoint=https://push.example/2", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		data, _, _ := decode(w)
+		assert.Equal(t, map[string]any{"subscribedMachines": []any{float64(11)}}, data)
+
+		w = do(http.MethodGet, "/api/subscriptions?endpoint=https://push.example/2", "")
+		assert.JSONEq(t, `{"subscribed_machines":[11]}`, w.Body.String())
+	})
+}
+
+func TestRoutesMatchSpec(t *testing.T) {
+	r := newTestRouter(t, newTestDB(t))
+	spec, err := openapi.Load()
+	require.NoError(t, err)
+	ops, err := openapi.Operations(spec)
+	require.NoError(t, err)
+
+	var documented, served []string
+	for _, op := range ops {
+		documented = append(documented, op.Method+" "+mw.GinPath(op.Path))
+	}
+	for _, route := range r.Routes() {
+		served = append(served, route.Method+" "+route.Path)
+	}
+	for _, route := range served {
+		assert.Contains(t, documented, route, "route is missing from docs/openapi")
+	}
+	for _, op := range documented {
+		assert.Contains(t, served, op, "documented operation is not served by NewRouter")
+	}
+}
+
+func TestDocs(t *testing.T) {
+	r := newTestRouter(t, newTestDB(t))
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	w := get("/api/docs")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `url: "docs/openapi.json"`)
+
+	w = get("/api/docs/openapi.json")
+	require.Equal(t, http.StatusOK, w.Code)
+	var spec struct {
+		Paths map[string]map[string]any `json:"paths"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &spec))
+	assert.Contains(t, spec.Paths, "/v2/dorms")
+	assert.NotContains(t, w.Body.String(), ".yaml", "references to other files are resolved")
+}
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"laundry-status-backend/internal/live"
+)
+
+// scrapeVersions versions responses by the data the live broker has seen
+// committed. Routes with a dorm_id depend on that dorm only; everything else
+// depends on all dorms. Every completed cycle also yields a new version,
+// since responses carry when their data was last confirmed.
+type scrapeVersions struct {
+	events     *live.Broker
+	interval   time.Duration // Scrape interval
+	staleAfter time.Duration
+}
+
+func (v scrapeVersions) Version(c *gin.Context) (string, time.Time) {
+	dormID, _ := strconv.ParseInt(c.Param("dorm_id"), 10, 64)
+	version := v.events.Version(dormID)
+	cycle := v.events.LastCycle()
+	stale := cycle.IsZero() || (v.staleAfter > 0 && time.Since(cycle) > v.staleAfter)
+	// 重启后序号从零开始，带上 epoch 避免与旧进程的 ETag 冲突
+	tag := fmt.Sprintf("%x-%d-%d-%x", v.events.Epoch().Unix(), dormID, version.Seq, cycle.Unix())
+	if stale {
+		tag += "-stale"
+	}
+	return tag, version.ModifiedAt
+}
+
+// MaxAge lasts until the next scrape is expected to commit.
+func (v scrapeVersions) MaxAge() time.Duration {
+	last := v.events.LastCycle()
+	if last.IsZero() {
+		return 0
+	}
+	remaining := time.Until(last.Add(v.interval))
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
+// touchOnWrite bumps every data version after a successful write, so cached
+// reads reflect admin corrections immediately.
+func touchOnWrite(events *live.Broker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		if c.Request.Method != http.MethodGet && c.Writer.Status() < 400 {
+			events.Touch(time.Now())
+		}
+	}
+}
@@ -2,21 +2,26 @@ package db
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
+	"strings"
 	"time"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
+	"gorm.io/gorm/clause"
 
 	"laundry-status-backend/config"
+	"laundry-status-backend/internal/logging"
 	"laundry-status-backend/internal/model"
+	"laundry-status-backend/internal/parse"
 )
 
-// Init initializes the database connection and runs migrations.
+// Init initializes the database connection and runs migrations. gorm logs
+// through the default slog logger; statements are only logged at debug
+// level unless they fail or are slower than cfg.SlowQuery.
 func Init(cfg *config.DatabaseConfig) (*gorm.DB, error) {
 	db, err := gorm.Open(postgres.Open(cfg.DSN), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Info),
+		Logger: logging.NewGormLogger(slog.Default(), cfg.SlowQuery),
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
@@ -31,25 +36,38 @@ func Init(cfg *config.DatabaseConfig) (*gorm.DB, error) {
 	sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
 	sqlDB.SetConnMaxLifetime(time.Duration(cfg.ConnMaxLifetimeMinutes) * time.Minute)
 
-	log.Println("Running database migrations...")
-	if err := db.AutoMigrate(
-		&model.Dorm{},
-		&model.Machine{},
-		&model.OccupancyOpen{},
-		&model.OccupancyHistory{},
-		&model.PushSubscription{},
-	); err != nil {
+	slog.Info("Running database migrations")
+	hadDormAliases := db.Migrator().HasTable(&model.DormAlias{})
+	if err := db.AutoMigrate(schemaModels...); err != nil {
 		return nil, fmt.Errorf("automigrate failed: %w", err)
 	}
 
+	if !hadDormAliases {
+		if err := backfillDormAliases(db); err != nil {
+			return nil, fmt.Errorf("dorm alias backfill failed: %w", err)
+		}
+	}
+
 	if cfg.EnableTimescale {
-		log.Println("TimescaleDB is enabled, applying TimescaleDB-specific DDL...")
+		slog.Info("TimescaleDB is enabled, applying TimescaleDB-specific DDL")
 		if err := applyTimescaleDDL(db); err != nil {
-			log.Printf("Warning: failed to apply some TimescaleDB DDL: %v. Continuing without them.", err)
+			slog.Warn("Failed to apply some TimescaleDB DDL, continuing without them", "error", err)
+		}
+		if err := applyTimescaleRetention(db, &cfg.Retention); err != nil {
+			slog.Warn("Failed to apply TimescaleDB retention policies, history will not be rolled up", "error", err)
 		}
+	} else {
+		// Without TimescaleDB the rollup is a plain table filled by the retention job.
+		if err := db.AutoMigrate(&model.OccupancyHourly{}); err != nil {
+			return nil, fmt.Errorf("automigrate failed: %w", err)
+		}
+	}
+
+	if err := db.Exec(dormRollupViewDDL).Error; err != nil {
+		slog.Warn("Failed to create rollup view", "view", model.DormOccupancyHourly{}.TableName(), "error", err)
 	}
 
-	log.Println("Database initialization complete.")
+	slog.Info("Database initialization complete")
 	return db, nil
 }
 
@@ -73,6 +91,46 @@ func Init(cfg *config.DatabaseConfig) (*gorm.DB, error) {
 // 	return nil
 // }
 
+// schemaModels are the tables every deployment migrates.
+var schemaModels = []any{
+	&model.Dorm{},
+	&model.DormAlias{},
+	&model.Machine{},
+	&model.QuarantinedMachine{},
+	&model.MachineOverride{},
+	&model.OccupancyOpen{},
+	&model.OccupancyHistory{},
+	&model.PushSubscription{},
+	&model.ScraperState{},
+	&model.APIKey{},
+	&model.AuditLog{},
+}
+
+// CheckSchema reports the first table or column of the current models that is
+// missing from the database, i.e. whether Init's migrations have been applied.
+func CheckSchema(db *gorm.DB) error {
+	migrator := db.Migrator()
+	for _, m := range schemaModels {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(m); err != nil {
+			return fmt.Errorf("failed to parse model %T: %w", m, err)
+		}
+		table := stmt.Schema.Table
+		if !migrator.HasTable(table) {
+			return fmt.Errorf("table %s is missing", table)
+		}
+		for _, field := range stmt.Schema.Fields {
+			if field.DBName == "" {
+				continue
+			}
+			if !migrator.HasColumn(m, field.DBName) {
+				return fmt.Errorf("column %s.%s is missing", table, field.DBName)
+			}
+		}
+	}
+	return nil
+}
+
 func applyTimescaleDDL(db *gorm.DB) error {
 	ddls := []string{
 		// 1) 必备扩展
@@ -82,16 +140,125 @@ func applyTimescaleDDL(db *gorm.DB) error {
 		// 2) 把 occupancy_histories 设为 hypertable（observed_at 为 time dimension）
 		"SELECT create_hypertable('occupancy_histories', 'observed_at', if_not_exists => TRUE);",
 
-		// 3) 基本校验：起止必须有效
-		"ALTER TABLE occupancy_histories " +
-			"ADD CONSTRAINT occupancy_histories_period_valid CHECK (period_start < period_end);",
+		// 3) 基本校验：起止必须有效（重复启动时跳过已存在的约束）
+		"DO $$ BEGIN " +
+			"IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'occupancy_histories_period_valid') THEN " +
+			"ALTER TABLE occupancy_histories ADD CONSTRAINT occupancy_histories_period_valid CHECK (period_start < period_end); " +
+			"END IF; END $$;",
 
 		// 4) 表达式 GIST 索引：支持 @>、&& 等范围操作（下界闭、上界开）
-		"CREATE INDEX idx_occupancy_history_period_expr ON occupancy_histories " +
+		"CREATE INDEX IF NOT EXISTS idx_occupancy_history_period_expr ON occupancy_histories " +
 			"USING GIST (machine_id, tstzrange(period_start, period_end, '[)'));",
 
-		// 5) 常用倒序时间索引：便于按机器拉最新记录
-		"CREATE INDEX idx_occupancy_history_machine_id_observed_at ON occupancy_histories (machine_id, observed_at DESC);",
+		// 5) 实际占用区间的 GIST 索引：按时间点还原状态（?at=）时使用
+		"CREATE INDEX IF NOT EXISTS idx_occupancy_history_observed_span ON occupancy_histories " +
+			"USING GIST (machine_id, tstzrange(period_start, observed_at, '[)'));",
+
+		// 6) 常用倒序时间索引：便于按机器拉最新记录
+		"CREATE INDEX IF NOT EXISTS idx_occupancy_history_machine_id_observed_at ON occupancy_histories (machine_id, observed_at DESC);",
+	}
+
+	for _, ddl := range ddls {
+		if err := db.Exec(ddl).Error; err != nil {
+			return fmt.Errorf("DDL failed on %q: %w", ddl, err)
+		}
+	}
+	return nil
+}
+
+// backfillDormAliases seeds dorm_aliases the first time the table is created.
+// Every dorm gets its own name as an alias. The parser used to rewrite every
+// 栋 to 东, so the upstream spelling cannot be told from a stored name that
+// also contains a genuine 东; it is recovered by re-parsing the raw names of
+// the dorm's machines instead.
+func backfillDormAliases(db *gorm.DB) error {
+	slog.Info("Backfilling dorm aliases from existing dorms")
+	var dorms []model.Dorm
+	if err := db.Find(&dorms).Error; err != nil {
+		return fmt.Errorf("failed to load dorms: %w", err)
+	}
+	var machines []model.Machine
+	if err := db.Select("dorm_id", "display_name", "floor_code").Find(&machines).Error; err != nil {
+		return fmt.Errorf("failed to load machines: %w", err)
+	}
+
+	names := make(map[int64]string, len(dorms))
+	aliases := make([]model.DormAlias, 0, len(dorms))
+	for _, d := range dorms {
+		names[d.ID] = d.Name
+		aliases = append(aliases, model.DormAlias{Alias: d.Name, DormID: d.ID})
+	}
+	seen := make(map[string]bool)
+	for _, m := range machines {
+		parsed, err := parse.ParseName(m.DisplayName, m.FloorCode)
+		if err != nil || seen[parsed.Dorm] {
+			continue
+		}
+		// 只收录经旧的 栋 -> 东 改写后正好得到所属楼名的写法
+		if name, ok := names[m.DormID]; !ok || parsed.Dorm == name || strings.ReplaceAll(parsed.Dorm, "栋", "东") != name {
+			continue
+		}
+		seen[parsed.Dorm] = true
+		aliases = append(aliases, model.DormAlias{Alias: parsed.Dorm, DormID: m.DormID})
+	}
+	if len(aliases) == 0 {
+		return nil
+	}
+
+	if err := db.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(aliases, 500).Error; err != nil {
+		return fmt.Errorf("failed to save dorm aliases: %w", err)
+	}
+	return nil
+}
+
+// rollupStartOffset is how far back the continuous aggregate policy refreshes.
+// It must stay inside the raw-history retention window, otherwise a refresh over
+// already-dropped chunks would erase the aggregated buckets.
+const rollupStartOffset = 72 * time.Hour
+
+// dormRollupViewDDL defines the per-dorm hourly view on top of the per-machine rollup.
+const dormRollupViewDDL = "CREATE OR REPLACE VIEW occupancy_hourly_dorm AS " +
+	"SELECT h.bucket, m.dorm_id, h.status, COUNT(DISTINCT h.machine_id) AS machines, " +
+	"SUM(h.sessions) AS sessions, SUM(h.busy_seconds) AS busy_seconds " +
+	"FROM occupancy_hourly h JOIN machines m ON m.id = h.machine_id " +
+	"GROUP BY h.bucket, m.dorm_id, h.status;"
+
+// applyTimescaleRetention creates the hourly continuous aggregate and (re)installs
+// the refresh and retention policies so that config changes take effect on restart.
+func applyTimescaleRetention(db *gorm.DB, cfg *config.RetentionConfig) error {
+	startOffset := rollupStartOffset
+	if cfg.HistoryDays > 0 {
+		if retention := time.Duration(cfg.HistoryDays) * 24 * time.Hour; retention <= startOffset {
+			startOffset = retention - time.Hour
+		}
+	}
+
+	ddls := []string{
+		// 1) 每小时、每台机器的连续聚合（按状态结束的观测时间分桶）
+		"CREATE MATERIALIZED VIEW IF NOT EXISTS occupancy_hourly " +
+			"WITH (timescaledb.continuous) AS " +
+			"SELECT time_bucket(INTERVAL '1 hour', observed_at) AS bucket, machine_id, status, " +
+			"COUNT(*) AS sessions, " +
+			"SUM(EXTRACT(EPOCH FROM observed_at - period_start))::bigint AS busy_seconds " +
+			"FROM occupancy_histories GROUP BY bucket, machine_id, status WITH NO DATA;",
+
+		// 2) 刷新策略
+		"SELECT remove_continuous_aggregate_policy('occupancy_hourly', if_exists => TRUE);",
+		fmt.Sprintf("SELECT add_continuous_aggregate_policy('occupancy_hourly', "+
+			"start_offset => INTERVAL '%d hours', end_offset => INTERVAL '1 hour', "+
+			"schedule_interval => INTERVAL '1 hour');", int(startOffset.Hours())),
+
+		// 3) 原始历史与聚合的保留策略
+		"SELECT remove_retention_policy('occupancy_histories', if_exists => TRUE);",
+		"SELECT remove_retention_policy('occupancy_hourly', if_exists => TRUE);",
+	}
+	if cfg.HistoryDays > 0 {
+		ddls = append(ddls, fmt.Sprintf(
+			"SELECT add_retention_policy('occupancy_histories', INTERVAL '%d days');", cfg.HistoryDays))
+	}
+	if cfg.RollupDays > 0 {
+		ddls = append(ddls, fmt.Sprintf(
+			"SELECT add_retention_policy('occupancy_hourly', INTERVAL '%d days');", cfg.RollupDays))
 	}
 
 	for _, ddl := range ddls {
@@ -0,0 +1,34 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"laundry-status-backend/internal/model"
+	"laundry-status-backend/internal/testdb"
+)
+
+func TestBackfillDormAliases(t *testing.T) {
+	testDB := testdb.New(t, &model.Dorm{}, &model.DormAlias{}, &model.Machine{})
+
+	// Stored names went through the old 栋 -> 东 rewrite; the machines keep the raw upstream names.
+	require.NoError(t, testDB.Create(&[]model.Dorm{{ID: 1, Name: "东区3东"}, {ID: 2, Name: "西1"}, {ID: 3, Name: "东5"}}).Error)
+	require.NoError(t, testDB.Create(&[]model.Machine{
+		{ID: 11, DormID: 1, DisplayName: "东区3栋2-1", FloorCode: "2"},
+		{ID: 12, DormID: 1, DisplayName: "东区3栋#3-2", FloorCode: "3"},
+		{ID: 21, DormID: 2, DisplayName: "西1#1-1", FloorCode: "1"},
+		{ID: 31, DormID: 3, DisplayName: "东5#1-1", FloorCode: "1"},
+	}).Error)
+
+	require.NoError(t, backfillDormAliases(testDB))
+
+	var aliases []model.DormAlias
+	require.NoError(t, testDB.Order("alias").Find(&aliases).Error)
+	got := make(map[string]int64, len(aliases))
+	for _, a := range aliases {
+		got[a.Alias] = a.DormID
+	}
+	assert.Equal(t, map[string]int64{"东区3东": 1, "东区3栋": 1, "西1": 2, "东5": 3}, got)
+}
@@ -32,7 +32,7 @@ func TestOccupancyLifecycle(t *testing.T) {
 	defer sqlDB.Close()
 
 	// Run database migrations.
-	err = testDB.AutoMigrate(&model.Machine{}, &model.OccupancyOpen{}, &model.OccupancyHistory{})
+	err = testDB.AutoMigrate(&model.Machine{}, &model.DormAlias{}, &model.QuarantinedMachine{}, &model.MachineOverride{}, &model.OccupancyOpen{}, &model.OccupancyHistory{})
 	assert.NoError(t, err)
 
 	// 2. Create a mock configuration.
@@ -91,8 +91,8 @@ func TestOccupancyLifecycle(t *testing.T) {
 	mockConfig.Scraper.Request.URL = server.URL
 
 	// 4. Instantiate the store and scraper service.
-	gormStore := store.NewGormStore(testDB)
-	scraperService := scraper.NewService(mockConfig, gormStore)
+	gormStore := store.NewGormStore(testDB, nil)
+	scraperService := scraper.NewService(mockConfig, gormStore, nil)
 
 	// 5. Pre-populate the database with a machine to be tested.
 	machine := model.Machine{ID: 101, DormID: 1, DisplayName: "Washing Machine 101"}
@@ -153,7 +153,7 @@ func TestOccupancyHistoryScenarios(t *testing.T) {
 		testDB, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
 		assert.NoError(t, err)
 
-		err = testDB.AutoMigrate(&model.Machine{}, &model.OccupancyOpen{}, &model.OccupancyHistory{})
+		err = testDB.AutoMigrate(&model.Machine{}, &model.DormAlias{}, &model.QuarantinedMachine{}, &model.MachineOverride{}, &model.OccupancyOpen{}, &model.OccupancyHistory{})
 		assert.NoError(t, err)
 
 		mockConfig := &config.Config{
@@ -193,8 +193,8 @@ func TestOccupancyHistoryScenarios(t *testing.T) {
 		}))
 
 		mockConfig.Scraper.Request.URL = server.URL
-		gormStore := store.NewGormStore(testDB)
-		scraperService := scraper.NewService(mockConfig, gormStore)
+		gormStore := store.NewGormStore(testDB, nil)
+		scraperService := scraper.NewService(mockConfig, gormStore, nil)
 
 		// This function is returned to the test to control the mock server's responses.
 		setResponses := func(responses [][]store.ApiItem) {
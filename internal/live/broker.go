@@ -0,0 +1,245 @@
+// Package live fans committed occupancy changes out to streaming clients.
+package live
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"laundry-status-backend/internal/store"
+)
+
+// ErrClosed is returned when subscribing to a broker that has shut down.
+var ErrClosed = errors.New("live broker is closed")
+
+// Event carries the changes of one dorm from one scrape cycle.
+type Event struct {
+	ID      uint64
+	DormID  int64
+	At      time.Time
+	Changes []store.MachineChange
+}
+
+// Subscription receives the events matching its filter. Its channel is
+// closed when the subscriber falls too far behind or the broker closes.
+type Subscription struct {
+	broker *Broker
+	match  func(Event) bool
+	events chan Event
+	head   uint64
+}
+
+// Head returns the ID of the last event published before subscribing.
+func (s *Subscription) Head() uint64 {
+	return s.head
+}
+
+// Events returns the channel events are delivered on.
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Close unsubscribes. It is safe to call more than once.
+func (s *Subscription) Close() {
+	s.broker.remove(s)
+}
+
+// DataVersion identifies a state of the data; it increases on every change.
+type DataVersion struct {
+	Seq        uint64
+	ModifiedAt time.Time
+}
+
+// Broker keeps the most recent events for replay and delivers new ones to
+// subscribers without ever blocking the publisher. It also versions the data
+// per dorm so that caches can tell when a response went stale.
+type Broker struct {
+	mu      sync.Mutex
+	nextID  uint64
+	history []Event // Ring buffer in publish order
+	start   int
+	size    int
+	buffer  int
+	subs    map[*Subscription]struct{}
+	closed  bool
+
+	epoch     time.Time // Versions are only comparable within one process
+	seq       uint64
+	all       DataVersion
+	touched   DataVersion // Last change affecting every dorm
+	dorms     map[int64]DataVersion
+	lastCycle time.Time
+}
+
+// NewBroker creates a broker that retains the last history events for replay
+// and buffers up to buffer undelivered events per subscriber.
+func NewBroker(history, buffer int) *Broker {
+	epoch := time.Now().UTC().Truncate(time.Second)
+	return &Broker{
+		history: make([]Event, history),
+		buffer:  buffer,
+		subs:    make(map[*Subscription]struct{}),
+		epoch:   epoch,
+		all:     DataVersion{ModifiedAt: epoch},
+		touched: DataVersion{ModifiedAt: epoch},
+		dorms:   make(map[int64]DataVersion),
+	}
+}
+
+// Publish groups the changes of one scrape cycle by dorm and delivers one
+// event per dorm. Subscribers whose buffer is full are dropped. Cycles
+// without changes are published too, to record that the scrape happened.
+func (b *Broker) Publish(at time.Time, changes []store.MachineChange) {
+	b.mu.Lock()
+	if at.After(b.lastCycle) {
+		b.lastCycle = at
+	}
+	b.mu.Unlock()
+	if len(changes) == 0 {
+		return
+	}
+
+	var dorms []int64
+	byDorm := make(map[int64][]store.MachineChange)
+	for _, c := range changes {
+		if _, ok := byDorm[c.DormID]; !ok {
+			dorms = append(dorms, c.DormID)
+		}
+		byDorm[c.DormID] = append(byDorm[c.DormID], c)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return
+	}
+
+	for _, dormID := range dorms {
+		b.nextID++
+		event := Event{ID: b.nextID, DormID: dormID, At: at, Changes: byDorm[dormID]}
+		b.remember(event)
+
+		b.seq++
+		b.dorms[dormID] = DataVersion{Seq: b.seq, ModifiedAt: at}
+		b.all = b.dorms[dormID]
+
+		for sub := range b.subs {
+			if !sub.match(event) {
+				continue
+			}
+			select {
+			case sub.events <- event:
+			default:
+				// 客户端消费过慢，断开后由其凭 Last-Event-ID 重连补齐
+				b.drop(sub)
+			}
+		}
+	}
+}
+
+// Subscribe registers a subscriber for the events matching match. Events
+// after lastEventID that are still retained are returned for replay; ok is
+// false when some of them have already been evicted and the client must
+// reload its state. A lastEventID of 0 requests no replay.
+func (b *Broker) Subscribe(lastEventID uint64, match func(Event) bool) (sub *Subscription, replay []Event, ok bool, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil, nil, false, ErrClosed
+	}
+
+	ok = true
+	if lastEventID > 0 {
+		if lastEventID > b.nextID {
+			// 服务重启后事件序号重新计数，旧的 ID 已无意义
+			ok = false
+		} else if lastEventID < b.nextID && (b.size == 0 || b.history[b.start].ID > lastEventID+1) {
+			ok = false
+		}
+		for i := 0; i < b.size; i++ {
+			event := b.history[(b.start+i)%len(b.history)]
+			if event.ID > lastEventID && match(event) {
+				replay = append(replay, event)
+			}
+		}
+	}
+
+	sub = &Subscription{broker: b, match: match, events: make(chan Event, b.buffer), head: b.nextID}
+	b.subs[sub] = struct{}{}
+	return sub, replay, ok, nil
+}
+
+// Touch marks the data of every dorm as changed, for edits made outside the
+// scrape cycle such as admin corrections.
+func (b *Broker) Touch(at time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.seq++
+	b.touched = DataVersion{Seq: b.seq, ModifiedAt: at}
+	b.all = b.touched
+}
+
+// Version returns the current data version of a dorm, or of all dorms when
+// dormID is 0.
+func (b *Broker) Version(dormID int64) DataVersion {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if dormID == 0 {
+		return b.all
+	}
+	if v, ok := b.dorms[dormID]; ok && v.Seq > b.touched.Seq {
+		return v
+	}
+	return b.touched
+}
+
+// Epoch returns the broker's start time; versions of different epochs are unrelated.
+func (b *Broker) Epoch() time.Time {
+	return b.epoch
+}
+
+// LastCycle returns when the most recent scrape cycle was published, or the
+// zero time if none has been yet.
+func (b *Broker) LastCycle() time.Time {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.lastCycle
+}
+
+// Close ends every subscription and rejects new ones.
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for sub := range b.subs {
+		b.drop(sub)
+	}
+}
+
+func (b *Broker) remember(event Event) {
+	if len(b.history) == 0 {
+		return
+	}
+	if b.size < len(b.history) {
+		b.history[(b.start+b.size)%len(b.history)] = event
+		b.size++
+		return
+	}
+	b.history[b.start] = event
+	b.start = (b.start + 1) % len(b.history)
+}
+
+func (b *Broker) remove(sub *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.drop(sub)
+}
+
+// drop must be called with b.mu held.
+func (b *Broker) drop(sub *Subscription) {
+	if _, ok := b.subs[sub]; !ok {
+		return
+	}
+	delete(b.subs, sub)
+	close(sub.events)
+}
@@ -0,0 +1,106 @@
+package live
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"laundry-status-backend/internal/store"
+)
+
+func dorm(id int64) func(Event) bool {
+	return func(e Event) bool { return e.DormID == id }
+}
+
+func TestBroker_PublishAndReplay(t *testing.T) {
+	b := NewBroker(3, 4)
+	now := time.Now()
+
+	sub, replay, ok, err := b.Subscribe(0, dorm(1))
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Empty(t, replay)
+
+	b.Publish(now, []store.MachineChange{
+		{MachineID: 11, DormID: 1},
+		{MachineID: 21, DormID: 2},
+		{MachineID: 12, DormID: 1},
+	})
+	e := <-sub.Events()
+	assert.Equal(t, uint64(1), e.ID)
+	assert.Len(t, e.Changes, 2, "changes of one cycle are grouped per dorm")
+	select {
+	case e := <-sub.Events():
+		t.Fatalf("unexpected event for dorm %d", e.DormID)
+	default:
+	}
+
+	b.Publish(now, []store.MachineChange{{MachineID: 11, DormID: 1}})
+
+	// Resuming after event 1 replays the retained events of the same dorm.
+	resumed, replay, ok, err := b.Subscribe(1, dorm(1))
+	require.NoError(t, err)
+	assert.True(t, ok)
+	require.Len(t, replay, 1)
+	assert.Equal(t, uint64(3), replay[0].ID)
+	assert.Equal(t, uint64(3), resumed.Head())
+	resumed.Close()
+	resumed.Close()
+
+	// Events 1 and 2 are evicted from the three-event history.
+	b.Publish(now, []store.MachineChange{{MachineID: 11, DormID: 1}})
+	b.Publish(now, []store.MachineChange{{MachineID: 11, DormID: 1}})
+	_, _, ok, err = b.Subscribe(1, dorm(1))
+	require.NoError(t, err)
+	assert.False(t, ok, "a gap must be reported")
+	_, _, ok, _ = b.Subscribe(99, dorm(1))
+	assert.False(t, ok, "IDs from before a restart must be reported")
+}
+
+func TestBroker_DropsSlowSubscribersAndCloses(t *testing.T) {
+	b := NewBroker(8, 1)
+	slow, _, _, err := b.Subscribe(0, dorm(1))
+	require.NoError(t, err)
+	other, _, _, err := b.Subscribe(0, dorm(2))
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		b.Publish(time.Now(), []store.MachineChange{{DormID: 1}})
+	}
+	<-slow.Events()
+	_, open := <-slow.Events()
+	assert.False(t, open, "a subscriber that falls behind is disconnected")
+
+	b.Close()
+	_, open = <-other.Events()
+	assert.False(t, open)
+	_, _, _, err = b.Subscribe(0, dorm(1))
+	assert.ErrorIs(t, err, ErrClosed)
+	b.Publish(time.Now(), []store.MachineChange{{DormID: 1}})
+}
+
+func TestBroker_Versions(t *testing.T) {
+	b := NewBroker(8, 1)
+	t0 := time.Now()
+	assert.Zero(t, b.Version(1).Seq)
+	assert.True(t, b.LastCycle().IsZero())
+
+	b.Publish(t0, nil)
+	assert.Equal(t, t0, b.LastCycle(), "cycles without changes still count as scrapes")
+	assert.Zero(t, b.Version(0).Seq)
+
+	t1 := t0.Add(time.Minute)
+	b.Publish(t1, []store.MachineChange{{DormID: 1}})
+	v1 := b.Version(1)
+	assert.NotZero(t, v1.Seq)
+	assert.Equal(t, t1, v1.ModifiedAt)
+	assert.Zero(t, b.Version(2).Seq, "other dorms are unaffected")
+	assert.Equal(t, v1, b.Version(0))
+
+	t2 := t1.Add(time.Minute)
+	b.Touch(t2)
+	assert.Greater(t, b.Version(1).Seq, v1.Seq)
+	assert.Equal(t, t2, b.Version(2).ModifiedAt)
+}
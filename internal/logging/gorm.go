@@ -0,0 +1,97 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+	"gorm.io/gorm/utils"
+)
+
+// GormLogger routes gorm's logs through slog. Failed statements are logged
+// as errors and statements slower than the threshold as warnings; every
+// other statement is only logged at debug level. A missing record is not
+// treated as a failure, since callers handle gorm.ErrRecordNotFound.
+type GormLogger struct {
+	logger        *slog.Logger
+	slowThreshold time.Duration
+	mode          gormlogger.LogLevel
+}
+
+// NewGormLogger creates a gorm logger writing to logger. A zero
+// slowThreshold disables slow query warnings.
+func NewGormLogger(logger *slog.Logger, slowThreshold time.Duration) *GormLogger {
+	return &GormLogger{logger: logger, slowThreshold: slowThreshold, mode: gormlogger.Info}
+}
+
+// LogMode implements gormlogger.Interface. gorm uses it for db.Debug() and
+// silent sessions; slog's level still applies on top of it.
+func (l *GormLogger) LogMode(mode gormlogger.LogLevel) gormlogger.Interface {
+	clone := *l
+	clone.mode = mode
+	return &clone
+}
+
+// Info implements gormlogger.Interface.
+func (l *GormLogger) Info(ctx context.Context, msg string, args ...any) {
+	if l.mode >= gormlogger.Info {
+		l.logger.InfoContext(ctx, fmt.Sprintf(msg, args...), "caller", utils.FileWithLineNum())
+	}
+}
+
+// Warn implements gormlogger.Interface.
+func (l *GormLogger) Warn(ctx context.Context, msg string, args ...any) {
+	if l.mode >= gormlogger.Warn {
+		l.logger.WarnContext(ctx, fmt.Sprintf(msg, args...), "caller", utils.FileWithLineNum())
+	}
+}
+
+// Error implements gormlogger.Interface.
+func (l *GormLogger) Error(ctx context.Context, msg string, args ...any) {
+	if l.mode >= gormlogger.Error {
+		l.logger.ErrorContext(ctx, fmt.Sprintf(msg, args...), "caller", utils.FileWithLineNum())
+	}
+}
+
+// Trace implements gormlogger.Interface; gorm calls it after every statement.
+func (l *GormLogger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	if l.mode <= gormlogger.Silent {
+		return
+	}
+	elapsed := time.Since(begin)
+
+	var level slog.Level
+	var msg string
+	switch {
+	case err != nil && l.mode >= gormlogger.Error && !errors.Is(err, gorm.ErrRecordNotFound):
+		level, msg = slog.LevelError, "SQL failed"
+	case l.slowThreshold > 0 && elapsed > l.slowThreshold && l.mode >= gormlogger.Warn:
+		level, msg = slog.LevelWarn, "slow SQL"
+	case l.mode >= gormlogger.Info:
+		level, msg = slog.LevelDebug, "SQL"
+	default:
+		return
+	}
+	if !l.logger.Enabled(ctx, level) {
+		return
+	}
+
+	sql, rows := fc()
+	attrs := []slog.Attr{
+		slog.Duration("elapsed", elapsed),
+		slog.Int64("rows", rows),
+		slog.String("sql", sql),
+		slog.String("caller", utils.FileWithLineNum()),
+	}
+	if level == slog.LevelError {
+		attrs = append(attrs, slog.Any("error", err))
+	}
+	if level == slog.LevelWarn {
+		attrs = append(attrs, slog.Duration("threshold", l.slowThreshold))
+	}
+	l.logger.LogAttrs(ctx, level, msg, attrs...)
+}
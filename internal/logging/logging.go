@@ -0,0 +1,94 @@
+// Package logging configures the process-wide log/slog logger and carries
+// correlation IDs through contexts. Records logged with a context, e.g.
+// slog.InfoContext(ctx, ...), get the request and scrape-cycle IDs stored in
+// it attached automatically.
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"log/slog"
+
+	"laundry-status-backend/config"
+)
+
+type ctxKey int
+
+const (
+	requestIDKey ctxKey = iota
+	cycleIDKey
+)
+
+// WithRequestID returns a context whose log records carry the request ID.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+// RequestID returns the request ID stored in ctx, or "".
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// WithCycleID returns a context whose log records carry the scrape-cycle ID.
+func WithCycleID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, cycleIDKey, id)
+}
+
+// CycleID returns the scrape-cycle ID stored in ctx, or "".
+func CycleID(ctx context.Context) string {
+	id, _ := ctx.Value(cycleIDKey).(string)
+	return id
+}
+
+// NewID returns a random 16 character hex ID.
+func NewID() string {
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// New creates a logger writing to w in the configured format and level.
+func New(w io.Writer, cfg config.LogConfig) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: cfg.Level}
+	var handler slog.Handler
+	if cfg.Format == config.LogFormatJSON {
+		handler = slog.NewJSONHandler(w, opts)
+	} else {
+		handler = slog.NewTextHandler(w, opts)
+	}
+	return slog.New(contextHandler{handler})
+}
+
+// Setup makes a logger writing to w the default, which also routes output
+// of the standard log package through it.
+func Setup(w io.Writer, cfg config.LogConfig) *slog.Logger {
+	logger := New(w, cfg)
+	slog.SetDefault(logger)
+	return logger
+}
+
+// contextHandler adds the correlation IDs found in the record's context.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	if id := CycleID(ctx); id != "" {
+		r.AddAttrs(slog.String("cycle_id", id))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
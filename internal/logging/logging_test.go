@@ -0,0 +1,87 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"laundry-status-backend/config"
+)
+
+func decodeLines(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	var records []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var record map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &record))
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestNew_AddsCorrelationIDs(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, config.LogConfig{Level: slog.LevelInfo, Format: config.LogFormatJSON})
+
+	ctx := WithCycleID(WithRequestID(context.Background(), "req-1"), "cycle-1")
+	logger.With("component", "test").InfoContext(ctx, "hello", "n", 1)
+	logger.DebugContext(ctx, "filtered by level")
+	logger.Info("no context")
+
+	records := decodeLines(t, &buf)
+	require.Len(t, records, 2)
+	assert.Equal(t, "hello", records[0]["msg"])
+	assert.Equal(t, "req-1", records[0]["request_id"])
+	assert.Equal(t, "cycle-1", records[0]["cycle_id"])
+	assert.Equal(t, "test", records[0]["component"])
+	assert.NotContains(t, records[1], "request_id")
+	assert.NotContains(t, records[1], "cycle_id")
+}
+
+func TestNew_TextFormat(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, config.LogConfig{Level: slog.LevelDebug, Format: config.LogFormatText})
+	logger.DebugContext(WithRequestID(context.Background(), "req-2"), "hello")
+	assert.Contains(t, buf.String(), "level=DEBUG msg=hello request_id=req-2")
+}
+
+func TestGormLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, config.LogConfig{Level: slog.LevelInfo, Format: config.LogFormatJSON})
+	gormLogger := NewGormLogger(logger, 100*time.Millisecond)
+	ctx := WithCycleID(context.Background(), "cycle-2")
+	sql := func() (string, int64) { return "SELECT 1", 1 }
+
+	gormLogger.Trace(ctx, time.Now(), sql, nil)
+	gormLogger.Trace(ctx, time.Now(), sql, gorm.ErrRecordNotFound)
+	gormLogger.Trace(ctx, time.Now().Add(-time.Second), sql, nil)
+	gormLogger.Trace(ctx, time.Now(), sql, errors.New("boom"))
+
+	records := decodeLines(t, &buf)
+	require.Len(t, records, 2, "fast statements and missing records are only logged at debug level")
+	assert.Equal(t, "WARN", records[0]["level"])
+	assert.Equal(t, "slow SQL", records[0]["msg"])
+	assert.Equal(t, "SELECT 1", records[0]["sql"])
+	assert.Equal(t, "cycle-2", records[0]["cycle_id"])
+	assert.Equal(t, "ERROR", records[1]["level"])
+	assert.Equal(t, "boom", records[1]["error"])
+
+	buf.Reset()
+	debug := NewGormLogger(New(&buf, config.LogConfig{Level: slog.LevelDebug, Format: config.LogFormatJSON}), 0)
+	debug.Trace(ctx, time.Now().Add(-time.Second), sql, nil)
+	debug.LogMode(0).Trace(ctx, time.Now(), sql, nil)
+	records = decodeLines(t, &buf)
+	require.Len(t, records, 1, "a zero threshold disables slow query warnings")
+	assert.Equal(t, "DEBUG", records[0]["level"])
+}
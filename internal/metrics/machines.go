@@ -0,0 +1,65 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"gorm.io/gorm"
+
+	"laundry-status-backend/internal/store"
+)
+
+// machineQueryTimeout bounds the count query run on every Prometheus scrape.
+const machineQueryTimeout = 5 * time.Second
+
+var machinesDesc = prometheus.NewDesc(
+	prometheus.BuildFQName(namespace, "", "machines"),
+	"Visible machines by dorm and state type: idle, occupied, faulty or unknown.",
+	[]string{"dorm", "state"}, nil,
+)
+
+// MachineCollector reports the current number of machines per dorm and state
+// type. It reads the database on collection, so the gauge is always as fresh
+// as the last committed scrape.
+type MachineCollector struct {
+	db        *gorm.DB
+	stateType func(int) store.MachineStateType
+}
+
+// NewMachineCollector creates a collector that classifies raw statuses with
+// stateType, the same mapping the scraper applies.
+func NewMachineCollector(db *gorm.DB, stateType func(int) store.MachineStateType) *MachineCollector {
+	return &MachineCollector{db: db, stateType: stateType}
+}
+
+// Describe implements prometheus.Collector.
+func (m *MachineCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- machinesDesc
+}
+
+// Collect implements prometheus.Collector.
+func (m *MachineCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), machineQueryTimeout)
+	defer cancel()
+
+	counts, err := store.CountMachineStates(ctx, m.db)
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(machinesDesc, err)
+		return
+	}
+
+	// Several raw statuses can map to the same type
+	type key struct{ dorm, state string }
+	totals := make(map[key]int)
+	for _, count := range counts {
+		state := store.StateTypeIdle
+		if count.Status != nil {
+			state = m.stateType(*count.Status)
+		}
+		totals[key{count.Dorm, string(state)}] += count.Machines
+	}
+	for k, n := range totals {
+		ch <- prometheus.MustNewConstMetric(machinesDesc, prometheus.GaugeValue, float64(n), k.dorm, k.state)
+	}
+}
@@ -0,0 +1,63 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"laundry-status-backend/internal/model"
+	"laundry-status-backend/internal/store"
+	"laundry-status-backend/internal/testdb"
+)
+
+func newTestDB(t *testing.T) *gorm.DB {
+	return testdb.New(t, &model.Dorm{}, &model.Machine{}, &model.MachineOverride{}, &model.OccupancyOpen{})
+}
+
+func TestMachineCollector(t *testing.T) {
+	db := newTestDB(t)
+
+	require.NoError(t, db.Create(&[]model.Dorm{{ID: 1, Name: "东3"}, {ID: 2, Name: "西1"}}).Error)
+	require.NoError(t, db.Create(&[]model.Machine{
+		{ID: 11, DormID: 1, DisplayName: "东3#1-1"},
+		{ID: 12, DormID: 1, DisplayName: "东3#1-2"},
+		{ID: 13, DormID: 1, DisplayName: "东3#2-1"},
+		{ID: 14, DormID: 1, DisplayName: "东3#2-2"},
+		{ID: 15, DormID: 1, DisplayName: "东3#3-1"},
+		{ID: 21, DormID: 2, DisplayName: "西1#1-1"},
+	}).Error)
+	require.NoError(t, db.Create(&model.MachineOverride{MachineID: 15, Hidden: true}).Error)
+	now := time.Now().UTC()
+	require.NoError(t, db.Create(&[]model.OccupancyOpen{
+		{MachineID: 12, ObservedAt: now, Status: 2, Message: "洗涤中"},
+		{MachineID: 13, ObservedAt: now, Status: 3, Message: "脱水中"},
+		{MachineID: 14, ObservedAt: now, Status: 9, Message: "故障"},
+		{MachineID: 15, ObservedAt: now, Status: 2, Message: "洗涤中"},
+	}).Error)
+
+	stateType := func(code int) store.MachineStateType {
+		switch code {
+		case 2, 3:
+			return store.StateTypeOccupied
+		case 9:
+			return store.StateTypeFaulty
+		}
+		return store.StateTypeUnknown
+	}
+
+	expected := `
+# HELP laundry_machines Visible machines by dorm and state type: idle, occupied, faulty or unknown.
+# TYPE laundry_machines gauge
+laundry_machines{dorm="东3",state="faulty"} 1
+laundry_machines{dorm="东3",state="idle"} 1
+laundry_machines{dorm="东3",state="occupied"} 2
+laundry_machines{dorm="西1",state="idle"} 1
+`
+	err := testutil.CollectAndCompare(NewMachineCollector(db, stateType), strings.NewReader(expected))
+	assert.NoError(t, err)
+}
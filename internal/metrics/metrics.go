@@ -0,0 +1,131 @@
+// Package metrics defines the Prometheus metrics exported at /metrics.
+// Collectors are registered on Registry rather than the global default
+// registry, so only metrics of this service are exposed.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "laundry"
+
+// Registry holds every metric of the service.
+var Registry = prometheus.NewRegistry()
+
+// Scraper metrics.
+var (
+	ScrapeDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "scrape",
+		Name:      "duration_seconds",
+		Help:      "Duration of scrape cycles.",
+		Buckets:   []float64{0.5, 1, 2, 5, 10, 20, 30, 60, 120},
+	})
+	ScrapePages = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "scrape",
+		Name:      "pages_total",
+		Help:      "Upstream pages requested.",
+	})
+	ScrapeErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "scrape",
+		Name:      "errors_total",
+		Help:      "Scrape cycle errors by stage: fetch, machines, occupancy or record.",
+	}, []string{"stage"})
+	ScrapeLastSuccess = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "scrape",
+		Name:      "last_success_timestamp_seconds",
+		Help:      "Start of the last scrape cycle that fetched and stored the whole feed.",
+	})
+	ScrapeTransitions = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "scrape",
+		Name:      "transitions",
+		Help:      "Machine state transitions committed per scrape cycle.",
+		Buckets:   []float64{0, 1, 2, 5, 10, 20, 50, 100, 200, 500},
+	})
+	UpstreamResponses = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "upstream",
+		Name:      "responses_total",
+		Help:      "Upstream API responses by HTTP status code; \"error\" if the request failed without one.",
+	}, []string{"code"})
+)
+
+// Notification metrics.
+var (
+	NotificationsSent = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "notifications",
+		Name:      "sent_total",
+		Help:      "Push notification sends by result: 2xx, 410 (subscription expired) or error.",
+	}, []string{"result"})
+	NotificationQueueDepth = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "notifications",
+		Name:      "queue_depth",
+		Help:      "Notification jobs waiting for a worker.",
+	})
+)
+
+// HTTP metrics.
+var (
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "HTTP request latency by route template, method and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method", "code"})
+	CacheRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "cache_requests_total",
+		Help:      "Cacheable requests by result: hit, miss or not_modified. The hit ratio is (hit + not_modified) / total.",
+	}, []string{"result"})
+	RateLimited = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "rate_limited_total",
+		Help:      "Requests rejected by rate limiting, by route template.",
+	}, []string{"route"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		ScrapeDuration,
+		ScrapePages,
+		ScrapeErrors,
+		ScrapeLastSuccess,
+		ScrapeTransitions,
+		UpstreamResponses,
+		NotificationsSent,
+		NotificationQueueDepth,
+		HTTPRequestDuration,
+		CacheRequests,
+		RateLimited,
+	)
+}
+
+// Handler serves Registry in the Prometheus exposition format. A failing
+// collector drops its own metrics instead of failing the whole scrape.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{ErrorHandling: promhttp.ContinueOnError})
+}
+
+// Route returns the route label of a request: its route template, or
+// "unmatched" for requests no route handled, which keeps the label bounded.
+func Route(fullPath string) string {
+	if fullPath == "" {
+		return "unmatched"
+	}
+	return fullPath
+}
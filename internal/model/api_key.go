@@ -0,0 +1,41 @@
+package model
+
+import "time"
+
+// API key roles, from least to most privileged. Viewers read admin data,
+// operators also change it and control the scraper, admins also read the
+// configuration and the audit log.
+const (
+	RoleViewer   = "viewer"
+	RoleOperator = "operator"
+	RoleAdmin    = "admin"
+)
+
+// APIKey grants access to the admin API. Only a hash of the key is stored;
+// the key itself is shown once, when it is created.
+type APIKey struct {
+	ID         int64     `gorm:"primaryKey"`
+	Name       string    `gorm:"size:128;not null"`
+	Prefix     string    `gorm:"size:16;not null"`             // Leading characters of the key, to tell keys apart
+	Hash       string    `gorm:"size:64;not null;uniqueIndex"` // Hex SHA-256 of the key
+	Role       string    `gorm:"size:16;not null"`
+	CreatedAt  time.Time `gorm:"not null"`
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+}
+
+// AuditLog records a change made through the admin API.
+type AuditLog struct {
+	ID        int64     `gorm:"primaryKey"`
+	At        time.Time `gorm:"not null;index"`
+	APIKeyID  int64     `gorm:"not null;index"`
+	KeyName   string    `gorm:"size:128;not null"` // Kept in case the key is deleted
+	Role      string    `gorm:"size:16;not null"`
+	Method    string    `gorm:"size:8;not null"`
+	Route     string    `gorm:"size:256;not null"` // Route template, e.g. /api/admin/machines/:machine_id/override
+	Path      string    `gorm:"size:512;not null"`
+	Body      string    `gorm:"size:2048"` // Request body, truncated
+	Status    int       `gorm:"not null"`
+	RequestID string    `gorm:"size:64"`
+	ClientIP  string    `gorm:"size:64"`
+}
@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+// DormAlias maps a dorm name as parsed from upstream data to its canonical dorm.
+// Every parsed name resolves through this table, so renames and merges never
+// split a building's machines or history across several Dorm rows.
+type DormAlias struct {
+	Alias     string    `gorm:"primaryKey;size:128"`
+	DormID    int64     `gorm:"index;not null"`
+	CreatedAt time.Time `gorm:"not null"`
+
+	// Associations
+	Dorm Dorm `gorm:"constraint:OnDelete:CASCADE"`
+}
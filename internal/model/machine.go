@@ -2,6 +2,13 @@ package model
 
 import "time"
 
+// Machine kinds recognised by default. Kind rules may introduce others.
+const (
+	MachineKindWasher     = "washer"
+	MachineKindDryer      = "dryer"
+	MachineKindShoeWasher = "shoe_washer"
+)
+
 // Machine represents a washing machine's basic information.
 type Machine struct {
 	ID          int64  `gorm:"primaryKey"` // Upstream ID
@@ -12,9 +19,12 @@ type Machine struct {
 	FloorCode   string `gorm:"size:32"`
 	Floor       int
 	Seq         int
+	Kind        string `gorm:"size:32;not null;default:washer;index"`
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
 
+	LastConfirmedAt *time.Time // Last scrape whose feed included the machine
+
 	// Associations
 	Dorm              Dorm                `gorm:"constraint:OnDelete:CASCADE"`
 	PushSubscriptions []*PushSubscription `gorm:"many2many:subscription_machine_mapping;"`
@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+// MachineOverride holds manual corrections for a machine's metadata. Nil
+// fields keep the upstream value. Overrides are merged into the machines row
+// on every upsert, so upstream changes never clobber them.
+type MachineOverride struct {
+	MachineID   int64   `gorm:"primaryKey;autoIncrement:false"`
+	DisplayName *string `gorm:"size:256"`
+	DormID      *int64
+	Floor       *int
+	Seq         *int
+	Hidden      bool   `gorm:"not null;default:false;index"`
+	Notes       string `gorm:"size:1024"`
+	UpdatedAt   time.Time
+}
@@ -0,0 +1,35 @@
+package model
+
+import "time"
+
+// OccupancyHourly is the hourly per-machine rollup of occupancy_histories.
+// Sessions are attributed to the bucket in which their end was observed, which
+// is the hypertable's time dimension. With TimescaleDB this is a continuous
+// aggregate; otherwise it is a plain table maintained by the retention job.
+type OccupancyHourly struct {
+	Bucket      time.Time `gorm:"primaryKey"`
+	MachineID   int64     `gorm:"primaryKey;autoIncrement:false"`
+	Status      int       `gorm:"primaryKey;autoIncrement:false"`
+	Sessions    int64     `gorm:"not null"`
+	BusySeconds int64     `gorm:"not null"` // Observed duration: observed_at - period_start
+}
+
+// TableName keeps the table name identical to the continuous aggregate's.
+func (OccupancyHourly) TableName() string {
+	return "occupancy_hourly"
+}
+
+// DormOccupancyHourly is the hourly per-dorm view over OccupancyHourly.
+type DormOccupancyHourly struct {
+	Bucket      time.Time
+	DormID      int64
+	Status      int
+	Machines    int64 // Distinct machines with at least one session in the bucket
+	Sessions    int64
+	BusySeconds int64
+}
+
+// TableName returns the name of the read-only per-dorm view.
+func (DormOccupancyHourly) TableName() string {
+	return "occupancy_hourly_dorm"
+}
@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+// QuarantinedMachine is an upstream device whose name could not be parsed.
+// It stays here, instead of in machines, until it parses or an admin promotes
+// it by assigning its dorm, floor and sequence number manually.
+type QuarantinedMachine struct {
+	ID          int64  `gorm:"primaryKey;autoIncrement:false"` // Upstream ID
+	RawName     string `gorm:"size:256;not null"`
+	FloorCode   string `gorm:"size:32"`
+	IMEI        string `gorm:"size:64"`
+	DeviceID    int64
+	Error       string    `gorm:"size:512;not null"`
+	FirstSeenAt time.Time `gorm:"not null"`
+	LastSeenAt  time.Time `gorm:"not null;index"`
+}
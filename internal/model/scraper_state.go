@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+// ScraperStateID is the primary key of the single scraper_states row.
+const ScraperStateID = 1
+
+// ScraperState records the outcome of the scrape cycles, so readers can tell
+// how fresh the stored occupancy is even across restarts.
+type ScraperState struct {
+	ID                  int `gorm:"primaryKey;autoIncrement:false"`
+	LastAttemptAt       time.Time
+	LastSuccessAt       *time.Time // Last cycle that fetched and stored the whole feed
+	ConsecutiveFailures int        `gorm:"not null;default:0"`
+	LastError           string     `gorm:"size:1024"`
+	UpdatedAt           time.Time
+}
@@ -0,0 +1,48 @@
+package mw
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AccessLog is a middleware that logs every request once it has been served,
+// replacing gin's default logger. Server errors are logged as errors;
+// requests to quietPaths, such as probes, only at debug level.
+func AccessLog(quietPaths ...string) gin.HandlerFunc {
+	quiet := make(map[string]struct{}, len(quietPaths))
+	for _, path := range quietPaths {
+		quiet[path] = struct{}{}
+	}
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		if status >= 500 {
+			level = slog.LevelError
+		} else if _, ok := quiet[c.Request.URL.Path]; ok {
+			level = slog.LevelDebug
+		}
+		ctx := c.Request.Context()
+		if !slog.Default().Enabled(ctx, level) {
+			return
+		}
+
+		attrs := []slog.Attr{
+			slog.String("method", c.Request.Method),
+			slog.String("path", c.Request.URL.Path),
+			slog.String("route", c.FullPath()),
+			slog.Int("status", status),
+			slog.Duration("latency", time.Since(start)),
+			slog.String("client_ip", c.ClientIP()),
+			slog.Int("bytes", c.Writer.Size()),
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, slog.String("errors", c.Errors.String()))
+		}
+		slog.LogAttrs(ctx, level, "HTTP request", attrs...)
+	}
+}
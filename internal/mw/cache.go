@@ -2,13 +2,26 @@ package mw
 
 import (
 	"bytes"
+	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/patrickmn/go-cache"
+
+	"laundry-status-backend/internal/metrics"
 )
 
+// Versioner reports which version of the data a request reads, so cached
+// responses can be invalidated and revalidated when that data changes.
+type Versioner interface {
+	// Version returns an opaque version tag and the time the data last changed.
+	Version(c *gin.Context) (tag string, modified time.Time)
+	// MaxAge returns how long clients may reuse a response without asking again.
+	MaxAge() time.Duration
+}
+
 type cachedResponse struct {
 	status  int
 	headers http.Header
@@ -30,26 +43,72 @@ func (w bodyCacheWriter) WriteString(s string) (int, error) {
 	return w.ResponseWriter.WriteString(s)
 }
 
-// Cache is a middleware for in-memory caching of GET requests.
-func Cache(store *cache.Cache, duration time.Duration) gin.HandlerFunc {
+func (w bodyCacheWriter) WriteHeader(code int) {
+	// Errors must not be reused by clients as if they were the data
+	if code < 200 || code >= 300 {
+		h := w.ResponseWriter.Header()
+		h.Del("ETag")
+		h.Del("Last-Modified")
+		h.Set("Cache-Control", "no-store")
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Cache is a middleware for in-memory caching of GET requests. Responses are
+// kept for at most ttl and are keyed by the data version reported by
+// versions, so a new version invalidates them. It also sets ETag,
+// Last-Modified and Cache-Control, and answers conditional requests for
+// unchanged data with 304. versions may be nil for plain TTL caching.
+func Cache(store *cache.Cache, ttl time.Duration, versions Versioner) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		if c.Request.Method != http.MethodGet {
 			c.Next()
 			return
 		}
 
+		var validators http.Header
 		key := c.Request.RequestURI
+		if versions != nil {
+			tag, modified := versions.Version(c)
+			etag := `W/"` + tag + `"`
+			validators = http.Header{}
+			validators.Set("ETag", etag)
+			if !modified.IsZero() {
+				validators.Set("Last-Modified", modified.UTC().Format(http.TimeFormat))
+			}
+			validators.Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(versions.MaxAge().Seconds())))
+
+			if notModified(c.Request, etag, modified) {
+				metrics.CacheRequests.WithLabelValues("not_modified").Inc()
+				for k, v := range validators {
+					c.Writer.Header()[k] = v
+				}
+				c.AbortWithStatus(http.StatusNotModified)
+				return
+			}
+			key += "\x00" + tag
+		}
+
 		if resp, found := store.Get(key); found {
+			metrics.CacheRequests.WithLabelValues("hit").Inc()
 			cached := resp.(cachedResponse)
-			c.Writer.WriteHeader(cached.status)
 			for k, v := range cached.headers {
 				c.Writer.Header()[k] = v
 			}
+			// Max-age shrinks as the next scrape approaches
+			for k, v := range validators {
+				c.Writer.Header()[k] = v
+			}
+			c.Writer.WriteHeader(cached.status)
 			c.Writer.Write(cached.body)
 			c.Abort()
 			return
 		}
 
+		metrics.CacheRequests.WithLabelValues("miss").Inc()
+		for k, v := range validators {
+			c.Writer.Header()[k] = v
+		}
 		blw := &bodyCacheWriter{body: bytes.NewBuffer(nil), ResponseWriter: c.Writer}
 		c.Writer = blw
 
@@ -63,7 +122,33 @@ func Cache(store *cache.Cache, duration time.Duration) gin.HandlerFunc {
 				headers: blw.Header().Clone(),
 				body:    blw.body.Bytes(),
 			}
-			store.Set(key, response, duration)
+			// Every request keeps its own ID
+			response.headers.Del(RequestIDHeader)
+			store.Set(key, response, ttl)
+		}
+	}
+}
+
+// notModified reports whether the client already holds the current version.
+// If-None-Match takes precedence over If-Modified-Since (RFC 9110 13.2.2).
+func notModified(r *http.Request, etag string, modified time.Time) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		for _, candidate := range strings.Split(inm, ",") {
+			candidate = strings.TrimSpace(candidate)
+			if candidate == "*" || weakTag(candidate) == weakTag(etag) {
+				return true
+			}
 		}
+		return false
 	}
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" && !modified.IsZero() {
+		since, err := http.ParseTime(ims)
+		return err == nil && !modified.Truncate(time.Second).After(since)
+	}
+	return false
+}
+
+// weakTag strips the weak indicator, since GET uses weak comparison.
+func weakTag(tag string) string {
+	return strings.TrimPrefix(tag, "W/")
 }
@@ -0,0 +1,73 @@
+package mw
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/patrickmn/go-cache"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeVersions struct {
+	tag      string
+	modified time.Time
+}
+
+func (v *fakeVersions) Version(*gin.Context) (string, time.Time) { return v.tag, v.modified }
+func (v *fakeVersions) MaxAge() time.Duration                    { return 42 * time.Second }
+
+func TestCache_VersionedResponses(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	modified := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	versions := &fakeVersions{tag: "v1", modified: modified}
+	calls := 0
+
+	r := gin.New()
+	r.GET("/data", Cache(cache.New(time.Minute, time.Minute), time.Minute, versions), func(c *gin.Context) {
+		calls++
+		c.String(http.StatusOK, "%d", calls)
+	})
+	r.GET("/missing", Cache(cache.New(time.Minute, time.Minute), time.Minute, versions), func(c *gin.Context) {
+		c.String(http.StatusNotFound, "missing")
+	})
+	get := func(path string, header ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		for i := 0; i+1 < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/data")
+	assert.Equal(t, "1", w.Body.String())
+	assert.Equal(t, `W/"v1"`, w.Header().Get("ETag"))
+	assert.Equal(t, "Wed, 01 May 2024 12:00:00 GMT", w.Header().Get("Last-Modified"))
+	assert.Equal(t, "public, max-age=42", w.Header().Get("Cache-Control"))
+
+	w = get("/data")
+	assert.Equal(t, "1", w.Body.String(), "same version is served from cache")
+	assert.Equal(t, `W/"v1"`, w.Header().Get("ETag"))
+
+	w = get("/data", "If-None-Match", `"v0", W/"v1"`)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Body.String())
+	w = get("/data", "If-Modified-Since", modified.Format(http.TimeFormat))
+	assert.Equal(t, http.StatusNotModified, w.Code)
+
+	versions.tag, versions.modified = "v2", modified.Add(time.Minute)
+	w = get("/data", "If-None-Match", `W/"v1"`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "2", w.Body.String(), "a new version invalidates the cached response")
+	w = get("/data", "If-Modified-Since", modified.Format(http.TimeFormat))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = get("/missing")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Empty(t, w.Header().Get("ETag"))
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+}
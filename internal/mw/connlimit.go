@@ -0,0 +1,41 @@
+package mw
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ConnLimit is a middleware that caps the number of concurrent requests per
+// client IP, meant for long-lived streaming routes. A max of 0 disables it.
+func ConnLimit(max int) gin.HandlerFunc {
+	var mu sync.Mutex
+	active := make(map[string]int)
+
+	return func(c *gin.Context) {
+		if max <= 0 {
+			c.Next()
+			return
+		}
+
+		ip := c.ClientIP()
+		mu.Lock()
+		if active[ip] >= max {
+			mu.Unlock()
+			AbortWithError(c, http.StatusTooManyRequests, CodeTooManyConnections, "too many open connections")
+			return
+		}
+		active[ip]++
+		mu.Unlock()
+
+		defer func() {
+			mu.Lock()
+			if active[ip]--; active[ip] <= 0 {
+				delete(active, ip)
+			}
+			mu.Unlock()
+		}()
+		c.Next()
+	}
+}
@@ -0,0 +1,110 @@
+package mw
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Error codes of /api/v2 error responses. Clients branch on them, so they
+// are part of the API contract: add new codes, never rename existing ones.
+const (
+	CodeInvalidParameter     = "invalid_parameter" // A path or query parameter is malformed or out of range
+	CodeInvalidBody          = "invalid_body"      // The request body is not valid JSON or lacks required fields
+	CodeNotFound             = "not_found"         // No such route
+	CodeDormNotFound         = "dorm_not_found"
+	CodeMachineNotFound      = "machine_not_found"
+	CodeSubscriptionNotFound = "subscription_not_found"
+	CodeOverrideNotFound     = "override_not_found"
+	CodeUnauthorized         = "unauthorized"         // Missing or invalid API key
+	CodeForbidden            = "forbidden"            // The API key's role does not allow the action
+	CodeConflict             = "conflict"             // The change conflicts with the current state
+	CodeRateLimited          = "rate_limited"         // Retry after the Retry-After header's seconds
+	CodeTooManyConnections   = "too_many_connections" // Too many open streams from this client
+	CodeInternal             = "internal_error"       // Details are only logged, never returned
+	CodeUnavailable          = "unavailable"          // The service cannot handle the request right now
+)
+
+const (
+	apiVersionKey = "apiVersion"
+	metaKey       = "responseMeta"
+)
+
+// Envelope is the body of every /api/v2 response with content. Exactly one
+// of Data and Error is set.
+type Envelope struct {
+	Data  any            `json:"data,omitempty"`
+	Error *ErrorBody     `json:"error,omitempty"`
+	Meta  map[string]any `json:"meta"`
+}
+
+// ErrorBody describes a failed /api/v2 request.
+type ErrorBody struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// APIVersion is a middleware marking the requests of a route group with the
+// API version whose response format they get. Unmarked requests get the
+// original /api format.
+func APIVersion(version int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(apiVersionKey, version)
+	}
+}
+
+// Version returns the API version of the request, 1 unless marked otherwise.
+func Version(c *gin.Context) int {
+	if v, ok := c.Get(apiVersionKey); ok {
+		return v.(int)
+	}
+	return 1
+}
+
+// SetMeta adds an entry to the meta object of the request's /api/v2
+// response. Version 1 responses ignore it.
+func SetMeta(c *gin.Context, key string, value any) {
+	meta, _ := c.Get(metaKey)
+	m, ok := meta.(map[string]any)
+	if !ok {
+		m = make(map[string]any)
+		c.Set(metaKey, m)
+	}
+	m[key] = value
+}
+
+func responseMeta(c *gin.Context) map[string]any {
+	if meta, ok := c.Get(metaKey); ok {
+		return meta.(map[string]any)
+	}
+	return map[string]any{}
+}
+
+// Respond writes data as the response body: as is for version 1, wrapped in
+// an Envelope with the collected meta for version 2.
+func Respond(c *gin.Context, status int, data any) {
+	if Version(c) < 2 {
+		c.JSON(status, data)
+		return
+	}
+	c.JSON(status, Envelope{Data: data, Meta: responseMeta(c)})
+}
+
+// AbortWithError ends the request with an error response: {"error": message}
+// for version 1, an Envelope with code and message for version 2. message is
+// shown to clients, so it must not contain internal error details.
+func AbortWithError(c *gin.Context, status int, code, message string) {
+	if Version(c) < 2 {
+		c.AbortWithStatusJSON(status, gin.H{"error": message})
+		return
+	}
+	c.AbortWithStatusJSON(status, Envelope{Error: &ErrorBody{Code: code, Message: message}, Meta: responseMeta(c)})
+}
+
+// Recovery answers requests whose handler panicked with a 500 error in the
+// format of their API version. gin.CustomRecovery logs the panic.
+func Recovery() gin.HandlerFunc {
+	return gin.CustomRecovery(func(c *gin.Context, _ any) {
+		AbortWithError(c, http.StatusInternalServerError, CodeInternal, "Internal server error")
+	})
+}
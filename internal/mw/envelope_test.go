@@ -0,0 +1,43 @@
+package mw
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnvelope(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Recovery())
+	for _, group := range []*gin.RouterGroup{r.Group("/v1"), r.Group("/v2", APIVersion(2))} {
+		group.GET("/data", func(c *gin.Context) {
+			SetMeta(c, "page", 1)
+			Respond(c, http.StatusOK, []int{1, 2})
+		})
+		group.GET("/empty", func(c *gin.Context) { Respond(c, http.StatusOK, []int{}) })
+		group.GET("/error", func(c *gin.Context) {
+			AbortWithError(c, http.StatusBadRequest, CodeInvalidParameter, "Invalid dorm ID")
+		})
+		group.GET("/panic", func(c *gin.Context) { panic("boom") })
+	}
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	assert.JSONEq(t, `[1,2]`, get("/v1/data").Body.String())
+	assert.JSONEq(t, `{"data":[1,2],"meta":{"page":1}}`, get("/v2/data").Body.String())
+	assert.JSONEq(t, `{"data":[],"meta":{}}`, get("/v2/empty").Body.String(), "empty data is kept")
+
+	assert.JSONEq(t, `{"error":"Invalid dorm ID"}`, get("/v1/error").Body.String())
+	assert.JSONEq(t, `{"error":{"code":"invalid_parameter","message":"Invalid dorm ID"},"meta":{}}`, get("/v2/error").Body.String())
+
+	w := get("/v2/panic")
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.JSONEq(t, `{"error":{"code":"internal_error","message":"Internal server error"},"meta":{}}`, w.Body.String())
+}
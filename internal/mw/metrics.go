@@ -0,0 +1,22 @@
+package mw
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"laundry-status-backend/internal/metrics"
+)
+
+// Metrics is a middleware recording the latency of every request by route
+// template, so paths with IDs share one series.
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+		metrics.HTTPRequestDuration.
+			WithLabelValues(metrics.Route(c.FullPath()), c.Request.Method, strconv.Itoa(c.Writer.Status())).
+			Observe(time.Since(start).Seconds())
+	}
+}
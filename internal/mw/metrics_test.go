@@ -0,0 +1,62 @@
+package mw
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/patrickmn/go-cache"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+
+	"laundry-status-backend/internal/metrics"
+)
+
+func TestMetrics_RecordsRoutesAndCacheResults(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Metrics())
+	r.GET("/items/:id", Cache(cache.New(time.Minute, time.Minute), time.Minute, &fakeVersions{tag: "v1"}), func(c *gin.Context) {
+		c.String(http.StatusOK, c.Param("id"))
+	})
+
+	get := func(path string, header ...string) {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		for i := 0; i+1 < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	requests := func(route string, codes ...string) uint64 {
+		var total uint64
+		for _, code := range codes {
+			var m dto.Metric
+			observer := metrics.HTTPRequestDuration.WithLabelValues(route, http.MethodGet, code)
+			assert.NoError(t, observer.(prometheus.Metric).Write(&m))
+			total += m.GetHistogram().GetSampleCount()
+		}
+		return total
+	}
+	cached := func(result string) float64 {
+		return testutil.ToFloat64(metrics.CacheRequests.WithLabelValues(result))
+	}
+
+	routed, unmatched := requests("/items/:id", "200", "304"), requests("unmatched", "404")
+	hit, miss, notModified := cached("hit"), cached("miss"), cached("not_modified")
+
+	get("/items/1")
+	get("/items/1")
+	get("/items/2")
+	get("/items/1", "If-None-Match", `W/"v1"`)
+	get("/nowhere")
+
+	assert.Equal(t, routed+4, requests("/items/:id", "200", "304"), "IDs share the route template")
+	assert.Equal(t, unmatched+1, requests("unmatched", "404"))
+	assert.Equal(t, miss+2, cached("miss"))
+	assert.Equal(t, hit+1, cached("hit"))
+	assert.Equal(t, notModified+1, cached("not_modified"))
+}
@@ -0,0 +1,200 @@
+package mw
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net/http"
+	"regexp"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/gin-gonic/gin"
+
+	"laundry-status-backend/docs/openapi"
+)
+
+// OpenAPIOptions selects what the OpenAPI middleware checks.
+type OpenAPIOptions struct {
+	// ValidateRequests rejects requests whose parameters or body do not match
+	// the spec with 400.
+	ValidateRequests bool
+	// ValidateResponses replaces responses that do not match the spec with a
+	// 500 naming the mismatch. Responses are buffered for this, so it is only
+	// meant for tests.
+	ValidateResponses bool
+}
+
+type specRoute struct {
+	route     routers.Route
+	streaming bool // Responses are streamed and never validated
+}
+
+var specParam = regexp.MustCompile(`\{([^}/]+)\}`)
+
+// GinPath converts an OpenAPI path template to gin's syntax, e.g.
+// /dorms/{dorm_id} to /dorms/:dorm_id.
+func GinPath(specPath string) string {
+	return specParam.ReplaceAllString(specPath, ":$1")
+}
+
+// OpenAPI is a middleware checking requests and responses against the
+// operations of doc. Requests are matched to operations by their gin route,
+// so requests no route handles and routes the spec lacks pass unchecked;
+// a test keeps the routes and the spec in sync. Authentication is left to
+// the handlers.
+func OpenAPI(doc *openapi3.T, opts OpenAPIOptions) (gin.HandlerFunc, error) {
+	ops, err := openapi.Operations(doc)
+	if err != nil {
+		return nil, err
+	}
+	routes := make(map[string]specRoute, len(ops))
+	for _, op := range ops {
+		routes[op.Method+" "+GinPath(op.Path)] = specRoute{
+			route: routers.Route{
+				Spec: doc, Server: op.Server, Path: op.SpecPath,
+				PathItem: op.PathItem, Method: op.Method, Operation: op.Operation,
+			},
+			streaming: isStreaming(op.Operation),
+		}
+	}
+	filterOpts := &openapi3filter.Options{
+		AuthenticationFunc:  openapi3filter.NoopAuthenticationFunc,
+		SkipSettingDefaults: true, // Handlers apply their own defaults
+	}
+
+	return func(c *gin.Context) {
+		sr, ok := routes[c.Request.Method+" "+c.FullPath()]
+		if !ok {
+			c.Next()
+			return
+		}
+		route := sr.route
+		params := make(map[string]string, len(c.Params))
+		for _, p := range c.Params {
+			params[p.Key] = p.Value
+		}
+		input := &openapi3filter.RequestValidationInput{
+			Request:    c.Request,
+			PathParams: params,
+			Route:      &route,
+			Options:    filterOpts,
+		}
+
+		if opts.ValidateRequests {
+			if err := openapi3filter.ValidateRequest(c.Request.Context(), input); err != nil {
+				abortInvalidRequest(c, err)
+				return
+			}
+		}
+		if !opts.ValidateResponses || sr.streaming || c.IsWebsocket() {
+			c.Next()
+			return
+		}
+
+		w := &bufferedWriter{ResponseWriter: c.Writer, status: http.StatusOK}
+		c.Writer = w
+		c.Next()
+		c.Writer = w.ResponseWriter
+
+		err := validateResponse(c.Request.Context(), input, w)
+		if err != nil {
+			slog.ErrorContext(c.Request.Context(), "Response does not match the OpenAPI spec", "route", c.FullPath(), "error", err)
+			// Only tests validate responses, so the details may be shown
+			c.Writer.Header().Del("Content-Length")
+			AbortWithError(c, http.StatusInternalServerError, CodeInternal, "response does not match the OpenAPI spec: "+err.Error())
+			return
+		}
+		c.Writer.WriteHeader(w.status)
+		_, _ = c.Writer.Write(w.body.Bytes())
+	}, nil
+}
+
+// abortInvalidRequest answers a request the spec does not allow, naming the
+// parameter or body at fault but not the schema internals.
+func abortInvalidRequest(c *gin.Context, err error) {
+	var reqErr *openapi3filter.RequestError
+	switch {
+	case errors.As(err, &reqErr) && reqErr.Parameter != nil:
+		AbortWithError(c, http.StatusBadRequest, CodeInvalidParameter,
+			fmt.Sprintf("Invalid %s parameter '%s'", reqErr.Parameter.In, reqErr.Parameter.Name))
+	case errors.As(err, &reqErr) && reqErr.RequestBody != nil:
+		AbortWithError(c, http.StatusBadRequest, CodeInvalidBody, "Invalid request body")
+	default:
+		AbortWithError(c, http.StatusBadRequest, CodeInvalidParameter, "Invalid request")
+	}
+}
+
+func validateResponse(ctx context.Context, input *openapi3filter.RequestValidationInput, w *bufferedWriter) error {
+	if w.status == http.StatusNotModified {
+		return nil // Has no body to check
+	}
+	return openapi3filter.ValidateResponse(ctx, &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: input,
+		Status:                 w.status,
+		Header:                 w.Header(),
+		Body:                   noopCloser{bytes.NewReader(w.body.Bytes())},
+		Options: &openapi3filter.Options{
+			IncludeResponseStatus: true,
+			// Only JSON bodies have schemas worth checking, e.g. not the docs page
+			ExcludeResponseBody: !isJSON(w.Header().Get("Content-Type")),
+		},
+	})
+}
+
+func isJSON(contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	return mediaType == "application/json"
+}
+
+// isStreaming reports whether op answers with an event stream or by
+// switching protocols.
+func isStreaming(op *openapi3.Operation) bool {
+	if op.Responses.Value("101") != nil {
+		return true
+	}
+	for _, resp := range op.Responses.Map() {
+		if resp.Value != nil && resp.Value.Content.Get("text/event-stream") != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// bufferedWriter holds back a response until it has been validated.
+type bufferedWriter struct {
+	gin.ResponseWriter
+	status  int
+	written bool
+	body    bytes.Buffer
+}
+
+func (w *bufferedWriter) WriteHeader(code int) {
+	if !w.written {
+		w.status = code
+	}
+}
+
+func (w *bufferedWriter) WriteHeaderNow() {}
+
+func (w *bufferedWriter) Write(b []byte) (int, error) {
+	w.written = true
+	return w.body.Write(b)
+}
+
+func (w *bufferedWriter) WriteString(s string) (int, error) {
+	w.written = true
+	return w.body.WriteString(s)
+}
+
+func (w *bufferedWriter) Status() int   { return w.status }
+func (w *bufferedWriter) Size() int     { return w.body.Len() }
+func (w *bufferedWriter) Written() bool { return w.written }
+
+type noopCloser struct{ *bytes.Reader }
+
+func (noopCloser) Close() error { return nil }
@@ -0,0 +1,94 @@
+package mw
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSpec = `
+openapi: 3.0.3
+info: {title: test, version: "1"}
+servers: [{url: /api}]
+paths:
+  /items/{id}:
+    parameters:
+      - {name: id, in: path, required: true, schema: {type: integer}}
+    get:
+      responses:
+        '200':
+          description: An item.
+          content:
+            application/json:
+              schema:
+                type: object
+                properties: {name: {type: string}}
+                required: [name]
+    put:
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              type: object
+              properties: {name: {type: string}}
+              required: [name]
+      responses:
+        '204': {description: Saved.}
+`
+
+func TestOpenAPI(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	doc, err := openapi3.NewLoader().LoadFromData([]byte(testSpec))
+	require.NoError(t, err)
+	validate, err := OpenAPI(doc, OpenAPIOptions{ValidateRequests: true, ValidateResponses: true})
+	require.NoError(t, err)
+
+	r := gin.New()
+	api := r.Group("/api", APIVersion(2), validate)
+	api.GET("/items/:id", func(c *gin.Context) {
+		if c.Param("id") == "2" {
+			c.JSON(http.StatusOK, gin.H{"title": "drifted"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"name": "washer"})
+	})
+	api.PUT("/items/:id", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	api.GET("/undocumented", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do(http.MethodGet, "/api/items/1", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"name":"washer"}`, w.Body.String())
+	assert.Equal(t, http.StatusNoContent, do(http.MethodPut, "/api/items/1", `{"name":"dryer"}`).Code)
+
+	w = do(http.MethodGet, "/api/items/abc", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.JSONEq(t, `{"error":{"code":"invalid_parameter","message":"Invalid path parameter 'id'"},"meta":{}}`, w.Body.String())
+
+	w = do(http.MethodPut, "/api/items/1", `{"title":"dryer"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.JSONEq(t, `{"error":{"code":"invalid_body","message":"Invalid request body"},"meta":{}}`, w.Body.String())
+
+	w = do(http.MethodGet, "/api/items/2", "")
+	assert.Equal(t, http.StatusInternalServerError, w.Code, "responses not matching the spec are replaced")
+	assert.Contains(t, w.Body.String(), `"code":"internal_error","message":"response does not match the OpenAPI spec`)
+
+	w = do(http.MethodGet, "/api/undocumented", "")
+	assert.Equal(t, http.StatusOK, w.Code, "routes missing from the spec are left to the route parity test")
+	assert.Equal(t, "ok", w.Body.String())
+}
@@ -1,28 +1,55 @@
 package mw
 
 import (
+	"math"
 	"net/http"
+	"strconv"
 	"sync"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"golang.org/x/time/rate"
+
+	"laundry-status-backend/internal/metrics"
 )
 
-// IPRateLimiter stores a rate limiter for each IP address.
+// minIdleTTL is the shortest time an unused limiter is kept.
+const minIdleTTL = time.Minute
+
+type ipLimiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// IPRateLimiter stores a rate limiter for each IP address. Limiters that
+// have been idle long enough to refill completely carry no state and are
+// evicted, so the map stays bounded by the number of recently active clients.
 type IPRateLimiter struct {
-	ips map[string]*rate.Limiter
-	mu  *sync.RWMutex
-	r   rate.Limit
-	b   int
+	ips       map[string]*ipLimiter
+	mu        *sync.Mutex
+	r         rate.Limit
+	b         int
+	idle      time.Duration
+	lastSweep time.Time
+	now       func() time.Time
 }
 
 // NewIPRateLimiter creates a new IPRateLimiter.
 func NewIPRateLimiter(r rate.Limit, b int) *IPRateLimiter {
+	idle := minIdleTTL
+	if r > 0 {
+		// Time to refill an empty bucket; a limiter idle this long is as good as new
+		if refill := time.Duration(float64(b) / float64(r) * float64(time.Second)); refill > idle {
+			idle = refill
+		}
+	}
 	return &IPRateLimiter{
-		ips: make(map[string]*rate.Limiter),
-		mu:  &sync.RWMutex{},
-		r:   r,
-		b:   b,
+		ips:  make(map[string]*ipLimiter),
+		mu:   &sync.Mutex{},
+		r:    r,
+		b:    b,
+		idle: idle,
+		now:  time.Now,
 	}
 }
 
@@ -31,29 +58,63 @@ func (i *IPRateLimiter) AddIP(ip string) *rate.Limiter {
 	i.mu.Lock()
 	defer i.mu.Unlock()
 
+	return i.add(ip, i.now())
+}
+
+func (i *IPRateLimiter) add(ip string, now time.Time) *rate.Limiter {
 	limiter := rate.NewLimiter(i.r, i.b)
-	i.ips[ip] = limiter
+	i.ips[ip] = &ipLimiter{limiter: limiter, lastSeen: now}
 	return limiter
 }
 
 // GetLimiter returns the rate limiter for an IP address.
 func (i *IPRateLimiter) GetLimiter(ip string) *rate.Limiter {
-	i.mu.RLock()
-	limiter, exists := i.ips[ip]
-	i.mu.RUnlock()
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	now := i.now()
+	if now.Sub(i.lastSweep) >= i.idle {
+		i.sweep(now)
+	}
 
+	entry, exists := i.ips[ip]
 	if !exists {
-		return i.AddIP(ip)
+		return i.add(ip, now)
 	}
-	return limiter
+	entry.lastSeen = now
+	return entry.limiter
+}
+
+// Len returns the number of tracked IP addresses.
+func (i *IPRateLimiter) Len() int {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return len(i.ips)
 }
 
-// RateLimiter is a middleware for IP-based rate limiting.
+func (i *IPRateLimiter) sweep(now time.Time) {
+	for ip, entry := range i.ips {
+		if now.Sub(entry.lastSeen) >= i.idle {
+			delete(i.ips, ip)
+		}
+	}
+	i.lastSweep = now
+}
+
+// RateLimiter is a middleware for IP-based rate limiting. Every call creates
+// an independent set of limiters, so routes can be given their own limits.
+// Client IPs come from c.ClientIP, which honors the engine's trusted proxies.
 func RateLimiter(r rate.Limit, b int) gin.HandlerFunc {
 	limiter := NewIPRateLimiter(r, b)
+	retryAfter := "1"
+	if r > 0 {
+		retryAfter = strconv.Itoa(int(math.Ceil(1 / float64(r))))
+	}
 	return func(c *gin.Context) {
 		if !limiter.GetLimiter(c.ClientIP()).Allow() {
-			c.AbortWithStatus(http.StatusTooManyRequests)
+			metrics.RateLimited.WithLabelValues(metrics.Route(c.FullPath())).Inc()
+			c.Header("Retry-After", retryAfter)
+			AbortWithError(c, http.StatusTooManyRequests, CodeRateLimited, "rate limit exceeded")
 			return
 		}
 		c.Next()
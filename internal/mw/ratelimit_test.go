@@ -0,0 +1,56 @@
+package mw
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/time/rate"
+
+	"laundry-status-backend/internal/metrics"
+)
+
+func TestIPRateLimiter_EvictsIdleEntries(t *testing.T) {
+	now := time.Now()
+	limiter := NewIPRateLimiter(rate.Limit(1), 5)
+	limiter.now = func() time.Time { return now }
+
+	for i := 0; i < 100; i++ {
+		limiter.GetLimiter("10.0.0." + strconv.Itoa(i))
+	}
+	assert.Equal(t, 100, limiter.Len())
+
+	now = now.Add(minIdleTTL / 2)
+	active := limiter.GetLimiter("10.0.0.1")
+	now = now.Add(minIdleTTL / 2)
+	limiter.GetLimiter("10.0.1.1")
+	assert.Equal(t, 2, limiter.Len(), "only recently seen clients are kept")
+	assert.Same(t, active, limiter.GetLimiter("10.0.0.1"))
+}
+
+func TestRateLimiter_RejectsOverLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/", RateLimiter(rate.Limit(0.5), 2), func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	do := func(ip string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = ip + ":1234"
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	rejected := testutil.ToFloat64(metrics.RateLimited.WithLabelValues("/"))
+	assert.Equal(t, http.StatusNoContent, do("192.0.2.1").Code)
+	assert.Equal(t, http.StatusNoContent, do("192.0.2.1").Code)
+	w := do("192.0.2.1")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "2", w.Header().Get("Retry-After"))
+	assert.Equal(t, rejected+1, testutil.ToFloat64(metrics.RateLimited.WithLabelValues("/")))
+	assert.Equal(t, http.StatusNoContent, do("192.0.2.2").Code, "clients have separate budgets")
+}
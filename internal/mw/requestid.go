@@ -0,0 +1,45 @@
+package mw
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"laundry-status-backend/internal/logging"
+)
+
+// RequestIDHeader carries the request ID in requests and responses.
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds IDs accepted from clients and proxies.
+const maxRequestIDLength = 64
+
+// RequestID is a middleware that tags each request with an ID. A well-formed
+// ID sent by a proxy or the client is kept, so logs can be correlated across
+// hops; otherwise a new one is generated. The ID is echoed in the response
+// and stored in the request context, where slog picks it up.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = logging.NewID()
+		}
+		c.Header(RequestIDHeader, id)
+		c.Request = c.Request.WithContext(logging.WithRequestID(c.Request.Context(), id))
+		c.Next()
+	}
+}
+
+// validRequestID accepts short IDs of characters that are safe to log.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-', r == '_', r == '.', r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
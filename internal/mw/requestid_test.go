@@ -0,0 +1,38 @@
+package mw
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"laundry-status-backend/internal/logging"
+)
+
+func TestRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	var seen string
+	r.GET("/", RequestID(), func(c *gin.Context) { seen = logging.RequestID(c.Request.Context()) })
+
+	do := func(id string) string {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if id != "" {
+			req.Header.Set(RequestIDHeader, id)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		assert.Equal(t, seen, w.Header().Get(RequestIDHeader), "the response echoes the ID in the context")
+		return seen
+	}
+
+	assert.Equal(t, "abc-123", do("abc-123"), "well-formed IDs from upstream are kept")
+	generated := do("")
+	assert.Len(t, generated, 16)
+	assert.NotEqual(t, generated, do(""), "every request gets its own ID")
+	assert.NotEqual(t, "bad id\n", do("bad id\n"))
+	assert.Len(t, do(strings.Repeat("a", maxRequestIDLength+1)), 16)
+}
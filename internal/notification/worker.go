@@ -3,13 +3,15 @@ package notification
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"sync"
 
 	"github.com/SherClockHolmes/webpush-go"
 	"gorm.io/gorm"
 
+	"laundry-status-backend/internal/logging"
+	"laundry-status-backend/internal/metrics"
 	"laundry-status-backend/internal/model"
 )
 
@@ -26,10 +28,16 @@ func (s *WebPushSender) Send(payload []byte, sub *webpush.Subscription, options
 	return webpush.SendNotification(payload, sub, options)
 }
 
+// Job asks the workers to notify the subscribers of a machine.
+type Job struct {
+	MachineID int64
+	CycleID   string // Scrape cycle that dispatched the job, for log correlation
+}
+
 // WorkerPool manages a pool of workers for sending notifications.
 type WorkerPool struct {
 	size    int
-	jobs    chan int64
+	jobs    chan Job
 	db      *gorm.DB
 	webpush *webpush.Options
 	sender  NotificationSender
@@ -40,7 +48,7 @@ type WorkerPool struct {
 func NewWorkerPool(size int, db *gorm.DB, webpushOptions *webpush.Options) *WorkerPool {
 	return &WorkerPool{
 		size:    size,
-		jobs:    make(chan int64, size), // Buffered channel
+		jobs:    make(chan Job, size), // Buffered channel
 		db:      db,
 		webpush: webpushOptions,
 		sender:  &WebPushSender{}, // Use the real sender by default
@@ -57,26 +65,43 @@ func (wp *WorkerPool) Start(ctx context.Context) {
 
 // worker is the actual worker goroutine.
 func (wp *WorkerPool) worker(ctx context.Context, id int) {
-	log.Printf("Worker %d started", id)
+	slog.Debug("Worker started", "worker", id)
 	for {
 		select {
-		case machineID := <-wp.jobs:
-			log.Printf("Worker %d processing machine %d", id, machineID)
-			wp.sendNotificationsForMachine(ctx, machineID)
+		case job := <-wp.jobs:
+			metrics.NotificationQueueDepth.Set(float64(len(wp.jobs)))
+			jobCtx := ctx
+			if job.CycleID != "" {
+				jobCtx = logging.WithCycleID(ctx, job.CycleID)
+			}
+			slog.DebugContext(jobCtx, "Worker processing machine", "worker", id, "machine_id", job.MachineID)
+			wp.sendNotificationsForMachine(jobCtx, job.MachineID)
 		case <-ctx.Done():
-			log.Printf("Worker %d shutting down", id)
+			slog.Debug("Worker shutting down", "worker", id)
 			return
 		}
 	}
 }
 
-// Dispatch sends a job to the worker pool.
-func (wp *WorkerPool) Dispatch(machineID int64) {
-	wp.jobs <- machineID
+// Dispatch sends a job to the worker pool. The scrape-cycle ID in ctx, if
+// any, is carried over to the logs of the job.
+func (wp *WorkerPool) Dispatch(ctx context.Context, machineID int64) {
+	wp.jobs <- Job{MachineID: machineID, CycleID: logging.CycleID(ctx)}
+	metrics.NotificationQueueDepth.Set(float64(len(wp.jobs)))
+}
+
+// QueueDepth returns the number of dispatched jobs no worker has picked up yet.
+func (wp *WorkerPool) QueueDepth() int {
+	return len(wp.jobs)
+}
+
+// Size returns the number of workers.
+func (wp *WorkerPool) Size() int {
+	return wp.size
 }
 
 // Jobs returns the jobs channel for testing.
-func (wp *WorkerPool) Jobs() chan int64 {
+func (wp *WorkerPool) Jobs() chan Job {
 	return wp.jobs
 }
 
@@ -88,7 +113,7 @@ func (wp *WorkerPool) sendNotificationsForMachine(ctx context.Context, machineID
 		Where("smm.machine_id = ?", machineID).
 		Find(&subscriptions).Error
 	if err != nil {
-		log.Printf("Error fetching subscriptions for machine %d: %v", machineID, err)
+		slog.ErrorContext(ctx, "Error fetching subscriptions", "machine_id", machineID, "error", err)
 		return
 	}
 
@@ -96,14 +121,14 @@ func (wp *WorkerPool) sendNotificationsForMachine(ctx context.Context, machineID
 		return
 	}
 
-	log.Printf("Sending %d notifications for machine %d", len(subscriptions), machineID)
+	slog.InfoContext(ctx, "Sending notifications", "machine_id", machineID, "subscriptions", len(subscriptions))
 
 	var machine model.Machine
 	machineLabel := fmt.Sprintf("%d", machineID)
 	if err := wp.db.WithContext(ctx).
 		Select("display_name").
 		First(&machine, machineID).Error; err != nil {
-		log.Printf("Error fetching machine %d: %v", machineID, err)
+		slog.ErrorContext(ctx, "Error fetching machine", "machine_id", machineID, "error", err)
 	} else if machine.DisplayName != "" {
 		machineLabel = machine.DisplayName
 	}
@@ -117,7 +142,7 @@ func (wp *WorkerPool) sendNotificationsForMachine(ctx context.Context, machineID
 // sendNotification sends a single web push notification.
 func (wp *WorkerPool) sendNotification(ctx context.Context, sub model.PushSubscription, payload []byte) {
 	if !wp.guard.Acquire(sub.Endpoint) {
-		log.Printf("Duplicate notification attempt for endpoint %s skipped", sub.Endpoint)
+		slog.DebugContext(ctx, "Duplicate notification attempt skipped", "endpoint", sub.Endpoint)
 		return
 	}
 	defer wp.guard.Release(sub.Endpoint)
@@ -133,19 +158,21 @@ func (wp *WorkerPool) sendNotification(ctx context.Context, sub model.PushSubscr
 
 	resp, err := wp.sender.Send(payload, wpSub, wp.webpush)
 	if err != nil {
-		log.Printf("Error sending notification to %s: %v", sub.Endpoint, err)
+		slog.ErrorContext(ctx, "Error sending notification", "endpoint", sub.Endpoint, "error", err)
+		metrics.NotificationsSent.WithLabelValues("error").Inc()
 		return
 	}
 	defer resp.Body.Close()
+	metrics.NotificationsSent.WithLabelValues(sendResult(resp.StatusCode)).Inc()
 
 	// Handle expired subscriptions
 	if resp.StatusCode == 410 {
-		log.Printf("Subscription for endpoint %s is expired. Deleting.", sub.Endpoint)
+		slog.InfoContext(ctx, "Subscription is expired, deleting", "endpoint", sub.Endpoint)
 		if err := wp.clearEndpointSubscriptions(ctx, sub.Endpoint); err != nil {
-			log.Printf("Failed to clear subscriptions for endpoint %s: %v", sub.Endpoint, err)
+			slog.ErrorContext(ctx, "Failed to clear subscriptions", "endpoint", sub.Endpoint, "error", err)
 		}
 		if err := wp.db.WithContext(ctx).Delete(&sub).Error; err != nil {
-			log.Printf("Failed to delete expired subscription %s: %v", sub.Endpoint, err)
+			slog.ErrorContext(ctx, "Failed to delete expired subscription", "endpoint", sub.Endpoint, "error", err)
 		}
 		return
 	}
@@ -153,11 +180,22 @@ func (wp *WorkerPool) sendNotification(ctx context.Context, sub model.PushSubscr
 	// Successful notification delivery should unsubscribe the endpoint from all machines.
 	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
 		if err := wp.clearEndpointSubscriptions(ctx, sub.Endpoint); err != nil {
-			log.Printf("Failed to clear subscriptions for endpoint %s after notification: %v", sub.Endpoint, err)
+			slog.ErrorContext(ctx, "Failed to clear subscriptions after notification", "endpoint", sub.Endpoint, "error", err)
 		}
 	}
 }
 
+// sendResult is the metrics label of a push service response.
+func sendResult(status int) string {
+	switch {
+	case status >= 200 && status < 300:
+		return "2xx"
+	case status == http.StatusGone:
+		return "410"
+	}
+	return "error"
+}
+
 // clearEndpointSubscriptions removes all machine subscriptions associated with the given endpoint.
 func (wp *WorkerPool) clearEndpointSubscriptions(ctx context.Context, endpoint string) error {
 	return wp.db.WithContext(ctx).
@@ -18,6 +18,7 @@ import (
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 
+	"laundry-status-backend/internal/logging"
 	"laundry-status-backend/internal/model"
 )
 
@@ -49,12 +50,12 @@ func TestWorkerPool_Dispatch(t *testing.T) {
 	wp := NewWorkerPool(1, db, &webpush.Options{})
 
 	// Dispatch a job
-	wp.Dispatch(123)
+	wp.Dispatch(logging.WithCycleID(context.Background(), "cycle-1"), 123)
 
 	// Check if the job is in the channel
 	select {
 	case job := <-wp.jobs:
-		assert.Equal(t, int64(123), job)
+		assert.Equal(t, Job{MachineID: 123, CycleID: "cycle-1"}, job)
 	case <-time.After(1 * time.Second):
 		t.Fatal("timed out waiting for job to be dispatched")
 	}
@@ -107,7 +108,7 @@ func TestWorkerPool_WorkerLogic(t *testing.T) {
 			WithArgs(subscription.Endpoint).
 			WillReturnResult(sqlmock.NewResult(0, 1))
 
-		wp.Dispatch(machineID)
+		wp.Dispatch(context.Background(), machineID)
 		wg.Wait()
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
@@ -155,7 +156,7 @@ func TestWorkerPool_WorkerLogic(t *testing.T) {
 			WillReturnResult(sqlmock.NewResult(1, 1))
 		mock.ExpectCommit()
 
-		wp.Dispatch(machineID)
+		wp.Dispatch(context.Background(), machineID)
 
 		// A short sleep to allow the worker to process the job
 		time.Sleep(100 * time.Millisecond)
@@ -200,7 +201,7 @@ func TestWorkerPool_WorkerLogic(t *testing.T) {
 			WithArgs(subscription.Endpoint).
 			WillReturnResult(sqlmock.NewResult(0, 1))
 
-		wp.Dispatch(machineID)
+		wp.Dispatch(context.Background(), machineID)
 		wg.Wait()
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
@@ -259,3 +260,10 @@ func TestWorkerPool_SkipsDuplicateEndpointNotifications(t *testing.T) {
 	assert.Equal(t, int32(1), atomic.LoadInt32(&sendCount), "expected exactly one notification to be sent")
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
+
+func TestSendResult(t *testing.T) {
+	assert.Equal(t, "2xx", sendResult(http.StatusCreated))
+	assert.Equal(t, "410", sendResult(http.StatusGone))
+	assert.Equal(t, "error", sendResult(http.StatusTooManyRequests))
+	assert.Equal(t, "error", sendResult(http.StatusInternalServerError))
+}
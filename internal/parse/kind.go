@@ -0,0 +1,58 @@
+package parse
+
+import (
+	"fmt"
+	"regexp"
+
+	"laundry-status-backend/config"
+	"laundry-status-backend/internal/model"
+)
+
+type kindRule struct {
+	kind      string
+	name      *regexp.Regexp
+	deviceIDs map[int64]struct{}
+}
+
+func compileKindRules(cfgs []config.KindRule) ([]kindRule, error) {
+	rules := make([]kindRule, 0, len(cfgs))
+	for i, kc := range cfgs {
+		if kc.Kind == "" {
+			return nil, fmt.Errorf("kind rule %d has no kind", i)
+		}
+		if kc.NamePattern == "" && len(kc.DeviceIDs) == 0 {
+			return nil, fmt.Errorf("kind rule %d (%s) needs a name_pattern or device_ids", i, kc.Kind)
+		}
+
+		r := kindRule{kind: kc.Kind, deviceIDs: make(map[int64]struct{}, len(kc.DeviceIDs))}
+		if kc.NamePattern != "" {
+			re, err := regexp.Compile(kc.NamePattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid name_pattern for kind rule %d (%s): %w", i, kc.Kind, err)
+			}
+			r.name = re
+		}
+		for _, id := range kc.DeviceIDs {
+			r.deviceIDs[id] = struct{}{}
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+// Kind classifies a machine from its raw name and upstream device ID. A nil
+// *Parser, or a machine no rule matches, gets the default kind.
+func (p *Parser) Kind(raw string, deviceID int64) string {
+	if p == nil {
+		return model.MachineKindWasher
+	}
+	for _, r := range p.kindRules {
+		if _, ok := r.deviceIDs[deviceID]; ok {
+			return r.kind
+		}
+		if r.name != nil && r.name.MatchString(raw) {
+			return r.kind
+		}
+	}
+	return p.defaultKind
+}
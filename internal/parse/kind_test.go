@@ -0,0 +1,38 @@
+package parse
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"laundry-status-backend/config"
+	"laundry-status-backend/internal/model"
+)
+
+func TestParser_Kind(t *testing.T) {
+	parser, err := NewParser(config.ParserConfig{
+		KindRules: []config.KindRule{
+			{Kind: model.MachineKindShoeWasher, NamePattern: `鞋`},
+			{Kind: model.MachineKindDryer, NamePattern: `烘干|干衣`, DeviceIDs: []int64{42}},
+		},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, model.MachineKindShoeWasher, parser.Kind("东3#1-洗鞋机", 0))
+	assert.Equal(t, model.MachineKindDryer, parser.Kind("东3#1-烘干机1", 0))
+	assert.Equal(t, model.MachineKindDryer, parser.Kind("东3#1-3", 42), "device ID alone is enough")
+	assert.Equal(t, model.MachineKindWasher, parser.Kind("东3#1-3", 7))
+
+	var nilParser *Parser
+	assert.Equal(t, model.MachineKindWasher, nilParser.Kind("烘干机", 42))
+
+	custom, err := NewParser(config.ParserConfig{DefaultKind: "unknown"})
+	require.NoError(t, err)
+	assert.Equal(t, "unknown", custom.Kind("东3#1-3", 0))
+
+	_, err = NewParser(config.ParserConfig{KindRules: []config.KindRule{{Kind: model.MachineKindDryer}}})
+	assert.Error(t, err, "rule without criteria")
+	_, err = NewParser(config.ParserConfig{KindRules: []config.KindRule{{NamePattern: "x"}}})
+	assert.Error(t, err, "rule without kind")
+}
@@ -99,13 +99,11 @@ func ParseName(raw string, floorCode string) (ParsedName, error) {
 		}
 	}
 
-	// 4) temp fix: 栋 -> 东
-	dorm = strings.ReplaceAll(dorm, "栋", "东")
-
 	if floor == 0 {
 		return ParsedName{}, fmt.Errorf("unable to parse floor from name: %q", raw)
 	}
 
-	// 5) 没有显式编号就保持为 0
+	// 4) 没有显式编号就保持为 0
+	// 楼名的同义写法（如 栋/东）由 dorm_aliases 统一解析，这里不再做替换
 	return ParsedName{Dorm: dorm, Floor: floor, Seq: seq}, nil
 }
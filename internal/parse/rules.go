@@ -0,0 +1,212 @@
+package parse
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"laundry-status-backend/config"
+	"laundry-status-backend/internal/model"
+)
+
+// BuiltinRule is the rule name reported when ParseName handled a name.
+const BuiltinRule = "builtin"
+
+var (
+	spaceRe         = regexp.MustCompile(`\s+`)
+	basementFloorRe = regexp.MustCompile(`(?i)^B\s*(\d+)$`)
+	plainFloorRe    = regexp.MustCompile(`(?i)^(-?\d+)\s*(?:F|层)?$`)
+)
+
+type rule struct {
+	name string
+	re   *regexp.Regexp
+	dorm string
+}
+
+type target struct {
+	name  string
+	match *regexp.Regexp
+	rules []rule
+}
+
+// Parser applies the configured naming rules in order. A nil *Parser only
+// uses the built-in ParseName, which matches the original campus' scheme.
+type Parser struct {
+	targets     []target
+	rules       []rule
+	builtin     bool
+	kindRules   []kindRule
+	defaultKind string
+}
+
+// NewParser compiles the naming and kind rules declared in cfg.
+func NewParser(cfg config.ParserConfig) (*Parser, error) {
+	p := &Parser{builtin: !cfg.DisableBuiltin, defaultKind: cfg.DefaultKind}
+	if p.defaultKind == "" {
+		p.defaultKind = model.MachineKindWasher
+	}
+
+	var err error
+	if p.rules, err = compileRules(cfg.Rules); err != nil {
+		return nil, err
+	}
+	if p.kindRules, err = compileKindRules(cfg.KindRules); err != nil {
+		return nil, err
+	}
+
+	for i, t := range cfg.Targets {
+		name := t.Name
+		if name == "" {
+			name = fmt.Sprintf("target[%d]", i)
+		}
+		match, err := regexp.Compile(t.Match)
+		if err != nil {
+			return nil, fmt.Errorf("invalid match pattern for parser target %q: %w", name, err)
+		}
+		rules, err := compileRules(t.Rules)
+		if err != nil {
+			return nil, fmt.Errorf("parser target %q: %w", name, err)
+		}
+		for j := range rules {
+			rules[j].name = name + "/" + rules[j].name
+		}
+		p.targets = append(p.targets, target{name: name, match: match, rules: rules})
+	}
+
+	if len(p.rules) == 0 && len(p.targets) == 0 && !p.builtin {
+		return nil, fmt.Errorf("parser has no rules and the built-in parser is disabled")
+	}
+	return p, nil
+}
+
+func compileRules(cfgs []config.ParseRule) ([]rule, error) {
+	rules := make([]rule, 0, len(cfgs))
+	for i, rc := range cfgs {
+		name := rc.Name
+		if name == "" {
+			name = fmt.Sprintf("rule[%d]", i)
+		}
+		re, err := regexp.Compile(rc.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern for parser rule %q: %w", name, err)
+		}
+		if re.SubexpIndex("dorm") < 0 && rc.Dorm == "" {
+			return nil, fmt.Errorf("parser rule %q needs a (?P<dorm>...) group or a dorm template", name)
+		}
+		rules = append(rules, rule{name: name, re: re, dorm: rc.Dorm})
+	}
+	return rules, nil
+}
+
+// Parse extracts dorm, floor, and sequence number from a raw machine name.
+func (p *Parser) Parse(raw string, floorCode string) (ParsedName, error) {
+	parsed, _, err := p.Explain(raw, floorCode)
+	return parsed, err
+}
+
+// Explain is Parse that also reports which rule produced the result.
+func (p *Parser) Explain(raw string, floorCode string) (ParsedName, string, error) {
+	if p == nil {
+		parsed, err := ParseName(raw, floorCode)
+		return parsed, BuiltinRule, err
+	}
+
+	s := strings.TrimSpace(raw)
+	var lastErr error
+	try := func(rules []rule) (ParsedName, string, bool) {
+		for _, r := range rules {
+			parsed, ok, err := r.apply(s, floorCode)
+			if !ok {
+				continue
+			}
+			if err == nil {
+				return parsed, r.name, true
+			}
+			lastErr = err
+		}
+		return ParsedName{}, "", false
+	}
+
+	for _, t := range p.targets {
+		if !t.match.MatchString(s) {
+			continue
+		}
+		if parsed, name, ok := try(t.rules); ok {
+			return parsed, name, nil
+		}
+	}
+	if parsed, name, ok := try(p.rules); ok {
+		return parsed, name, nil
+	}
+
+	if p.builtin {
+		parsed, err := ParseName(raw, floorCode)
+		return parsed, BuiltinRule, err
+	}
+	if lastErr != nil {
+		return ParsedName{}, "", lastErr
+	}
+	return ParsedName{}, "", fmt.Errorf("no parser rule matches name: %q", raw)
+}
+
+// apply reports ok when the rule matched, with err set if the match could
+// not be turned into a usable name.
+func (r rule) apply(s string, floorCode string) (ParsedName, bool, error) {
+	m := r.re.FindStringSubmatchIndex(s)
+	if m == nil {
+		return ParsedName{}, false, nil
+	}
+
+	group := func(name string) string {
+		i := r.re.SubexpIndex(name)
+		if i < 0 || m[2*i] < 0 {
+			return ""
+		}
+		return s[m[2*i]:m[2*i+1]]
+	}
+
+	dorm := group("dorm")
+	if r.dorm != "" {
+		dorm = string(r.re.ExpandString(nil, r.dorm, s, m))
+	}
+	dorm = strings.TrimSpace(spaceRe.ReplaceAllString(strings.ReplaceAll(dorm, "#", " "), " "))
+	if dorm == "" {
+		return ParsedName{}, true, fmt.Errorf("rule %q matched %q without a dorm name", r.name, s)
+	}
+
+	// 楼层：优先取规则里的 floor 分组，取不到时用 floorCode 兜底
+	floor, ok := parseFloor(group("floor"))
+	if !ok {
+		floor, ok = parseFloor(floorCode)
+	}
+	if !ok || floor == 0 {
+		return ParsedName{}, true, fmt.Errorf("rule %q could not determine floor of %q", r.name, s)
+	}
+
+	seq := 0
+	if raw := group("seq"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return ParsedName{}, true, fmt.Errorf("rule %q captured invalid seq %q in %q", r.name, raw, s)
+		}
+		seq = n
+	}
+
+	return ParsedName{Dorm: dorm, Floor: floor, Seq: seq}, true, nil
+}
+
+// parseFloor accepts "3", "3F", "3层" and basement floors such as "B1" (-1).
+func parseFloor(s string) (int, bool) {
+	s = strings.TrimSpace(s)
+	if m := basementFloorRe.FindStringSubmatch(s); m != nil {
+		n, err := strconv.Atoi(m[1])
+		return -n, err == nil
+	}
+	if m := plainFloorRe.FindStringSubmatch(s); m != nil {
+		n, err := strconv.Atoi(m[1])
+		return n, err == nil
+	}
+	return 0, false
+}
@@ -0,0 +1,113 @@
+package parse
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"laundry-status-backend/config"
+)
+
+func TestParser(t *testing.T) {
+	parser, err := NewParser(config.ParserConfig{
+		Rules: []config.ParseRule{
+			{Name: "north", Pattern: `^(?P<dorm>北区\d+号楼)-(?P<floor>B?\d+)-洗衣机(?P<seq>\d+)$`},
+			{Name: "south", Pattern: `^南(?P<building>\d+)-洗衣机$`, Dorm: "南区${building}栋"},
+		},
+		Targets: []config.ParseTarget{
+			{
+				Name:  "west",
+				Match: `^西区`,
+				Rules: []config.ParseRule{
+					{Name: "unit", Pattern: `^(?P<dorm>西区\d+)(?P<unit>[A-Z])(?P<floor>\d+)-(?P<seq>\d+)$`},
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name      string
+		raw       string
+		floorCode string
+		expected  ParsedName
+		rule      string
+		expectErr bool
+	}{
+		{
+			name:     "Configured rule",
+			raw:      "北区3号楼-2-洗衣机02",
+			expected: ParsedName{Dorm: "北区3号楼", Floor: 2, Seq: 2},
+			rule:     "north",
+		},
+		{
+			name:     "Basement floor",
+			raw:      "北区3号楼-B1-洗衣机01",
+			expected: ParsedName{Dorm: "北区3号楼", Floor: -1, Seq: 1},
+			rule:     "north",
+		},
+		{
+			name:      "Dorm template with floorCode fallback",
+			raw:       "南7-洗衣机",
+			floorCode: "4",
+			expected:  ParsedName{Dorm: "南区7栋", Floor: 4},
+			rule:      "south",
+		},
+		{
+			name:      "Matched rule without floor is an error",
+			raw:       "南7-洗衣机",
+			expectErr: true,
+		},
+		{
+			name:     "Target rule takes precedence",
+			raw:      "西区5A10-1",
+			expected: ParsedName{Dorm: "西区5", Floor: 10, Seq: 1},
+			rule:     "west/unit",
+		},
+		{
+			name:      "Falls back to builtin parser",
+			raw:       "东3#2-3",
+			floorCode: "2",
+			expected:  ParsedName{Dorm: "东3", Floor: 2, Seq: 3},
+			rule:      BuiltinRule,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			parsed, rule, err := parser.Explain(tc.raw, tc.floorCode)
+			if tc.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, parsed)
+			assert.Equal(t, tc.rule, rule)
+		})
+	}
+}
+
+func TestNewParser_Validation(t *testing.T) {
+	_, err := NewParser(config.ParserConfig{Rules: []config.ParseRule{{Pattern: `(`}}})
+	assert.Error(t, err, "invalid regex")
+
+	_, err = NewParser(config.ParserConfig{Rules: []config.ParseRule{{Pattern: `^(?P<floor>\d+)$`}}})
+	assert.Error(t, err, "rule without dorm group or template")
+
+	_, err = NewParser(config.ParserConfig{DisableBuiltin: true})
+	assert.Error(t, err, "no rules at all")
+
+	parser, err := NewParser(config.ParserConfig{
+		DisableBuiltin: true,
+		Rules:          []config.ParseRule{{Pattern: `^(?P<dorm>\D+)(?P<floor>\d+)$`}},
+	})
+	require.NoError(t, err)
+	_, err = parser.Parse("东3#2-3", "2")
+	assert.Error(t, err, "builtin fallback disabled")
+
+	var nilParser *Parser
+	parsed, err := nilParser.Parse("中楼5-12", "5")
+	assert.NoError(t, err)
+	assert.Equal(t, ParsedName{Dorm: "中楼", Floor: 5, Seq: 12}, parsed)
+}
@@ -0,0 +1,174 @@
+package retention
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"laundry-status-backend/config"
+	"laundry-status-backend/internal/model"
+)
+
+// batchSize bounds both the history scan and the rollup upsert.
+const batchSize = 1000
+
+// Service is the Go equivalent of the TimescaleDB continuous aggregate and
+// retention policies: it rolls occupancy_histories into occupancy_hourly and
+// prunes raw rows (and old rollups) past their configured retention.
+type Service struct {
+	cfg *config.DatabaseConfig
+	db  *gorm.DB
+}
+
+// NewService creates a new retention service.
+func NewService(cfg *config.DatabaseConfig, db *gorm.DB) *Service {
+	return &Service{
+		cfg: cfg,
+		db:  db,
+	}
+}
+
+// Run performs a rollup immediately and then once per configured interval.
+// It returns straight away when TimescaleDB policies do the work instead.
+func (s *Service) Run(ctx context.Context) {
+	if s.cfg.EnableTimescale {
+		slog.Info("TimescaleDB is enabled; retention and rollups are handled by database policies")
+		return
+	}
+	slog.Info("Starting retention service", "interval", s.cfg.Retention.RollupInterval)
+
+	s.runAndLog(ctx)
+
+	ticker := time.NewTicker(s.cfg.Retention.RollupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("Retention service shutting down")
+			return
+		case <-ticker.C:
+			s.runAndLog(ctx)
+		}
+	}
+}
+
+func (s *Service) runAndLog(ctx context.Context) {
+	if err := s.RunOnce(ctx, time.Now().UTC()); err != nil {
+		slog.ErrorContext(ctx, "Error running retention cycle", "error", err)
+	}
+}
+
+// RunOnce rolls up every bucket from the newest existing one onwards, then
+// deletes raw history and rollups older than their retention windows.
+func (s *Service) RunOnce(ctx context.Context, now time.Time) error {
+	db := s.db.WithContext(ctx)
+
+	from, err := s.rollupWatermark(db)
+	if err != nil {
+		return fmt.Errorf("failed to determine rollup watermark: %w", err)
+	}
+
+	rows, err := s.rollup(db, from)
+	if err != nil {
+		return err
+	}
+	if rows > 0 {
+		slog.InfoContext(ctx, "Rolled up hourly occupancy buckets", "buckets", rows, "since", from)
+	}
+
+	// Everything observed before now has been rolled up, so pruning is safe.
+	if days := s.cfg.Retention.HistoryDays; days > 0 {
+		cutoff := now.Add(-time.Duration(days) * 24 * time.Hour)
+		res := db.Where("observed_at < ?", cutoff).Delete(&model.OccupancyHistory{})
+		if res.Error != nil {
+			return fmt.Errorf("failed to prune occupancy history: %w", res.Error)
+		}
+		if res.RowsAffected > 0 {
+			slog.InfoContext(ctx, "Pruned occupancy history", "rows", res.RowsAffected, "cutoff", cutoff)
+		}
+	}
+
+	if days := s.cfg.Retention.RollupDays; days > 0 {
+		cutoff := now.Add(-time.Duration(days) * 24 * time.Hour)
+		if err := db.Where("bucket < ?", cutoff).Delete(&model.OccupancyHourly{}).Error; err != nil {
+			return fmt.Errorf("failed to prune hourly rollups: %w", err)
+		}
+	}
+	return nil
+}
+
+// rollupWatermark returns the start of the newest bucket already rolled up.
+// That bucket is recomputed because it may have been partial at the last run.
+func (s *Service) rollupWatermark(db *gorm.DB) (time.Time, error) {
+	var latest model.OccupancyHourly
+	err := db.Order("bucket DESC").First(&latest).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	return latest.Bucket, nil
+}
+
+type bucketKey struct {
+	bucket    time.Time
+	machineID int64
+	status    int
+}
+
+// rollup recomputes every bucket at or after from and upserts the results.
+func (s *Service) rollup(db *gorm.DB, from time.Time) (int, error) {
+	totals := make(map[bucketKey]*model.OccupancyHourly)
+
+	var batch []model.OccupancyHistory
+	err := db.Where("observed_at >= ?", from).
+		FindInBatches(&batch, batchSize, func(tx *gorm.DB, _ int) error {
+			for _, h := range batch {
+				key := bucketKey{
+					bucket:    h.ObservedAt.UTC().Truncate(time.Hour),
+					machineID: h.MachineID,
+					status:    h.Status,
+				}
+				row, ok := totals[key]
+				if !ok {
+					row = &model.OccupancyHourly{Bucket: key.bucket, MachineID: key.machineID, Status: key.status}
+					totals[key] = row
+				}
+				row.Sessions++
+				if busy := h.ObservedAt.Sub(h.PeriodStart); busy > 0 {
+					row.BusySeconds += int64(busy.Seconds())
+				}
+			}
+			return nil
+		}).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to scan occupancy history: %w", err)
+	}
+
+	if len(totals) == 0 {
+		return 0, nil
+	}
+
+	rows := make([]model.OccupancyHourly, 0, len(totals))
+	for _, row := range totals {
+		rows = append(rows, *row)
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "bucket"}, {Name: "machine_id"}, {Name: "status"}},
+			DoUpdates: clause.AssignmentColumns([]string{"sessions", "busy_seconds"}),
+		}).CreateInBatches(&rows, batchSize).Error
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to upsert hourly rollups: %w", err)
+	}
+	return len(rows), nil
+}
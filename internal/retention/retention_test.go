@@ -0,0 +1,69 @@
+package retention
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"laundry-status-backend/config"
+	"laundry-status-backend/internal/model"
+	"laundry-status-backend/internal/testdb"
+)
+
+func newTestDB(t *testing.T) *gorm.DB {
+	return testdb.New(t, &model.OccupancyHistory{}, &model.OccupancyHourly{})
+}
+
+func TestService_RunOnce(t *testing.T) {
+	testDB := newTestDB(t)
+	now := time.Date(2025, 3, 10, 12, 30, 0, 0, time.UTC)
+
+	history := []model.OccupancyHistory{
+		// Old session: rolled up, then pruned from raw history.
+		{MachineID: 1, Status: 2, PeriodStart: now.Add(-50 * time.Hour), ObservedAt: now.Add(-49*time.Hour - 20*time.Minute), PeriodEnd: now.Add(-49 * time.Hour)},
+		// Two sessions ending in the same bucket for machine 1.
+		{MachineID: 1, Status: 2, PeriodStart: now.Add(-150 * time.Minute), ObservedAt: now.Add(-115 * time.Minute), PeriodEnd: now.Add(-110 * time.Minute)},
+		{MachineID: 1, Status: 2, PeriodStart: now.Add(-110 * time.Minute), ObservedAt: now.Add(-100 * time.Minute), PeriodEnd: now.Add(-95 * time.Minute)},
+		// Faulty session for machine 2 in the same bucket is kept separate.
+		{MachineID: 2, Status: 3, PeriodStart: now.Add(-3 * time.Hour), ObservedAt: now.Add(-105 * time.Minute), PeriodEnd: now.Add(-105 * time.Minute)},
+	}
+	require.NoError(t, testDB.Create(&history).Error)
+
+	svc := NewService(&config.DatabaseConfig{
+		Retention: config.RetentionConfig{HistoryDays: 1},
+	}, testDB)
+	require.NoError(t, svc.RunOnce(context.Background(), now))
+
+	var rollups []model.OccupancyHourly
+	require.NoError(t, testDB.Order("bucket, machine_id").Find(&rollups).Error)
+	require.Len(t, rollups, 3)
+
+	assert.Equal(t, int64(1), rollups[0].Sessions)
+	assert.Equal(t, int64(40*60), rollups[0].BusySeconds)
+
+	assert.Equal(t, now.Add(-2*time.Hour).Truncate(time.Hour), rollups[1].Bucket.UTC())
+	assert.Equal(t, int64(1), rollups[1].MachineID)
+	assert.Equal(t, int64(2), rollups[1].Sessions)
+	assert.Equal(t, int64((35+10)*60), rollups[1].BusySeconds)
+
+	assert.Equal(t, int64(2), rollups[2].MachineID)
+	assert.Equal(t, 3, rollups[2].Status)
+
+	var remaining int64
+	testDB.Model(&model.OccupancyHistory{}).Count(&remaining)
+	assert.Equal(t, int64(3), remaining, "history older than the retention window should be pruned")
+
+	// A later session in the newest bucket is merged on the next run without double counting.
+	late := model.OccupancyHistory{MachineID: 1, Status: 2, PeriodStart: now.Add(-100 * time.Minute), ObservedAt: now.Add(-95 * time.Minute), PeriodEnd: now.Add(-90 * time.Minute)}
+	require.NoError(t, testDB.Create(&late).Error)
+	require.NoError(t, svc.RunOnce(context.Background(), now))
+
+	var merged model.OccupancyHourly
+	require.NoError(t, testDB.Where("machine_id = ? AND status = ?", 1, 2).Order("bucket DESC").First(&merged).Error)
+	assert.Equal(t, int64(3), merged.Sessions)
+	assert.Equal(t, int64((35+10+5)*60), merged.BusySeconds)
+}
@@ -6,12 +6,18 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
 	"net/url"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"laundry-status-backend/config"
+	"laundry-status-backend/internal/live"
+	"laundry-status-backend/internal/logging"
+	"laundry-status-backend/internal/metrics"
 	"laundry-status-backend/internal/notification"
 	"laundry-status-backend/internal/store"
 
@@ -24,16 +30,37 @@ type Service struct {
 	store      store.Store
 	client     *http.Client
 	workerPool *notification.WorkerPool // New field for the worker pool
+	events     *live.Broker             // Receives committed changes; may be nil
+
+	mu     sync.Mutex
+	status Status
+
+	trigger chan struct{} // Requests an immediate cycle; holds at most one
+	paused  atomic.Bool
+}
+
+// Status is a snapshot of the scraper's in-process health. The outcome of
+// the cycles is persisted separately, see store.LoadScraperState.
+type Status struct {
+	Enabled           bool
+	Paused            bool      // Scheduled cycles are skipped; triggered ones still run
+	LastCycleAt       time.Time // Start of the last completed cycle
+	LastCycleDuration time.Duration
+	UpstreamLatency   time.Duration // Mean page fetch time of the last cycle
+	UpstreamPages     int
+	QueueDepth        int // Notifications waiting for a worker
+	Workers           int
 }
 
 // NewService creates and initializes a new scraper service.
-// It now accepts a store.Store instead of a *gorm.DB.
-func NewService(cfg *config.Config, store store.Store) *Service {
+// It now accepts a store.Store instead of a *gorm.DB. Committed occupancy
+// changes are published to events unless it is nil.
+func NewService(cfg *config.Config, store store.Store, events *live.Broker) *Service {
 	var transport http.RoundTripper = &http.Transport{}
 	if cfg.Scraper.HTTPProxy != "" {
 		proxyURL, err := url.Parse(cfg.Scraper.HTTPProxy)
 		if err != nil {
-			log.Printf("Warning: Invalid proxy URL %q: %v. Scraper will not use a proxy.", cfg.Scraper.HTTPProxy, err)
+			slog.Warn("Invalid proxy URL, scraper will not use a proxy", "proxy", cfg.Scraper.HTTPProxy, "error", err)
 		} else {
 			transport = &http.Transport{Proxy: http.ProxyURL(proxyURL)}
 		}
@@ -57,11 +84,13 @@ func NewService(cfg *config.Config, store store.Store) *Service {
 			Timeout:   30 * time.Second,
 		},
 		workerPool: workerPool,
+		events:     events,
+		trigger:    make(chan struct{}, 1),
 	}
 }
 
-// getStateType determines the machine's state type based on the raw state code.
-func (s *Service) getStateType(stateCode int) store.MachineStateType {
+// StateType determines the machine's state type based on the raw state code.
+func (s *Service) StateType(stateCode int) store.MachineStateType {
 	for _, idleVal := range s.cfg.Scraper.StateIdleValues {
 		if stateCode == idleVal {
 			return store.StateTypeIdle
@@ -83,10 +112,10 @@ func (s *Service) getStateType(stateCode int) store.MachineStateType {
 // Run starts the scraping process in a loop.
 func (s *Service) Run(ctx context.Context) {
 	if !s.cfg.Scraper.Enabled {
-		log.Println("Scraper is disabled. Not starting.")
+		slog.Info("Scraper is disabled, not starting")
 		return
 	}
-	log.Println("Starting scraper service...")
+	slog.Info("Starting scraper service", "interval", s.cfg.Scraper.Interval)
 
 	// Start the worker pool
 	s.workerPool.Start(ctx)
@@ -99,19 +128,33 @@ func (s *Service) Run(ctx context.Context) {
 	for {
 		select {
 		case <-ctx.Done():
-			log.Println("Scraper service shutting down.")
+			slog.Info("Scraper service shutting down")
 			return
 		case <-timer.C:
+			if s.paused.Load() {
+				slog.DebugContext(ctx, "Scraper is paused, skipping scheduled cycle")
+			} else {
+				s.ScrapeOnce(ctx)
+			}
+			timer.Reset(s.cfg.Scraper.Interval)
+		case <-s.trigger:
 			s.ScrapeOnce(ctx)
+			// Reset drops a tick that fired meanwhile (Go 1.23 timers)
 			timer.Reset(s.cfg.Scraper.Interval)
 		}
 	}
 }
 
 // ScrapeOnce performs a single round of data scraping and calls the store to persist changes.
+// Logs of the cycle, including those of the store and of the notifications it
+// dispatches, share a cycle ID.
 func (s *Service) ScrapeOnce(ctx context.Context) {
-	log.Println("Executing scrape cycle...")
+	ctx = logging.WithCycleID(ctx, logging.NewID())
+	slog.InfoContext(ctx, "Executing scrape cycle")
 	now := time.Now().UTC()
+	var pages int
+	var latency time.Duration
+	defer func() { s.finishCycle(now, pages, latency) }()
 
 	// Step 1: Fetch all data from the upstream API
 	var allItems []store.ApiItem
@@ -119,9 +162,13 @@ func (s *Service) ScrapeOnce(ctx context.Context) {
 	pageSize := s.cfg.Scraper.Request.PageSize
 	var fetchErr error
 	for page := 1; (page-1)*pageSize < total; page++ {
+		fetchStart := time.Now()
 		resp, err := s.fetchPage(ctx, page)
+		pages++
+		latency += time.Since(fetchStart)
 		if err != nil {
-			log.Printf("Error fetching page %d: %v", page, err)
+			slog.ErrorContext(ctx, "Error fetching page", "page", page, "error", err)
+			metrics.ScrapeErrors.WithLabelValues("fetch").Inc()
 			fetchErr = err
 			break
 		}
@@ -130,12 +177,13 @@ func (s *Service) ScrapeOnce(ctx context.Context) {
 		}
 		total = resp.Data.Total
 		allItems = append(allItems, resp.Data.Items...)
-		log.Printf("Fetched page %d/%d, total items so far: %d", page, (total/pageSize)+1, len(allItems))
+		slog.DebugContext(ctx, "Fetched page", "page", page, "pages", (total/pageSize)+1, "items", len(allItems))
 	}
 
 	// If the fetch failed and resulted in zero items, abort to avoid clearing state.
 	if fetchErr != nil && len(allItems) == 0 {
-		log.Println("Scrape cycle aborted due to fetch error with no items retrieved. Occupancy data will not be updated.")
+		slog.WarnContext(ctx, "Scrape cycle aborted: fetch failed with no items retrieved, occupancy data will not be updated")
+		s.recordScrape(ctx, now, nil, fetchErr)
 		return
 	}
 
@@ -143,38 +191,118 @@ func (s *Service) ScrapeOnce(ctx context.Context) {
 	for i := range allItems {
 		parsedTime, err := s.parseTimestamp(allItems[i].FinishTime)
 		if err != nil {
-			log.Printf("Warning: could not parse finishTime for machine %d: %v", allItems[i].ID, err)
+			slog.WarnContext(ctx, "Could not parse finishTime", "machine_id", allItems[i].ID, "error", err)
 			continue
 		}
 		allItems[i].FinishTimeParsed = parsedTime
 	}
 
 	if len(allItems) == 0 {
-		log.Println("Scrape cycle finished: no items to process.")
+		slog.InfoContext(ctx, "Scrape cycle has no items to process")
 		// Still need to process occupancy to archive any remaining open sessions.
 	}
 
 	// Step 2: Delegate persistence to the store layer
 	if err := s.store.UpsertDormsAndMachines(ctx, allItems); err != nil {
-		log.Printf("Error processing dorms and machines: %v", err)
+		slog.ErrorContext(ctx, "Error processing dorms and machines", "error", err)
+		metrics.ScrapeErrors.WithLabelValues("machines").Inc()
+		s.recordScrape(ctx, now, nil, err)
 		return // Return early if machine metadata fails
 	}
 
 	// Step 3: Delegate occupancy updates to the store layer
-	machineIDsToNotify, err := s.store.UpdateOccupancy(ctx, now, allItems, s.getStateType)
+	update, err := s.store.UpdateOccupancy(ctx, now, allItems, s.StateType)
 	if err != nil {
-		log.Printf("Error processing occupancy changes: %v", err)
+		slog.ErrorContext(ctx, "Error processing occupancy changes", "error", err)
+		metrics.ScrapeErrors.WithLabelValues("occupancy").Inc()
+		update = &store.OccupancyUpdate{}
+		s.recordScrape(ctx, now, nil, err)
+	} else {
+		// A partial fetch still confirms the machines it returned
+		seen := make([]int64, len(allItems))
+		for i, item := range allItems {
+			seen[i] = item.ID
+		}
+		s.recordScrape(ctx, now, seen, fetchErr)
+		if fetchErr == nil {
+			metrics.ScrapeLastSuccess.Set(float64(now.Unix()))
+		}
+		metrics.ScrapeTransitions.Observe(float64(len(update.Changes)))
+	}
+
+	// Push the committed changes to live clients
+	if s.events != nil {
+		s.events.Publish(now, update.Changes)
 	}
 
 	// Dispatch notification jobs to the worker pool
-	if len(machineIDsToNotify) > 0 {
-		log.Printf("Dispatching notifications for %d machines", len(machineIDsToNotify))
-		for _, machineID := range machineIDsToNotify {
-			s.workerPool.Dispatch(machineID)
+	if len(update.Notify) > 0 {
+		slog.InfoContext(ctx, "Dispatching notifications", "machines", len(update.Notify))
+		for _, machineID := range update.Notify {
+			s.workerPool.Dispatch(ctx, machineID)
 		}
 	}
 
-	log.Println("Scrape cycle finished.")
+	slog.InfoContext(ctx, "Scrape cycle finished", "items", len(allItems), "changes", len(update.Changes), "elapsed", time.Since(now))
+}
+
+// Trigger asks the running scraper for a cycle right away, even while it is
+// paused. It returns false if a triggered cycle is already pending.
+func (s *Service) Trigger() bool {
+	select {
+	case s.trigger <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// Pause makes the scraper skip scheduled cycles until Resume is called.
+// The flag is not persisted; a restarted process scrapes again.
+func (s *Service) Pause() {
+	s.paused.Store(true)
+}
+
+// Resume undoes Pause.
+func (s *Service) Resume() {
+	s.paused.Store(false)
+}
+
+// Status returns a snapshot of the scraper's health.
+func (s *Service) Status() Status {
+	s.mu.Lock()
+	status := s.status
+	s.mu.Unlock()
+
+	status.Enabled = s.cfg.Scraper.Enabled
+	status.Paused = s.paused.Load()
+	status.QueueDepth = s.workerPool.QueueDepth()
+	status.Workers = s.workerPool.Size()
+	return status
+}
+
+func (s *Service) finishCycle(start time.Time, pages int, latency time.Duration) {
+	duration := time.Since(start)
+	metrics.ScrapeDuration.Observe(duration.Seconds())
+	metrics.ScrapePages.Add(float64(pages))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status.LastCycleAt = start
+	s.status.LastCycleDuration = duration
+	s.status.UpstreamPages = pages
+	s.status.UpstreamLatency = 0
+	if pages > 0 {
+		s.status.UpstreamLatency = latency / time.Duration(pages)
+	}
+}
+
+// recordScrape stores the cycle outcome that freshness metadata is derived from.
+func (s *Service) recordScrape(ctx context.Context, now time.Time, seen []int64, scrapeErr error) {
+	if err := s.store.RecordScrape(ctx, now, seen, scrapeErr); err != nil {
+		slog.ErrorContext(ctx, "Error recording scrape state", "error", err)
+		metrics.ScrapeErrors.WithLabelValues("record").Inc()
+	}
 }
 
 // parseTimestamp converts the API's timestamp string into a time.Time object, respecting the configured timezone.
@@ -197,7 +325,7 @@ func (s *Service) parseTimestamp(tsStr *string) (*time.Time, error) {
 	return &parsedTime, nil
 }
 
-// fetchPage fetches a single page of device data from the upstream API. (This function remains unchanged)
+// fetchPage fetches a single page of device data from the upstream API.
 func (s *Service) fetchPage(ctx context.Context, page int) (*ApiResponse, error) {
 	payload := make(map[string]any)
 	for k, v := range s.cfg.Scraper.Request.Payload {
@@ -221,9 +349,11 @@ func (s *Service) fetchPage(ctx context.Context, page int) (*ApiResponse, error)
 
 	resp, err := s.client.Do(req)
 	if err != nil {
+		metrics.UpstreamResponses.WithLabelValues("error").Inc()
 		return nil, fmt.Errorf("http request failed: %w", err)
 	}
 	defer resp.Body.Close()
+	metrics.UpstreamResponses.WithLabelValues(strconv.Itoa(resp.StatusCode)).Inc()
 
 	if resp.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("received non-200 status code: %d", resp.StatusCode)
@@ -13,6 +13,7 @@ import (
 	"gorm.io/gorm"
 
 	"laundry-status-backend/config"
+	"laundry-status-backend/internal/logging"
 	"laundry-status-backend/internal/notification"
 	"laundry-status-backend/internal/store"
 )
@@ -26,7 +27,8 @@ type ApiData struct {
 // mockStore is a mock implementation of the store.Store interface.
 type mockStore struct {
 	UpsertDormsAndMachinesFunc func(ctx context.Context, items []store.ApiItem) error
-	UpdateOccupancyFunc        func(ctx context.Context, now time.Time, items []store.ApiItem, getStateType func(int) store.MachineStateType) ([]int64, error)
+	UpdateOccupancyFunc        func(ctx context.Context, now time.Time, items []store.ApiItem, getStateType func(int) store.MachineStateType) (*store.OccupancyUpdate, error)
+	RecordScrapeFunc           func(ctx context.Context, at time.Time, seen []int64, scrapeErr error) error
 	DBFunc                     func() *gorm.DB
 }
 
@@ -34,10 +36,17 @@ func (m *mockStore) UpsertDormsAndMachines(ctx context.Context, items []store.Ap
 	return m.UpsertDormsAndMachinesFunc(ctx, items)
 }
 
-func (m *mockStore) UpdateOccupancy(ctx context.Context, now time.Time, items []store.ApiItem, getStateType func(int) store.MachineStateType) ([]int64, error) {
+func (m *mockStore) UpdateOccupancy(ctx context.Context, now time.Time, items []store.ApiItem, getStateType func(int) store.MachineStateType) (*store.OccupancyUpdate, error) {
 	return m.UpdateOccupancyFunc(ctx, now, items, getStateType)
 }
 
+func (m *mockStore) RecordScrape(ctx context.Context, at time.Time, seen []int64, scrapeErr error) error {
+	if m.RecordScrapeFunc == nil {
+		return nil
+	}
+	return m.RecordScrapeFunc(ctx, at, seen, scrapeErr)
+}
+
 func (m *mockStore) DB() *gorm.DB {
 	return m.DBFunc()
 }
@@ -66,13 +75,15 @@ func TestScraper_Integration(t *testing.T) {
 	defer server.Close()
 
 	// Mock store
+	var storeCycle string
 	mockStore := &mockStore{
 		UpsertDormsAndMachinesFunc: func(ctx context.Context, items []store.ApiItem) error {
 			return nil // Do nothing
 		},
-		UpdateOccupancyFunc: func(ctx context.Context, now time.Time, items []store.ApiItem, getStateType func(int) store.MachineStateType) ([]int64, error) {
+		UpdateOccupancyFunc: func(ctx context.Context, now time.Time, items []store.ApiItem, getStateType func(int) store.MachineStateType) (*store.OccupancyUpdate, error) {
+			storeCycle = logging.CycleID(ctx)
 			// Simulate that machine 101 became idle and needs a notification
-			return []int64{101}, nil
+			return &store.OccupancyUpdate{Notify: []int64{101}}, nil
 		},
 		DBFunc: func() *gorm.DB {
 			return nil // Not needed for this test
@@ -94,7 +105,7 @@ func TestScraper_Integration(t *testing.T) {
 	}
 
 	// Create the scraper service with the mock store
-	service := NewService(cfg, mockStore)
+	service := NewService(cfg, mockStore, nil)
 
 	// Replace the real worker pool with a mock one
 	mockWorkerPool := notification.NewWorkerPool(1, nil, nil)
@@ -102,9 +113,11 @@ func TestScraper_Integration(t *testing.T) {
 
 	// Start the mock worker pool and listen for dispatched jobs
 	var dispatchedID int64
+	var dispatchedCycle string
 	go func() {
-		for id := range mockWorkerPool.Jobs() {
-			dispatchedID = id
+		for job := range mockWorkerPool.Jobs() {
+			dispatchedID = job.MachineID
+			dispatchedCycle = job.CycleID
 			wg.Done()
 		}
 	}()
@@ -115,4 +128,11 @@ func TestScraper_Integration(t *testing.T) {
 	// --- Verification ---
 	wg.Wait() // Wait for the job to be dispatched
 	assert.Equal(t, int64(101), dispatchedID, "The machine ID returned by UpdateOccupancy should be dispatched to the worker pool")
+	assert.NotEmpty(t, dispatchedCycle, "jobs carry the ID of the cycle that dispatched them")
+	assert.Equal(t, dispatchedCycle, storeCycle, "the store sees the same cycle ID")
+
+	status := service.Status()
+	assert.False(t, status.LastCycleAt.IsZero())
+	assert.Equal(t, 1, status.UpstreamPages)
+	assert.Equal(t, 1, status.Workers)
 }
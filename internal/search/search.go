@@ -0,0 +1,213 @@
+// Package search implements the fuzzy matching used to find machines by
+// dorm and display name, including pinyin spellings of Chinese names.
+package search
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/mozillazg/go-pinyin"
+)
+
+// Match scores, highest first.
+const (
+	ScoreNone        = 0
+	ScoreSubsequence = 1 // Query characters appear in order, e.g. "d31" in "东3#1-1"
+	ScoreSubstring   = 2
+	ScorePrefix      = 3
+)
+
+// Key is the searchable form of a name: the normalized text plus its pinyin
+// initials and full pinyin, e.g. "东3#1-1" -> "东3#1-1", "d3#1-1", "dong3#1-1".
+type Key struct {
+	Text     string
+	Initials string
+	Pinyin   string
+}
+
+var (
+	keysMu sync.RWMutex
+	keys   = make(map[string]Key) // Names repeat across requests; pinyin lookup is per rune
+)
+
+// maxCachedKeys bounds the memo; names come from the machines table, so this
+// is only reached if that grows unexpectedly.
+const maxCachedKeys = 1 << 16
+
+// KeyOf returns the searchable form of name.
+func KeyOf(name string) Key {
+	keysMu.RLock()
+	key, ok := keys[name]
+	keysMu.RUnlock()
+	if ok {
+		return key
+	}
+
+	key = buildKey(name)
+	keysMu.Lock()
+	if len(keys) < maxCachedKeys {
+		keys[name] = key
+	}
+	keysMu.Unlock()
+	return key
+}
+
+func buildKey(name string) Key {
+	initialsArgs := pinyin.NewArgs()
+	initialsArgs.Style = pinyin.FirstLetter
+	fullArgs := pinyin.NewArgs()
+
+	var text, initials, full strings.Builder
+	for _, r := range Normalize(name) {
+		text.WriteRune(r)
+		if !unicode.Is(unicode.Han, r) {
+			initials.WriteRune(r)
+			full.WriteRune(r)
+			continue
+		}
+		// 多音字取默认读音；没有拼音的汉字原样保留
+		if py := pinyin.SinglePinyin(r, initialsArgs); len(py) > 0 {
+			initials.WriteString(py[0])
+		} else {
+			initials.WriteRune(r)
+		}
+		if py := pinyin.SinglePinyin(r, fullArgs); len(py) > 0 {
+			full.WriteString(py[0])
+		} else {
+			full.WriteRune(r)
+		}
+	}
+	return Key{Text: text.String(), Initials: initials.String(), Pinyin: full.String()}
+}
+
+// Normalize lowercases s and drops whitespace, so "东3 #1" matches "东3#1".
+func Normalize(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if unicode.IsSpace(r) {
+			continue
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// Score returns how well the normalized term matches the key.
+func (k Key) Score(term string) int {
+	best := ScoreNone
+	for _, s := range []string{k.Text, k.Initials, k.Pinyin} {
+		if score := scoreString(s, term); score > best {
+			best = score
+		}
+	}
+	return best
+}
+
+func scoreString(s, term string) int {
+	switch {
+	case term == "":
+		return ScoreNone
+	case strings.HasPrefix(s, term):
+		return ScorePrefix
+	case strings.Contains(s, term):
+		return ScoreSubstring
+	case isSubsequence(s, term):
+		return ScoreSubsequence
+	}
+	return ScoreNone
+}
+
+func isSubsequence(s, term string) bool {
+	rest := []rune(term)
+	for _, r := range s {
+		if len(rest) == 0 {
+			break
+		}
+		if r == rest[0] {
+			rest = rest[1:]
+		}
+	}
+	return len(rest) == 0
+}
+
+// Terms splits a query into normalized terms; every term has to match.
+func Terms(query string) []string {
+	var terms []string
+	for _, field := range strings.Fields(query) {
+		if term := Normalize(field); term != "" {
+			terms = append(terms, term)
+		}
+	}
+	return terms
+}
+
+// MatchAll scores a query against the keys of one item, e.g. its dorm and
+// display names. Each term contributes its best score over the keys; the
+// result is 0 if any term matches none of them.
+func MatchAll(terms []string, keys ...Key) int {
+	total := 0
+	for _, term := range terms {
+		best := ScoreNone
+		for _, key := range keys {
+			if score := key.Score(term); score > best {
+				best = score
+			}
+		}
+		if best == ScoreNone {
+			return ScoreNone
+		}
+		total += best
+	}
+	return total
+}
+
+// farDorm is the distance between dorms that share no naming scheme.
+const farDorm = 1000
+
+// DormDistance estimates how far apart two dorms are from their names.
+// Dorms named like "东3" and "东5" are numbered within an area, so the
+// distance is the difference of the numbers; dorms of different areas are
+// farDorm apart.
+func DormDistance(a, b string) int {
+	if a == b {
+		return 0
+	}
+	areaA, numA, okA := splitDormName(a)
+	areaB, numB, okB := splitDormName(b)
+	if !okA || !okB || areaA != areaB {
+		return farDorm
+	}
+	if d := numA - numB; d > 0 {
+		return d
+	} else if d < 0 {
+		return -d
+	}
+	return 1 // Same number, different suffix, e.g. "东3" and "东3A"
+}
+
+// splitDormName splits a name at its first number into the area before it
+// and the number, e.g. "北区12号楼" -> ("北区", 12).
+func splitDormName(name string) (string, int, bool) {
+	runes := []rune(name)
+	start := -1
+	for i, r := range runes {
+		if unicode.IsDigit(r) {
+			start = i
+			break
+		}
+	}
+	if start < 0 {
+		return "", 0, false
+	}
+	end := start
+	for end < len(runes) && unicode.IsDigit(runes[end]) {
+		end++
+	}
+	n, err := strconv.Atoi(string(runes[start:end]))
+	if err != nil {
+		return "", 0, false
+	}
+	return string(runes[:start]), n, true
+}
@@ -0,0 +1,35 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyOf(t *testing.T) {
+	key := KeyOf("东3 #1-1")
+	assert.Equal(t, "东3#1-1", key.Text)
+	assert.Equal(t, "d3#1-1", key.Initials)
+	assert.Equal(t, "dong3#1-1", key.Pinyin)
+	assert.Equal(t, "bq12hl", KeyOf("北区12号楼").Initials)
+}
+
+func TestMatchAll(t *testing.T) {
+	dorm, machine := KeyOf("东3"), KeyOf("东3#2-烘干")
+
+	assert.Equal(t, ScorePrefix, MatchAll(Terms("d3"), dorm, machine))
+	assert.Equal(t, ScorePrefix, MatchAll(Terms("Dong3"), dorm, machine))
+	assert.Equal(t, ScoreSubstring, MatchAll(Terms("hg"), dorm, machine))
+	assert.Equal(t, ScoreSubsequence, MatchAll(Terms("d3hg"), dorm, machine))
+	assert.Equal(t, ScorePrefix+ScoreSubstring, MatchAll(Terms("东3  烘干"), dorm, machine), "terms may match different names")
+	assert.Equal(t, ScoreNone, MatchAll(Terms("d3 xi"), dorm, machine), "every term must match")
+}
+
+func TestDormDistance(t *testing.T) {
+	assert.Equal(t, 0, DormDistance("东3", "东3"))
+	assert.Equal(t, 2, DormDistance("东3", "东5"))
+	assert.Equal(t, 1, DormDistance("东3", "东3A"))
+	assert.Equal(t, 3, DormDistance("北区12号楼", "北区9号楼"))
+	assert.Equal(t, farDorm, DormDistance("东3", "西3"))
+	assert.Equal(t, farDorm, DormDistance("研究生公寓", "东3"))
+}
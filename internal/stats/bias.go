@@ -0,0 +1,63 @@
+package stats
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+
+	"laundry-status-backend/internal/model"
+)
+
+const (
+	// biasSampleLimit bounds the number of recent sessions the bias is taken from.
+	biasSampleLimit = 2000
+	// minBiasSamples is the number of sessions needed before a bias is trusted.
+	minBiasSamples = 10
+)
+
+// Bias is how much later than predicted machines were observed to finish.
+type Bias struct {
+	Median  time.Duration
+	Samples int
+}
+
+// PredictionBias returns the median of observed end minus predicted end over
+// the sessions of the selected machines observed in [From, To). The observed
+// end includes up to one scrape interval of detection delay, which is part of
+// the wait users experience too. Below minBiasSamples the bias is zero.
+func PredictionBias(ctx context.Context, db *gorm.DB, q Query) (Bias, error) {
+	db = db.WithContext(ctx)
+
+	// 无预计时长的状态归档时 period_end 即 observed_at，不参与统计
+	query := db.Select("observed_at, period_end").
+		Where("machine_id IN (?) AND observed_at >= ? AND observed_at < ?", machineIDs(db, q), q.From, q.To).
+		Where("period_end > period_start AND period_end <> observed_at")
+	if len(q.Statuses) > 0 {
+		query = query.Where("status IN ?", q.Statuses)
+	}
+
+	var sessions []model.OccupancyHistory
+	if err := query.Order("observed_at DESC").Limit(biasSampleLimit).Find(&sessions).Error; err != nil {
+		return Bias{}, fmt.Errorf("failed to load finished sessions of dorm %d: %w", q.DormID, err)
+	}
+
+	bias := Bias{Samples: len(sessions)}
+	if len(sessions) < minBiasSamples {
+		return bias, nil
+	}
+	deltas := make([]time.Duration, len(sessions))
+	for i, s := range sessions {
+		deltas[i] = s.ObservedAt.Sub(s.PeriodEnd)
+	}
+	sort.Slice(deltas, func(i, j int) bool { return deltas[i] < deltas[j] })
+	mid := len(deltas) / 2
+	if len(deltas)%2 == 0 {
+		bias.Median = (deltas[mid-1] + deltas[mid]) / 2
+	} else {
+		bias.Median = deltas[mid]
+	}
+	return bias, nil
+}
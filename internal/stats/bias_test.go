@@ -0,0 +1,47 @@
+package stats
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"laundry-status-backend/internal/model"
+)
+
+func TestPredictionBias(t *testing.T) {
+	testDB := newTestDB(t)
+	to := time.Date(2025, 3, 17, 0, 0, 0, 0, time.UTC)
+	q := Query{DormID: 1, Statuses: []int{2}, From: to.Add(-7 * 24 * time.Hour), To: to}
+
+	require.NoError(t, testDB.Create(&model.Dorm{ID: 1, Name: "东3"}).Error)
+	require.NoError(t, testDB.Create(&model.Machine{ID: 11, DormID: 1, Floor: 1}).Error)
+
+	var sessions []model.OccupancyHistory
+	for i := 0; i < minBiasSamples; i++ {
+		start := to.Add(-time.Duration(i+2) * time.Hour)
+		late := time.Duration(i+1) * time.Minute // 1..10 minutes late
+		sessions = append(sessions, model.OccupancyHistory{
+			MachineID: 11, Status: 2, PeriodStart: start,
+			PeriodEnd: start.Add(40 * time.Minute), ObservedAt: start.Add(40*time.Minute + late),
+		})
+	}
+	// Faults without a prediction are ignored.
+	sessions = append(sessions, model.OccupancyHistory{
+		MachineID: 11, Status: 3, PeriodStart: to.Add(-20 * time.Hour), PeriodEnd: to.Add(-19 * time.Hour), ObservedAt: to.Add(-19 * time.Hour),
+	})
+
+	require.NoError(t, testDB.Create(sessions[:minBiasSamples-1]).Error)
+	bias, err := PredictionBias(context.Background(), testDB, q)
+	require.NoError(t, err)
+	assert.Equal(t, minBiasSamples-1, bias.Samples)
+	assert.Zero(t, bias.Median, "too few samples to trust")
+
+	require.NoError(t, testDB.Create(sessions[minBiasSamples-1:]).Error)
+	bias, err = PredictionBias(context.Background(), testDB, q)
+	require.NoError(t, err)
+	assert.Equal(t, minBiasSamples, bias.Samples)
+	assert.Equal(t, 5*time.Minute+30*time.Second, bias.Median)
+}
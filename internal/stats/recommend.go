@@ -0,0 +1,132 @@
+package stats
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// currentInfoHalfLife is how quickly the live machine states lose weight
+// against the historical pattern as a window lies further in the future.
+const currentInfoHalfLife = time.Hour
+
+// MachineState is what is currently known about one machine.
+type MachineState struct {
+	MachineID int64
+	Floor     int
+	Available bool
+	// FreeAt is the expected finish time of a busy machine. It is nil for
+	// available machines and for states without a prediction, such as faults.
+	FreeAt *time.Time
+}
+
+// RecommendOptions parameterises Recommend.
+type RecommendOptions struct {
+	Now      time.Time
+	Duration time.Duration // Length of the wash the user plans
+	Within   time.Duration // Only windows ending before Now+Within are considered
+	Step     time.Duration // Spacing of candidate start times
+	Limit    int
+	Location *time.Location // Location the utilization slots are reckoned in
+}
+
+// Window is a recommended time to start a wash.
+type Window struct {
+	Start time.Time
+	End   time.Time
+	// Probability that at least one machine is free at Start.
+	Probability float64
+	// Confidence in Probability: 1 when it rests on live states alone and
+	// decreasing as it relies on sparse history.
+	Confidence float64
+}
+
+// Recommend ranks candidate windows by the probability that one of machines is
+// free when the window starts. Near-term estimates follow the live states and
+// predicted finish times; further out they fade into the historical
+// hour-of-week utilization. Returned windows do not overlap.
+func Recommend(machines []MachineState, util *Utilization, opts RecommendOptions) []Window {
+	if len(machines) == 0 || opts.Duration <= 0 || opts.Step <= 0 {
+		return nil
+	}
+	if opts.Location == nil {
+		opts.Location = time.UTC
+	}
+
+	var candidates []Window
+	deadline := opts.Now.Add(opts.Within)
+	for start := opts.Now; !start.Add(opts.Duration).After(deadline); start = start.Add(opts.Step) {
+		end := start.Add(opts.Duration)
+		weight := math.Pow(0.5, float64(start.Sub(opts.Now))/float64(currentInfoHalfLife))
+		historicalFree := historicalFreeness(util, start, end, opts.Location)
+
+		allBusy := 1.0
+		for _, m := range machines {
+			current := 0.0
+			if m.Available || (m.FreeAt != nil && !m.FreeAt.After(start)) {
+				current = 1
+			}
+			allBusy *= 1 - (weight*current + (1-weight)*historicalFree)
+		}
+
+		candidates = append(candidates, Window{
+			Start:       start,
+			End:         end,
+			Probability: 1 - allBusy,
+			Confidence:  weight + (1-weight)*coverageOf(util),
+		})
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Probability > candidates[j].Probability
+	})
+
+	var picked []Window
+	for _, c := range candidates {
+		if opts.Limit > 0 && len(picked) >= opts.Limit {
+			break
+		}
+		overlaps := false
+		for _, p := range picked {
+			if c.Start.Before(p.End) && p.Start.Before(c.End) {
+				overlaps = true
+				break
+			}
+		}
+		if !overlaps {
+			picked = append(picked, c)
+		}
+	}
+	return picked
+}
+
+// historicalFreeness is the chance a machine is free over [start, end) by the
+// hour-of-week pattern, shrunk towards an even chance when history is sparse.
+func historicalFreeness(util *Utilization, start, end time.Time, loc *time.Location) float64 {
+	c := coverageOf(util)
+	return c*(1-averageUtilization(util, start, end, loc)) + (1-c)*0.5
+}
+
+func coverageOf(util *Utilization) float64 {
+	if util == nil {
+		return 0
+	}
+	return util.Coverage
+}
+
+// averageUtilization is the time-weighted utilization over [start, end).
+func averageUtilization(util *Utilization, start, end time.Time, loc *time.Location) float64 {
+	if util == nil || !start.Before(end) {
+		return 0
+	}
+	var sum float64
+	for t := start; t.Before(end); {
+		next := t.Truncate(time.Hour).Add(time.Hour)
+		if next.After(end) {
+			next = end
+		}
+		sum += util.Slots[HourOfWeek(t.In(loc))] * float64(next.Sub(t))
+		t = next
+	}
+	return sum / float64(end.Sub(start))
+}
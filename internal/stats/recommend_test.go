@@ -0,0 +1,76 @@
+package stats
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecommend(t *testing.T) {
+	// Monday 08:00 UTC.
+	now := time.Date(2025, 3, 17, 8, 0, 0, 0, time.UTC)
+	util := &Utilization{Machines: 2, Coverage: 1}
+	for slot := range util.Slots {
+		util.Slots[slot] = 0.9
+	}
+	// Monday 13:00 is historically quiet.
+	util.Slots[HourOfWeek(now.Add(5*time.Hour))] = 0.1
+
+	freeAt := now.Add(50 * time.Minute)
+	machines := []MachineState{
+		{MachineID: 1, Floor: 3, FreeAt: &freeAt},
+		{MachineID: 2, Floor: 3}, // Faulty: busy without a prediction
+	}
+
+	windows := Recommend(machines, util, RecommendOptions{
+		Now: now, Duration: 45 * time.Minute, Within: 8 * time.Hour, Step: 15 * time.Minute, Limit: 3,
+	})
+	require.Len(t, windows, 3)
+
+	// The historically quiet hour ranks first.
+	assert.Equal(t, 13, windows[0].Start.Hour())
+	assert.Equal(t, windows[0].Start.Add(45*time.Minute), windows[0].End)
+	assert.Greater(t, windows[0].Probability, 0.95)
+
+	// Right after the predicted finish, the live state lifts an otherwise busy hour.
+	var afterFinish *Window
+	for i := range windows {
+		if windows[i].Start.Equal(now.Add(time.Hour)) {
+			afterFinish = &windows[i]
+		}
+	}
+	require.NotNil(t, afterFinish, "expected the window after the predicted finish among %v", windows)
+	assert.Greater(t, afterFinish.Probability, 0.5)
+
+	for i := 1; i < len(windows); i++ {
+		assert.GreaterOrEqual(t, windows[i-1].Probability, windows[i].Probability)
+		for j := 0; j < i; j++ {
+			overlap := windows[i].Start.Before(windows[j].End) && windows[j].Start.Before(windows[i].End)
+			assert.False(t, overlap, "windows %d and %d overlap", i, j)
+		}
+	}
+
+	// While the machine is still running there is no live chance of a free machine.
+	busy := Recommend(machines, util, RecommendOptions{
+		Now: now, Duration: 45 * time.Minute, Within: 45 * time.Minute, Step: 15 * time.Minute,
+	})
+	require.Len(t, busy, 1)
+	assert.Less(t, busy[0].Probability, 0.05)
+
+	// Without history the estimate falls back to an even chance with low confidence.
+	sparse := Recommend(machines, &Utilization{}, RecommendOptions{
+		Now: now, Duration: time.Hour, Within: 24 * time.Hour, Step: time.Hour, Limit: 24,
+	})
+	last := sparse[0]
+	for _, w := range sparse {
+		if w.Start.After(last.Start) {
+			last = w
+		}
+	}
+	assert.InDelta(t, 0.75, last.Probability, 0.01)
+	assert.Less(t, last.Confidence, 0.01)
+
+	assert.Empty(t, Recommend(nil, util, RecommendOptions{Now: now, Duration: time.Hour, Within: time.Hour, Step: time.Hour}))
+}
@@ -0,0 +1,295 @@
+// Package stats derives usage statistics from occupancy history.
+package stats
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"laundry-status-backend/internal/model"
+	"laundry-status-backend/internal/store"
+)
+
+// HoursPerWeek is the number of hour-of-week slots.
+const HoursPerWeek = 7 * 24
+
+// HourOfWeek returns the slot of t in its own location, Sunday 00:00 being 0.
+func HourOfWeek(t time.Time) int {
+	return int(t.Weekday())*24 + t.Hour()
+}
+
+// Query selects the machines and the period a statistic is computed over.
+type Query struct {
+	DormID   int64
+	Kind     string // Optional machine kind filter
+	Floors   []int  // Optional floor filter
+	Statuses []int  // States counted as occupied; empty counts every archived state
+	From     time.Time
+	To       time.Time
+	Location *time.Location // Location hour-of-week slots are reckoned in
+	// Timescale splits segments into hourly buckets in the database with
+	// time_bucket instead of streaming them into Go.
+	Timescale bool
+	// RawSince is the retention cutoff of raw history: older segments may
+	// have been pruned, so they are read from the occupancy_hourly rollup
+	// instead. Zero reads raw history only.
+	RawSince time.Time
+}
+
+// Utilization is the average fraction of machines occupied per hour-of-week.
+type Utilization struct {
+	Machines int64
+	Slots    [HoursPerWeek]float64
+	// Coverage is the fraction of [From, To) after the earliest history
+	// record, i.e. how much of the average is backed by data at all. Slots
+	// only average the hours from that record on.
+	Coverage float64
+}
+
+// DormUtilization computes the utilization heatmap of a dorm over [From, To).
+// Buckets are whole hours, so locations with fractional UTC offsets are
+// attributed to the hour their bucket starts in.
+func DormUtilization(ctx context.Context, db *gorm.DB, q Query) (*Utilization, error) {
+	db = db.WithContext(ctx)
+	if q.Location == nil {
+		q.Location = time.UTC
+	}
+
+	machines := machineScope(db, q)
+	var count int64
+	if err := machines.Session(&gorm.Session{}).Count(&count).Error; err != nil {
+		return nil, fmt.Errorf("failed to count machines of dorm %d: %w", q.DormID, err)
+	}
+	result := &Utilization{Machines: count}
+	if count == 0 {
+		return result, nil
+	}
+
+	// Segments are split by the hour they were observed to end in: those
+	// before rawFrom come from the rollup, the others from raw history
+	rawFrom := q.From
+	if !q.RawSince.IsZero() {
+		rawFrom = ceilHour(q.RawSince)
+	}
+
+	var (
+		busy     map[time.Time]float64
+		dataFrom time.Time
+		err      error
+	)
+	if dataFrom, result.Coverage, err = coverage(db, q, rawFrom); err != nil {
+		return nil, err
+	}
+	if q.Timescale {
+		busy, err = busyBucketsTimescale(db, q, rawFrom)
+	} else {
+		busy, err = busyBucketsGo(db, q, rawFrom)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if rawFrom.After(q.From) {
+		if err := addRolledUpBusy(db, q, rawFrom, busy); err != nil {
+			return nil, err
+		}
+	}
+
+	// 当前仍在进行中的状态也计入，结束时间按 To 截断
+	var opens []model.OccupancyOpen
+	openQuery := db.Where("machine_id IN (?) AND observed_at < ?", machineIDs(db, q), q.To)
+	if len(q.Statuses) > 0 {
+		openQuery = openQuery.Where("status IN ?", q.Statuses)
+	}
+	if err := openQuery.Find(&opens).Error; err != nil {
+		return nil, fmt.Errorf("failed to load open occupancy of dorm %d: %w", q.DormID, err)
+	}
+	for _, o := range opens {
+		addBusy(busy, o.ObservedAt, q.To, q.From, q.To)
+	}
+
+	// Hours before the earliest record have no data rather than no use, e.g.
+	// after raw history was pruned, so they are left out of the average
+	var samples [HoursPerWeek]int
+	var total [HoursPerWeek]float64
+	for h := dataFrom.Truncate(time.Hour); h.Before(q.To); h = h.Add(time.Hour) {
+		slot := HourOfWeek(h.In(q.Location))
+		samples[slot]++
+		total[slot] += busy[h.UTC()]
+	}
+	capacity := float64(count) * time.Hour.Seconds()
+	for slot := range result.Slots {
+		if samples[slot] > 0 {
+			result.Slots[slot] = total[slot] / capacity / float64(samples[slot])
+		}
+	}
+	return result, nil
+}
+
+func machineScope(db *gorm.DB, q Query) *gorm.DB {
+	query := db.Model(&model.Machine{}).Scopes(store.VisibleMachines).Where("dorm_id = ?", q.DormID)
+	if q.Kind != "" {
+		query = query.Where("kind = ?", q.Kind)
+	}
+	if len(q.Floors) > 0 {
+		query = query.Where("floor IN ?", q.Floors)
+	}
+	return query
+}
+
+func machineIDs(db *gorm.DB, q Query) *gorm.DB {
+	return machineScope(db, q).Select("id")
+}
+
+// coverage returns the start of the data for [From, To), i.e. From or the
+// earliest later history record of the selected machines, and the fraction
+// of [From, To) following it. Without any record the data starts at To.
+// Before rawFrom, the earliest rollup bucket stands in for raw records.
+func coverage(db *gorm.DB, q Query, rawFrom time.Time) (time.Time, float64, error) {
+	start := q.To
+	if rawFrom.After(q.From) {
+		var first []model.OccupancyHourly
+		err := db.Select("bucket").
+			Where("machine_id IN (?) AND bucket >= ? AND bucket < ?", machineIDs(db, q), q.From.Truncate(time.Hour), rawFrom).
+			Order("bucket").Limit(1).
+			Find(&first).Error
+		if err != nil {
+			return q.To, 0, fmt.Errorf("failed to find earliest rollup of dorm %d: %w", q.DormID, err)
+		}
+		if len(first) > 0 {
+			start = first[0].Bucket
+		}
+	}
+
+	var first []model.OccupancyHistory
+	err := db.Select("period_start").
+		Where("machine_id IN (?) AND observed_at > ?", machineIDs(db, q), q.From).
+		Order("period_start").Limit(1).
+		Find(&first).Error
+	if err != nil {
+		return q.To, 0, fmt.Errorf("failed to find earliest history of dorm %d: %w", q.DormID, err)
+	}
+	if len(first) > 0 && first[0].PeriodStart.Before(start) {
+		start = first[0].PeriodStart
+	}
+	if !start.Before(q.To) {
+		return q.To, 0, nil
+	}
+	if start.Before(q.From) {
+		return q.From, 1, nil
+	}
+	return start, float64(q.To.Sub(start)) / float64(q.To.Sub(q.From)), nil
+}
+
+// busyBucketsGo streams the overlapping history segments observed to end at
+// or after rawFrom and splits them into hourly buckets in Go.
+func busyBucketsGo(db *gorm.DB, q Query, rawFrom time.Time) (map[time.Time]float64, error) {
+	busy := make(map[time.Time]float64)
+
+	query := db.Model(&model.OccupancyHistory{}).
+		Select("machine_id, observed_at, period_start").
+		Where("machine_id IN (?) AND observed_at > ? AND observed_at >= ? AND period_start < ?", machineIDs(db, q), q.From, rawFrom, q.To)
+	if len(q.Statuses) > 0 {
+		query = query.Where("status IN ?", q.Statuses)
+	}
+
+	var batch []model.OccupancyHistory
+	err := query.FindInBatches(&batch, 1000, func(tx *gorm.DB, _ int) error {
+		for _, h := range batch {
+			addBusy(busy, h.PeriodStart, h.ObservedAt, q.From, q.To)
+		}
+		return nil
+	}).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan occupancy history of dorm %d: %w", q.DormID, err)
+	}
+	return busy, nil
+}
+
+// busyBucketsTimescale splits the segments observed to end at or after
+// rawFrom into hourly buckets with time_bucket and generate_series, returning
+// one row per bucket.
+func busyBucketsTimescale(db *gorm.DB, q Query, rawFrom time.Time) (map[time.Time]float64, error) {
+	statusFilter := ""
+	args := []any{q.From, q.To, machineIDs(db, q), q.From, rawFrom, q.To}
+	if len(q.Statuses) > 0 {
+		statusFilter = " AND status IN ?"
+		args = append(args, q.Statuses)
+	}
+
+	var rows []struct {
+		Bucket      time.Time
+		BusySeconds float64
+	}
+	err := db.Raw(
+		"SELECT b.bucket AS bucket, "+
+			"SUM(EXTRACT(EPOCH FROM LEAST(s.end_at, b.bucket + INTERVAL '1 hour') - GREATEST(s.start_at, b.bucket))) AS busy_seconds "+
+			"FROM (SELECT GREATEST(period_start, ?) AS start_at, LEAST(observed_at, ?) AS end_at "+
+			"FROM occupancy_histories WHERE machine_id IN (?) AND observed_at > ? AND observed_at >= ? AND period_start < ?"+statusFilter+") s "+
+			"CROSS JOIN LATERAL generate_series(time_bucket(INTERVAL '1 hour', s.start_at), "+
+			"s.end_at - INTERVAL '1 microsecond', INTERVAL '1 hour') AS b(bucket) "+
+			"GROUP BY b.bucket",
+		args...,
+	).Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to bucket occupancy history of dorm %d: %w", q.DormID, err)
+	}
+
+	busy := make(map[time.Time]float64, len(rows))
+	for _, r := range rows {
+		busy[r.Bucket.UTC()] = r.BusySeconds
+	}
+	return busy, nil
+}
+
+// addRolledUpBusy adds the rolled-up sessions observed to end in [From,
+// rawFrom) to busy. The rollup only keeps each machine's busy seconds per
+// hour the sessions ended in, so they are assumed to have run back to back
+// up to the end of that hour.
+func addRolledUpBusy(db *gorm.DB, q Query, rawFrom time.Time, busy map[time.Time]float64) error {
+	query := db.Model(&model.OccupancyHourly{}).
+		Select("bucket, machine_id, SUM(busy_seconds) AS busy_seconds").
+		Where("machine_id IN (?) AND bucket >= ? AND bucket < ?", machineIDs(db, q), q.From.Truncate(time.Hour), rawFrom).
+		Group("bucket, machine_id")
+	if len(q.Statuses) > 0 {
+		query = query.Where("status IN ?", q.Statuses)
+	}
+
+	var rows []model.OccupancyHourly
+	if err := query.Scan(&rows).Error; err != nil {
+		return fmt.Errorf("failed to load hourly rollups of dorm %d: %w", q.DormID, err)
+	}
+	for _, r := range rows {
+		end := r.Bucket.Add(time.Hour)
+		addBusy(busy, end.Add(-time.Duration(r.BusySeconds)*time.Second), end, q.From, q.To)
+	}
+	return nil
+}
+
+// ceilHour rounds t up to a whole hour.
+func ceilHour(t time.Time) time.Time {
+	if hour := t.Truncate(time.Hour); hour.Before(t) {
+		return hour.Add(time.Hour)
+	}
+	return t
+}
+
+// addBusy adds the part of [start, end) inside [from, to) to the hourly buckets.
+func addBusy(busy map[time.Time]float64, start, end, from, to time.Time) {
+	if start.Before(from) {
+		start = from
+	}
+	if end.After(to) {
+		end = to
+	}
+	for start.Before(end) {
+		bucket := start.Truncate(time.Hour)
+		next := bucket.Add(time.Hour)
+		if next.After(end) {
+			next = end
+		}
+		busy[bucket.UTC()] += next.Sub(start).Seconds()
+		start = next
+	}
+}
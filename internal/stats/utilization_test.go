@@ -0,0 +1,170 @@
+package stats
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"laundry-status-backend/internal/model"
+	"laundry-status-backend/internal/testdb"
+)
+
+func newTestDB(t *testing.T) *gorm.DB {
+	return testdb.New(t,
+		&model.Dorm{}, &model.Machine{}, &model.MachineOverride{},
+		&model.OccupancyOpen{}, &model.OccupancyHistory{}, &model.OccupancyHourly{},
+	)
+}
+
+func TestDormUtilization(t *testing.T) {
+	testDB := newTestDB(t)
+	loc := time.FixedZone("CST", 8*3600)
+	// Monday 00:00 local time.
+	to := time.Date(2025, 3, 17, 0, 0, 0, 0, loc)
+	from := to.Add(-7 * 24 * time.Hour)
+
+	require.NoError(t, testDB.Create(&model.Dorm{ID: 1, Name: "东3"}).Error)
+	require.NoError(t, testDB.Create(&[]model.Machine{
+		{ID: 11, DormID: 1, Floor: 1, Kind: model.MachineKindWasher},
+		{ID: 12, DormID: 1, Floor: 1, Kind: model.MachineKindWasher},
+		{ID: 13, DormID: 2, Floor: 1, Kind: model.MachineKindWasher},
+	}).Error)
+
+	// Sunday 20:30–22:00 local on machine 11, spanning two hour slots.
+	sunday := to.Add(-4 * time.Hour)
+	require.NoError(t, testDB.Create(&[]model.OccupancyHistory{
+		{MachineID: 11, Status: 2, PeriodStart: sunday.Add(30 * time.Minute), PeriodEnd: sunday.Add(75 * time.Minute), ObservedAt: sunday.Add(2 * time.Hour)},
+		// Faulty states are not counted as occupied.
+		{MachineID: 12, Status: 3, PeriodStart: sunday, PeriodEnd: sunday.Add(time.Hour), ObservedAt: sunday.Add(time.Hour)},
+		// Another dorm.
+		{MachineID: 13, Status: 2, PeriodStart: sunday, PeriodEnd: sunday.Add(time.Hour), ObservedAt: sunday.Add(time.Hour)},
+		// Started before the window: only the part inside counts (Monday 00:00–00:30).
+		{MachineID: 12, Status: 2, PeriodStart: from.Add(-time.Hour), PeriodEnd: from, ObservedAt: from.Add(30 * time.Minute)},
+	}).Error)
+	// Still running at the end of the window: Sunday 23:00 until To.
+	require.NoError(t, testDB.Create(&model.OccupancyOpen{MachineID: 12, Status: 2, ObservedAt: to.Add(-time.Hour), TimeRemaining: 3600}).Error)
+
+	util, err := DormUtilization(context.Background(), testDB, Query{
+		DormID: 1, Statuses: []int{2}, From: from, To: to, Location: loc,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), util.Machines)
+	assert.Equal(t, 1.0, util.Coverage, "history starts before the window")
+
+	sun := int(time.Sunday) * 24
+	mon := int(time.Monday) * 24
+	assert.InDelta(t, 0.25, util.Slots[sun+20], 1e-9, "half an hour of one of two machines")
+	assert.InDelta(t, 0.5, util.Slots[sun+21], 1e-9)
+	assert.InDelta(t, 0.5, util.Slots[sun+23], 1e-9)
+	assert.InDelta(t, 0.25, util.Slots[mon], 1e-9)
+	assert.Zero(t, util.Slots[sun+22])
+
+	var sum float64
+	for _, v := range util.Slots {
+		sum += v
+	}
+	assert.InDelta(t, 1.5, sum, 1e-9)
+
+	floor, err := DormUtilization(context.Background(), testDB, Query{
+		DormID: 1, Floors: []int{2}, From: from, To: to,
+	})
+	require.NoError(t, err)
+	assert.Zero(t, floor.Machines)
+
+	empty, err := DormUtilization(context.Background(), testDB, Query{DormID: 1, Kind: model.MachineKindDryer, From: from, To: to})
+	require.NoError(t, err)
+	assert.Zero(t, empty.Machines)
+}
+
+func TestDormUtilization_PartialCoverage(t *testing.T) {
+	testDB := newTestDB(t)
+	to := time.Date(2025, 3, 17, 0, 0, 0, 0, time.UTC)
+	from := to.Add(-4 * 7 * 24 * time.Hour)
+	require.NoError(t, testDB.Create(&model.Machine{ID: 11, DormID: 1, Floor: 1, Kind: model.MachineKindWasher}).Error)
+
+	// Older history was pruned: the data starts on the last Sunday, 20:00
+	sunday := to.Add(-4 * time.Hour)
+	require.NoError(t, testDB.Create(&[]model.OccupancyHistory{
+		{MachineID: 11, Status: 2, PeriodStart: sunday, PeriodEnd: sunday.Add(time.Hour), ObservedAt: sunday.Add(time.Hour)},
+		{MachineID: 11, Status: 2, PeriodStart: sunday.Add(2 * time.Hour), PeriodEnd: sunday.Add(150 * time.Minute), ObservedAt: sunday.Add(150 * time.Minute)},
+	}).Error)
+
+	util, err := DormUtilization(context.Background(), testDB, Query{
+		DormID: 1, Statuses: []int{2}, From: from, To: to,
+	})
+	require.NoError(t, err)
+	assert.InDelta(t, 4.0/(28*24), util.Coverage, 1e-9)
+
+	sun := int(time.Sunday) * 24
+	assert.InDelta(t, 1.0, util.Slots[sun+20], 1e-9, "the weeks without data do not dilute the average")
+	assert.InDelta(t, 0.5, util.Slots[sun+22], 1e-9)
+	assert.Zero(t, util.Slots[sun+21])
+}
+
+func TestDormUtilization_Rollup(t *testing.T) {
+	testDB := newTestDB(t)
+	to := time.Date(2025, 3, 17, 0, 0, 0, 0, time.UTC)
+	from := to.Add(-2 * 7 * 24 * time.Hour)
+	rawSince := to.Add(-7*24*time.Hour + 30*time.Minute)
+	require.NoError(t, testDB.Create(&model.Machine{ID: 11, DormID: 1, Floor: 1, Kind: model.MachineKindWasher}).Error)
+
+	// Sunday 20:00–21:00 in both weeks: the older one only survives in the rollup
+	sunday := to.Add(-4 * time.Hour)
+	older := sunday.Add(-7 * 24 * time.Hour)
+	require.NoError(t, testDB.Create(&[]model.OccupancyHistory{
+		{MachineID: 11, Status: 2, PeriodStart: sunday, PeriodEnd: sunday.Add(time.Hour), ObservedAt: sunday.Add(59 * time.Minute)},
+		// Not pruned yet, but already rolled up; it must not count twice
+		{MachineID: 11, Status: 2, PeriodStart: older, PeriodEnd: older.Add(time.Hour), ObservedAt: older.Add(59 * time.Minute)},
+	}).Error)
+	require.NoError(t, testDB.Create(&[]model.OccupancyHourly{
+		{Bucket: older, MachineID: 11, Status: 2, Sessions: 1, BusySeconds: 3540},
+		{Bucket: older, MachineID: 11, Status: 3, Sessions: 1, BusySeconds: 60},
+	}).Error)
+
+	util, err := DormUtilization(context.Background(), testDB, Query{
+		DormID: 1, Statuses: []int{2}, From: from, To: to, RawSince: rawSince,
+	})
+	require.NoError(t, err)
+	assert.InDelta(t, float64(to.Sub(older))/float64(to.Sub(from)), util.Coverage, 1e-9, "the rollup counts as data")
+
+	sun := int(time.Sunday) * 24
+	assert.InDelta(t, (3540.0/3600+59.0/60)/2, util.Slots[sun+20], 1e-9)
+	assert.Zero(t, util.Slots[sun+19])
+}
+
+func TestDormUtilization_Timescale(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer sqlDB.Close()
+	testDB, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{Logger: logger.Discard})
+	require.NoError(t, err)
+
+	to := time.Date(2025, 3, 17, 0, 0, 0, 0, time.UTC)
+	from := to.Add(-7 * 24 * time.Hour)
+	bucket := to.Add(-3 * time.Hour)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "machines"`)).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(4))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT "period_start" FROM "occupancy_histories"`)).
+		WillReturnRows(sqlmock.NewRows([]string{"period_start"}).AddRow(from.Add(-time.Hour)))
+	mock.ExpectQuery(`time_bucket\(INTERVAL '1 hour', s\.start_at\).*GROUP BY b\.bucket`).
+		WillReturnRows(sqlmock.NewRows([]string{"bucket", "busy_seconds"}).AddRow(bucket, 7200.0))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "occupancy_opens"`)).
+		WillReturnRows(sqlmock.NewRows([]string{"machine_id"}))
+
+	util, err := DormUtilization(context.Background(), testDB, Query{
+		DormID: 1, Statuses: []int{2}, From: from, To: to, Timescale: true,
+	})
+	require.NoError(t, err)
+	assert.InDelta(t, 0.5, util.Slots[HourOfWeek(bucket)], 1e-9)
+	assert.Equal(t, 1.0, util.Coverage)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,156 @@
+package store
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"laundry-status-backend/internal/model"
+)
+
+const (
+	// apiKeyPrefix marks the keys of this service, so leaked keys are recognizable.
+	apiKeyPrefix = "lsk_"
+	// apiKeyDisplayLength is how much of a key is kept in clear to identify it.
+	apiKeyDisplayLength = 12
+	// lastUsedResolution limits last_used_at writes to one per key and minute.
+	lastUsedResolution = time.Minute
+	// maxAuditBodyLength matches the size of audit_logs.body.
+	maxAuditBodyLength = 2048
+)
+
+var (
+	// ErrAPIKeyNotFound is returned for unknown or revoked API keys.
+	ErrAPIKeyNotFound = errors.New("API key not found")
+	// ErrInvalidRole is returned when creating a key with an unknown role.
+	ErrInvalidRole = errors.New("role must be viewer, operator or admin")
+)
+
+var roleRanks = map[string]int{model.RoleViewer: 1, model.RoleOperator: 2, model.RoleAdmin: 3}
+
+// RoleAllows reports whether role grants at least the privileges of required.
+func RoleAllows(role, required string) bool {
+	rank, ok := roleRanks[role]
+	return ok && rank >= roleRanks[required]
+}
+
+// HashAPIKey returns the stored form of a key. Keys are random, so a plain
+// SHA-256 is enough; a slow hash would only add latency to every request.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateAPIKey generates and stores a new key with the given role. The
+// returned key is the only copy of it; only its hash is stored.
+func CreateAPIKey(ctx context.Context, db *gorm.DB, name, role string, now time.Time) (string, model.APIKey, error) {
+	if _, ok := roleRanks[role]; !ok {
+		return "", model.APIKey{}, ErrInvalidRole
+	}
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", model.APIKey{}, fmt.Errorf("failed to generate API key: %w", err)
+	}
+	key := apiKeyPrefix + base64.RawURLEncoding.EncodeToString(secret)
+
+	record := model.APIKey{
+		Name:      name,
+		Prefix:    key[:apiKeyDisplayLength],
+		Hash:      HashAPIKey(key),
+		Role:      role,
+		CreatedAt: now,
+	}
+	if err := db.WithContext(ctx).Create(&record).Error; err != nil {
+		return "", model.APIKey{}, fmt.Errorf("failed to store API key: %w", err)
+	}
+	return key, record, nil
+}
+
+// AuthenticateAPIKey returns the active key matching key and notes its use.
+func AuthenticateAPIKey(ctx context.Context, db *gorm.DB, key string, now time.Time) (model.APIKey, error) {
+	var record model.APIKey
+	if !strings.HasPrefix(key, apiKeyPrefix) {
+		return record, ErrAPIKeyNotFound
+	}
+	err := db.WithContext(ctx).Where("hash = ? AND revoked_at IS NULL", HashAPIKey(key)).First(&record).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return record, ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return record, fmt.Errorf("failed to look up API key: %w", err)
+	}
+
+	if record.LastUsedAt == nil || now.Sub(*record.LastUsedAt) >= lastUsedResolution {
+		err := db.WithContext(ctx).Model(&model.APIKey{}).Where("id = ?", record.ID).UpdateColumn("last_used_at", now).Error
+		if err != nil {
+			return record, fmt.Errorf("failed to record use of API key %d: %w", record.ID, err)
+		}
+		record.LastUsedAt = &now
+	}
+	return record, nil
+}
+
+// ListAPIKeys returns every key, revoked ones included, oldest first.
+func ListAPIKeys(ctx context.Context, db *gorm.DB) ([]model.APIKey, error) {
+	var keys []model.APIKey
+	if err := db.WithContext(ctx).Order("id").Find(&keys).Error; err != nil {
+		return nil, fmt.Errorf("failed to list API keys: %w", err)
+	}
+	return keys, nil
+}
+
+// RevokeAPIKey disables a key for good.
+func RevokeAPIKey(ctx context.Context, db *gorm.DB, id int64, now time.Time) error {
+	res := db.WithContext(ctx).Model(&model.APIKey{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		UpdateColumn("revoked_at", now)
+	if res.Error != nil {
+		return fmt.Errorf("failed to revoke API key %d: %w", id, res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// RecordAudit stores an audit log entry, truncating fields that are too long.
+func RecordAudit(ctx context.Context, db *gorm.DB, entry model.AuditLog) error {
+	entry.Route = truncate(entry.Route, 256)
+	entry.Path = truncate(entry.Path, 512)
+	entry.Body = truncate(entry.Body, maxAuditBodyLength)
+	if err := db.WithContext(ctx).Create(&entry).Error; err != nil {
+		return fmt.Errorf("failed to record audit log: %w", err)
+	}
+	return nil
+}
+
+// AuditQuery selects audit log entries, newest first.
+type AuditQuery struct {
+	APIKeyID int64 // 0 for all keys
+	BeforeID int64 // Entries older than this one, for paging; 0 for the newest
+	Limit    int
+}
+
+// ListAuditLogs returns the audit log entries selected by q.
+func ListAuditLogs(ctx context.Context, db *gorm.DB, q AuditQuery) ([]model.AuditLog, error) {
+	query := db.WithContext(ctx).Order("id DESC").Limit(q.Limit)
+	if q.APIKeyID != 0 {
+		query = query.Where("api_key_id = ?", q.APIKeyID)
+	}
+	if q.BeforeID != 0 {
+		query = query.Where("id < ?", q.BeforeID)
+	}
+	var entries []model.AuditLog
+	if err := query.Find(&entries).Error; err != nil {
+		return nil, fmt.Errorf("failed to list audit logs: %w", err)
+	}
+	return entries, nil
+}
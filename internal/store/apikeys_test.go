@@ -0,0 +1,82 @@
+package store
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"laundry-status-backend/internal/model"
+)
+
+func TestAPIKeys(t *testing.T) {
+	ctx := context.Background()
+	testDB := newSQLiteDB(t)
+	require.NoError(t, testDB.AutoMigrate(&model.APIKey{}))
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	_, _, err := CreateAPIKey(ctx, testDB, "ops", "root", now)
+	assert.ErrorIs(t, err, ErrInvalidRole)
+
+	key, record, err := CreateAPIKey(ctx, testDB, "ops", model.RoleOperator, now)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(key, record.Prefix))
+	assert.NotContains(t, record.Hash, key[len(apiKeyPrefix):], "only the hash is stored")
+
+	authenticated, err := AuthenticateAPIKey(ctx, testDB, key, now.Add(time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, record.ID, authenticated.ID)
+	assert.Equal(t, model.RoleOperator, authenticated.Role)
+	require.NotNil(t, authenticated.LastUsedAt)
+
+	_, err = AuthenticateAPIKey(ctx, testDB, key+"x", now)
+	assert.ErrorIs(t, err, ErrAPIKeyNotFound)
+	_, err = AuthenticateAPIKey(ctx, testDB, "Bearer", now)
+	assert.ErrorIs(t, err, ErrAPIKeyNotFound)
+
+	require.NoError(t, RevokeAPIKey(ctx, testDB, record.ID, now.Add(time.Minute)))
+	assert.ErrorIs(t, RevokeAPIKey(ctx, testDB, record.ID, now.Add(time.Minute)), ErrAPIKeyNotFound)
+	_, err = AuthenticateAPIKey(ctx, testDB, key, now.Add(2*time.Minute))
+	assert.ErrorIs(t, err, ErrAPIKeyNotFound, "revoked keys are rejected")
+
+	keys, err := ListAPIKeys(ctx, testDB)
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	assert.NotNil(t, keys[0].RevokedAt)
+}
+
+func TestRoleAllows(t *testing.T) {
+	assert.True(t, RoleAllows(model.RoleAdmin, model.RoleOperator))
+	assert.True(t, RoleAllows(model.RoleOperator, model.RoleOperator))
+	assert.False(t, RoleAllows(model.RoleViewer, model.RoleOperator))
+	assert.False(t, RoleAllows("", model.RoleViewer))
+}
+
+func TestAuditLogs(t *testing.T) {
+	ctx := context.Background()
+	testDB := newSQLiteDB(t)
+	require.NoError(t, testDB.AutoMigrate(&model.AuditLog{}))
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	for i, keyID := range []int64{1, 2, 1} {
+		require.NoError(t, RecordAudit(ctx, testDB, model.AuditLog{
+			At: now.Add(time.Duration(i) * time.Minute), APIKeyID: keyID, KeyName: "k", Role: model.RoleOperator,
+			Method: "PUT", Route: "/r", Path: "/r", Body: strings.Repeat("界", 1000), Status: 200,
+		}))
+	}
+
+	entries, err := ListAuditLogs(ctx, testDB, AuditQuery{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	assert.Greater(t, entries[0].ID, entries[1].ID, "newest first")
+	assert.LessOrEqual(t, len(entries[0].Body), maxAuditBodyLength)
+	assert.True(t, strings.HasSuffix(entries[0].Body, "界"), "bodies are cut at a character boundary")
+
+	entries, err = ListAuditLogs(ctx, testDB, AuditQuery{APIKeyID: 1, BeforeID: entries[0].ID, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, int64(1), entries[0].APIKeyID)
+}
@@ -0,0 +1,76 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"laundry-status-backend/internal/model"
+)
+
+// FloorAvailability counts the visible machines of one floor of a dorm by
+// state type. Busy machines in states of unknown type count in neither
+// Occupied nor Faulty.
+type FloorAvailability struct {
+	DormID       int64
+	Floor        int
+	Available    int        // Idle machines, which have no open record
+	Occupied     int        // Machines whose status is a configured occupied value
+	Faulty       int        // Machines whose status is a configured faulty value
+	NextFinishAt *time.Time // Soonest predicted finish of an occupied machine, nil if none has one
+}
+
+type floorAvailabilityRow struct {
+	DormID     int64
+	Floor      int
+	Available  int
+	Occupied   int
+	Faulty     int
+	NextFinish *int64 // Unix seconds
+}
+
+// finishUnixExpr returns the predicted finish of an open record in Unix
+// seconds. Date arithmetic differs between dialects, so both compute epochs.
+func finishUnixExpr(db *gorm.DB) string {
+	if db.Dialector.Name() == "sqlite" {
+		return "CAST(strftime('%s', occupancy_opens.observed_at) AS INTEGER) + occupancy_opens.time_remaining"
+	}
+	return "CAST(EXTRACT(EPOCH FROM occupancy_opens.observed_at) AS BIGINT) + occupancy_opens.time_remaining"
+}
+
+// CountAvailability counts the visible machines of every dorm and floor by
+// state type in one aggregate query. occupied and faulty are the status
+// codes configured for those state types.
+func CountAvailability(ctx context.Context, db *gorm.DB, occupied, faulty []int) ([]FloorAvailability, error) {
+	var rows []floorAvailabilityRow
+	err := db.WithContext(ctx).Model(&model.Machine{}).
+		Scopes(VisibleMachines).
+		Select("machines.dorm_id AS dorm_id, machines.floor AS floor, "+
+			"SUM(CASE WHEN occupancy_opens.machine_id IS NULL THEN 1 ELSE 0 END) AS available, "+
+			"SUM(CASE WHEN occupancy_opens.status IN @occupied THEN 1 ELSE 0 END) AS occupied, "+
+			"SUM(CASE WHEN occupancy_opens.status IN @faulty THEN 1 ELSE 0 END) AS faulty, "+
+			"MIN(CASE WHEN occupancy_opens.status IN @occupied AND occupancy_opens.time_remaining > 0 THEN "+finishUnixExpr(db)+" END) AS next_finish",
+			map[string]any{"occupied": occupied, "faulty": faulty}).
+		Joins("LEFT JOIN occupancy_opens ON occupancy_opens.machine_id = machines.id").
+		Group("machines.dorm_id, machines.floor").
+		Order("machines.dorm_id, machines.floor").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to count machine availability: %w", err)
+	}
+
+	floors := make([]FloorAvailability, len(rows))
+	for i, row := range rows {
+		floors[i] = FloorAvailability{
+			DormID: row.DormID, Floor: row.Floor,
+			Available: row.Available, Occupied: row.Occupied, Faulty: row.Faulty,
+		}
+		if row.NextFinish != nil {
+			finish := time.Unix(*row.NextFinish, 0).UTC()
+			floors[i].NextFinishAt = &finish
+		}
+	}
+	return floors, nil
+}
@@ -0,0 +1,64 @@
+package store
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"laundry-status-backend/internal/model"
+)
+
+func TestCountAvailability(t *testing.T) {
+	testDB := newSQLiteDB(t)
+	require.NoError(t, testDB.AutoMigrate(&model.OccupancyOpen{}))
+	observed := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	require.NoError(t, testDB.Create(&[]model.Dorm{{ID: 1, Name: "东3"}, {ID: 2, Name: "西1"}}).Error)
+	require.NoError(t, testDB.Create(&[]model.Machine{
+		{ID: 11, DormID: 1, DisplayName: "东3#1-1", Floor: 1},
+		{ID: 12, DormID: 1, DisplayName: "东3#1-2", Floor: 1},
+		{ID: 13, DormID: 1, DisplayName: "东3#1-3", Floor: 1},
+		{ID: 14, DormID: 1, DisplayName: "东3#1-4", Floor: 1},
+		{ID: 15, DormID: 1, DisplayName: "东3#2-1", Floor: 2},
+		{ID: 16, DormID: 1, DisplayName: "东3#2-2", Floor: 2},
+		{ID: 21, DormID: 2, DisplayName: "西1#1-1", Floor: 1},
+	}).Error)
+	require.NoError(t, testDB.Create(&model.MachineOverride{MachineID: 16, Hidden: true}).Error)
+	require.NoError(t, testDB.Create(&[]model.OccupancyOpen{
+		{MachineID: 12, ObservedAt: observed, Status: 2, Message: "使用中", TimeRemaining: 1800},
+		{MachineID: 13, ObservedAt: observed.Add(time.Minute), Status: 2, Message: "使用中", TimeRemaining: 600},
+		{MachineID: 14, ObservedAt: observed, Status: 3, Message: "故障"},
+		{MachineID: 15, ObservedAt: observed, Status: 9, Message: "未知"},
+		{MachineID: 16, ObservedAt: observed, Status: 2, Message: "使用中", TimeRemaining: 60},
+	}).Error)
+
+	floors, err := CountAvailability(context.Background(), testDB, []int{2}, []int{3})
+	require.NoError(t, err)
+
+	nextFinish := observed.Add(11 * time.Minute)
+	assert.Equal(t, []FloorAvailability{
+		{DormID: 1, Floor: 1, Available: 1, Occupied: 2, Faulty: 1, NextFinishAt: &nextFinish},
+		{DormID: 1, Floor: 2}, // Unknown state; the hidden machine is not counted
+		{DormID: 2, Floor: 1, Available: 1},
+	}, floors)
+}
+
+func TestCountAvailability_Postgres(t *testing.T) {
+	db, mock := newTestDB(t)
+	mock.ExpectQuery(regexp.QuoteMeta("CAST(EXTRACT(EPOCH FROM occupancy_opens.observed_at) AS BIGINT) + occupancy_opens.time_remaining END) AS next_finish")).
+		WillReturnRows(sqlmock.NewRows([]string{"dorm_id", "floor", "available", "occupied", "faulty", "next_finish"}).
+			AddRow(1, 3, 2, 1, 0, 1714565400))
+
+	floors, err := CountAvailability(context.Background(), db, []int{2}, []int{3})
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	require.Len(t, floors, 1)
+	require.NotNil(t, floors[0].NextFinishAt)
+	assert.Equal(t, time.Date(2024, 5, 1, 12, 10, 0, 0, time.UTC), *floors[0].NextFinishAt)
+}
@@ -0,0 +1,35 @@
+package store
+
+import (
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"laundry-status-backend/internal/model"
+)
+
+// MachineStateCount is the number of visible machines of a dorm that share a
+// status. Status is nil for idle machines, which have no open record.
+type MachineStateCount struct {
+	Dorm     string
+	Status   *int
+	Machines int
+}
+
+// CountMachineStates counts the visible machines of every dorm by their
+// current status.
+func CountMachineStates(ctx context.Context, db *gorm.DB) ([]MachineStateCount, error) {
+	var counts []MachineStateCount
+	err := db.WithContext(ctx).Model(&model.Machine{}).
+		Scopes(VisibleMachines).
+		Select("dorms.name AS dorm, occupancy_opens.status AS status, COUNT(*) AS machines").
+		Joins("JOIN dorms ON dorms.id = machines.dorm_id").
+		Joins("LEFT JOIN occupancy_opens ON occupancy_opens.machine_id = machines.id").
+		Group("dorms.name, occupancy_opens.status").
+		Scan(&counts).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to count machine states: %w", err)
+	}
+	return counts, nil
+}
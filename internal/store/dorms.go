@@ -0,0 +1,140 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"laundry-status-backend/internal/model"
+)
+
+var (
+	// ErrDormNotFound is returned when a dorm referenced by an admin operation does not exist.
+	ErrDormNotFound = errors.New("dorm not found")
+	// ErrDormNameTaken is returned when renaming a dorm to a name another dorm already uses.
+	ErrDormNameTaken = errors.New("dorm name already in use")
+	// ErrDormMergeSelf is returned when merging a dorm into itself.
+	ErrDormMergeSelf = errors.New("cannot merge a dorm into itself")
+	// ErrAliasTaken is returned when a name is already an alias or the name of
+	// another dorm. Only MergeDorms moves aliases between dorms.
+	ErrAliasTaken = errors.New("name is already an alias of another dorm")
+)
+
+// RenameDorm changes a dorm's display name. The old name stays as an alias so
+// upstream data using it keeps resolving to the same dorm.
+func RenameDorm(ctx context.Context, db *gorm.DB, dormID int64, name string) (model.Dorm, error) {
+	var dorm model.Dorm
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := findDorm(tx, dormID, &dorm); err != nil {
+			return err
+		}
+		if dorm.Name == name {
+			return nil
+		}
+
+		var taken int64
+		if err := tx.Model(&model.Dorm{}).Where("name = ? AND id <> ?", name, dormID).Count(&taken).Error; err != nil {
+			return err
+		}
+		if taken > 0 {
+			return ErrDormNameTaken
+		}
+
+		oldName := dorm.Name
+		if err := tx.Model(&dorm).Update("name", name).Error; err != nil {
+			return fmt.Errorf("failed to rename dorm %d: %w", dormID, err)
+		}
+		dorm.Name = name
+		return addDormAliases(tx, dormID, oldName, name)
+	})
+	return dorm, err
+}
+
+// MergeDorms moves every machine and alias of the source dorm to the target
+// dorm and deletes the source. Overrides pinning machines to the source are
+// re-pointed too, since every upsert applies them. Occupancy history is keyed
+// by machine, so it follows the machines without being rewritten.
+func MergeDorms(ctx context.Context, db *gorm.DB, sourceID, targetID int64) (model.Dorm, error) {
+	var target model.Dorm
+	if sourceID == targetID {
+		return target, ErrDormMergeSelf
+	}
+
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var source model.Dorm
+		if err := findDorm(tx, sourceID, &source); err != nil {
+			return err
+		}
+		if err := findDorm(tx, targetID, &target); err != nil {
+			return err
+		}
+
+		if err := tx.Model(&model.Machine{}).Where("dorm_id = ?", sourceID).
+			Update("dorm_id", targetID).Error; err != nil {
+			return fmt.Errorf("failed to move machines from dorm %d: %w", sourceID, err)
+		}
+		if err := tx.Model(&model.MachineOverride{}).Where("dorm_id = ?", sourceID).
+			Update("dorm_id", targetID).Error; err != nil {
+			return fmt.Errorf("failed to move overrides from dorm %d: %w", sourceID, err)
+		}
+		if err := tx.Model(&model.DormAlias{}).Where("dorm_id = ?", sourceID).
+			Update("dorm_id", targetID).Error; err != nil {
+			return fmt.Errorf("failed to move aliases from dorm %d: %w", sourceID, err)
+		}
+		if err := tx.Delete(&model.Dorm{}, sourceID).Error; err != nil {
+			return fmt.Errorf("failed to delete merged dorm %d: %w", sourceID, err)
+		}
+		return addDormAliases(tx, targetID, source.Name)
+	})
+	return target, err
+}
+
+// SetDormAlias adds an alias to the given dorm. Aliases and names of other
+// dorms are refused with ErrAliasTaken; use MergeDorms to combine dorms.
+func SetDormAlias(ctx context.Context, db *gorm.DB, dormID int64, alias string) error {
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var dorm model.Dorm
+		if err := findDorm(tx, dormID, &dorm); err != nil {
+			return err
+		}
+		return addDormAliases(tx, dormID, alias)
+	})
+}
+
+func findDorm(tx *gorm.DB, dormID int64, dorm *model.Dorm) error {
+	err := tx.First(dorm, dormID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrDormNotFound
+	}
+	return err
+}
+
+// addDormAliases makes names aliases of the dorm. A name that is an alias or
+// the name of another dorm would move that dorm's upstream machines here on
+// the next scrape, so it fails with ErrAliasTaken instead.
+func addDormAliases(tx *gorm.DB, dormID int64, names ...string) error {
+	var taken int64
+	if err := tx.Model(&model.DormAlias{}).Where("alias IN ? AND dorm_id <> ?", names, dormID).Count(&taken).Error; err != nil {
+		return fmt.Errorf("failed to look up aliases for dorm %d: %w", dormID, err)
+	}
+	if taken == 0 {
+		if err := tx.Model(&model.Dorm{}).Where("name IN ? AND id <> ?", names, dormID).Count(&taken).Error; err != nil {
+			return fmt.Errorf("failed to look up dorm names for dorm %d: %w", dormID, err)
+		}
+	}
+	if taken > 0 {
+		return ErrAliasTaken
+	}
+
+	aliases := make([]model.DormAlias, len(names))
+	for i, name := range names {
+		aliases[i] = model.DormAlias{Alias: name, DormID: dormID}
+	}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&aliases).Error; err != nil {
+		return fmt.Errorf("failed to save aliases for dorm %d: %w", dormID, err)
+	}
+	return nil
+}
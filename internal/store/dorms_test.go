@@ -0,0 +1,165 @@
+package store
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"laundry-status-backend/internal/model"
+	"laundry-status-backend/internal/testdb"
+)
+
+// newSQLiteDB creates an isolated in-memory database with the dorm schema.
+func newSQLiteDB(t *testing.T) *gorm.DB {
+	return testdb.New(t, &model.Dorm{}, &model.DormAlias{}, &model.Machine{}, &model.QuarantinedMachine{}, &model.MachineOverride{}, &model.OccupancyHistory{}, &model.ScraperState{})
+}
+
+func dormOf(t *testing.T, db *gorm.DB, machineID int64) model.Dorm {
+	var machine model.Machine
+	require.NoError(t, db.Preload("Dorm").First(&machine, machineID).Error)
+	return machine.Dorm
+}
+
+func TestUpsertDormsAndMachines_ResolvesAliases(t *testing.T) {
+	ctx := context.Background()
+	testDB := newSQLiteDB(t)
+	s := NewGormStore(testDB, nil)
+
+	require.NoError(t, s.UpsertDormsAndMachines(ctx, []ApiItem{
+		{ID: 1, Name: "东3#2-1", FloorCode: "2"},
+		{ID: 2, Name: "东3栋2-1", FloorCode: "2"},
+	}))
+	first, second := dormOf(t, testDB, 1), dormOf(t, testDB, 2)
+	assert.Equal(t, "东3", first.Name)
+	assert.NotEqual(t, first.ID, second.ID, "distinct spellings are distinct dorms until merged")
+
+	// Merging records the spelling as an alias, so the next scrape keeps the machines together.
+	_, err := MergeDorms(ctx, testDB, second.ID, first.ID)
+	require.NoError(t, err)
+	require.NoError(t, s.UpsertDormsAndMachines(ctx, []ApiItem{
+		{ID: 1, Name: "东3#2-1", FloorCode: "2"},
+		{ID: 2, Name: "东3栋2-1", FloorCode: "2"},
+		{ID: 3, Name: "东3栋3-1", FloorCode: "3"},
+	}))
+	assert.Equal(t, first.ID, dormOf(t, testDB, 2).ID)
+	assert.Equal(t, first.ID, dormOf(t, testDB, 3).ID)
+
+	var dormCount int64
+	testDB.Model(&model.Dorm{}).Count(&dormCount)
+	assert.Equal(t, int64(1), dormCount)
+}
+
+func TestRenameDorm(t *testing.T) {
+	ctx := context.Background()
+	testDB := newSQLiteDB(t)
+	s := NewGormStore(testDB, nil)
+
+	require.NoError(t, s.UpsertDormsAndMachines(ctx, []ApiItem{
+		{ID: 1, Name: "北村E3-1", FloorCode: "3"},
+		{ID: 2, Name: "北村F3-1", FloorCode: "3"},
+	}))
+	dorm := dormOf(t, testDB, 1)
+
+	renamed, err := RenameDorm(ctx, testDB, dorm.ID, "北村 E 栋")
+	require.NoError(t, err)
+	assert.Equal(t, "北村 E 栋", renamed.Name)
+
+	_, err = RenameDorm(ctx, testDB, dorm.ID, "北村F")
+	assert.ErrorIs(t, err, ErrDormNameTaken)
+	_, err = RenameDorm(ctx, testDB, 999, "whatever")
+	assert.ErrorIs(t, err, ErrDormNotFound)
+
+	// Upstream still uses the old name; it resolves through the alias.
+	require.NoError(t, s.UpsertDormsAndMachines(ctx, []ApiItem{
+		{ID: 1, Name: "北村E3-1", FloorCode: "3"},
+		{ID: 4, Name: "北村E4-1", FloorCode: "4"},
+	}))
+	assert.Equal(t, "北村 E 栋", dormOf(t, testDB, 4).Name)
+}
+
+func TestRenameDorm_KeepsOtherDormsAliases(t *testing.T) {
+	ctx := context.Background()
+	testDB := newSQLiteDB(t)
+	s := NewGormStore(testDB, nil)
+
+	require.NoError(t, s.UpsertDormsAndMachines(ctx, []ApiItem{
+		{ID: 1, Name: "北村E3-1", FloorCode: "3"},
+		{ID: 2, Name: "北村F3-1", FloorCode: "3"},
+	}))
+	dormE, dormF := dormOf(t, testDB, 1), dormOf(t, testDB, 2)
+	_, err := RenameDorm(ctx, testDB, dormF.ID, "北村 F 栋")
+	require.NoError(t, err)
+
+	// "北村F" is no dorm's name any more, but still F's alias
+	_, err = RenameDorm(ctx, testDB, dormE.ID, dormF.Name)
+	assert.ErrorIs(t, err, ErrAliasTaken)
+	assert.ErrorIs(t, SetDormAlias(ctx, testDB, dormE.ID, dormF.Name), ErrAliasTaken)
+	assert.ErrorIs(t, SetDormAlias(ctx, testDB, dormE.ID, "北村 F 栋"), ErrAliasTaken, "another dorm's current name")
+	assert.Equal(t, dormE.Name, dormOf(t, testDB, 1).Name, "the failed rename is rolled back")
+
+	require.NoError(t, s.UpsertDormsAndMachines(ctx, []ApiItem{{ID: 3, Name: "北村F4-1", FloorCode: "4"}}))
+	assert.Equal(t, dormF.ID, dormOf(t, testDB, 3).ID, "upstream machines of F stay in F")
+}
+
+func TestMergeDorms_MovesMachinesAndAliases(t *testing.T) {
+	ctx := context.Background()
+	testDB := newSQLiteDB(t)
+
+	source := model.Dorm{Name: "A栋"}
+	target := model.Dorm{Name: "A东"}
+	require.NoError(t, testDB.Create(&[]*model.Dorm{&source, &target}).Error)
+	require.NoError(t, testDB.Create(&[]model.DormAlias{
+		{Alias: "A栋", DormID: source.ID},
+		{Alias: "A座", DormID: source.ID},
+	}).Error)
+	require.NoError(t, testDB.Create(&model.Machine{ID: 7, DormID: source.ID, DisplayName: "A栋1-1"}).Error)
+
+	_, err := MergeDorms(ctx, testDB, source.ID, source.ID)
+	assert.ErrorIs(t, err, ErrDormMergeSelf)
+
+	merged, err := MergeDorms(ctx, testDB, source.ID, target.ID)
+	require.NoError(t, err)
+	assert.Equal(t, target.ID, merged.ID)
+
+	assert.Equal(t, target.ID, dormOf(t, testDB, 7).ID)
+
+	var aliases []model.DormAlias
+	require.NoError(t, testDB.Order("alias").Find(&aliases).Error)
+	for _, a := range aliases {
+		assert.Equal(t, target.ID, a.DormID, "alias %q should point at the target", a.Alias)
+	}
+
+	err = testDB.First(&model.Dorm{}, source.ID).Error
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func TestMergeDorms_MovesOverrides(t *testing.T) {
+	ctx := context.Background()
+	testDB := newSQLiteDB(t)
+	require.NoError(t, testDB.Exec("PRAGMA foreign_keys = ON").Error)
+	s := NewGormStore(testDB, nil)
+
+	require.NoError(t, s.UpsertDormsAndMachines(ctx, []ApiItem{
+		{ID: 1, Name: "北村E3-1", FloorCode: "3"},
+		{ID: 2, Name: "北村F3-1", FloorCode: "3"},
+		{ID: 3, Name: "北村G3-1", FloorCode: "3"},
+	}))
+	dormE, dormF := dormOf(t, testDB, 1), dormOf(t, testDB, 2)
+	// Machine 3 is pinned to F by an override
+	_, err := SaveMachineOverride(ctx, testDB, model.MachineOverride{MachineID: 3, DormID: &dormF.ID})
+	require.NoError(t, err)
+
+	_, err = MergeDorms(ctx, testDB, dormF.ID, dormE.ID)
+	require.NoError(t, err)
+
+	require.NoError(t, s.UpsertDormsAndMachines(ctx, []ApiItem{
+		{ID: 1, Name: "北村E3-1", FloorCode: "3"},
+		{ID: 2, Name: "北村F3-1", FloorCode: "3"},
+		{ID: 3, Name: "北村G3-1", FloorCode: "3"},
+	}))
+	assert.Equal(t, dormE.ID, dormOf(t, testDB, 2).ID)
+	assert.Equal(t, dormE.ID, dormOf(t, testDB, 3).ID, "the override follows the merge")
+}
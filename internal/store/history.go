@@ -0,0 +1,53 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"laundry-status-backend/internal/model"
+)
+
+// HistoryQuery selects the state segments of one machine that overlap [From, To).
+// Before is the pagination cursor: only segments observed to end before it are
+// returned. Zero values leave the corresponding bound open.
+type HistoryQuery struct {
+	MachineID int64
+	From      time.Time
+	To        time.Time
+	Before    time.Time
+	Limit     int
+}
+
+// MachineHistory returns archived segments newest first. The observed_at bounds
+// and ordering are served by the (machine_id, observed_at DESC) index, so each
+// page is a bounded index range scan. Segments of one machine never overlap,
+// so at most one segment overlapping [From, To) is observed to end after To:
+// the first one to do so, which one index probe finds. Its observed_at bounds
+// the scan from above and period_start is only a residual filter.
+func MachineHistory(ctx context.Context, db *gorm.DB, q HistoryQuery) ([]model.OccupancyHistory, error) {
+	db = db.WithContext(ctx)
+	query := db.Where("machine_id = ?", q.MachineID)
+	if !q.From.IsZero() {
+		query = query.Where("observed_at > ?", q.From)
+	}
+	if !q.To.IsZero() {
+		straddling := db.Model(&model.OccupancyHistory{}).Select("MIN(observed_at)").
+			Where("machine_id = ? AND observed_at > ?", q.MachineID, q.To)
+		query = query.Where("observed_at <= COALESCE((?), ?) AND period_start < ?", straddling, q.To, q.To)
+	}
+	if !q.Before.IsZero() {
+		query = query.Where("observed_at < ?", q.Before)
+	}
+	if q.Limit > 0 {
+		query = query.Limit(q.Limit)
+	}
+
+	var segments []model.OccupancyHistory
+	if err := query.Order("observed_at DESC").Find(&segments).Error; err != nil {
+		return nil, fmt.Errorf("failed to query history for machine %d: %w", q.MachineID, err)
+	}
+	return segments, nil
+}
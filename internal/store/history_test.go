@@ -0,0 +1,44 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"laundry-status-backend/internal/model"
+)
+
+func TestMachineHistory_To(t *testing.T) {
+	testDB := newSQLiteDB(t)
+	require.NoError(t, testDB.AutoMigrate(&model.OccupancyHistory{}))
+	base := time.Date(2025, 3, 10, 8, 0, 0, 0, time.UTC)
+	require.NoError(t, testDB.Create(&[]model.OccupancyHistory{
+		{MachineID: 11, Status: 2, PeriodStart: base, PeriodEnd: base.Add(time.Hour), ObservedAt: base.Add(time.Hour)},
+		{MachineID: 11, Status: 3, PeriodStart: base.Add(2 * time.Hour), PeriodEnd: base.Add(5 * time.Hour), ObservedAt: base.Add(5 * time.Hour)},
+		{MachineID: 11, Status: 2, PeriodStart: base.Add(6 * time.Hour), PeriodEnd: base.Add(7 * time.Hour), ObservedAt: base.Add(7 * time.Hour)},
+		{MachineID: 12, Status: 2, PeriodStart: base, PeriodEnd: base.Add(9 * time.Hour), ObservedAt: base.Add(9 * time.Hour)},
+	}).Error)
+
+	starts := func(q HistoryQuery) []time.Time {
+		segments, err := MachineHistory(context.Background(), testDB, q)
+		require.NoError(t, err)
+		var starts []time.Time
+		for _, s := range segments {
+			starts = append(starts, s.PeriodStart.UTC())
+		}
+		return starts
+	}
+
+	// The segment straddling To is kept, later ones are not.
+	assert.Equal(t, []time.Time{base.Add(2 * time.Hour), base},
+		starts(HistoryQuery{MachineID: 11, To: base.Add(3 * time.Hour)}))
+	// A segment observed to end after To but starting at it does not overlap.
+	assert.Equal(t, []time.Time{base},
+		starts(HistoryQuery{MachineID: 11, To: base.Add(2 * time.Hour)}))
+	// Nothing ends after To.
+	assert.Equal(t, []time.Time{base.Add(6 * time.Hour), base.Add(2 * time.Hour), base},
+		starts(HistoryQuery{MachineID: 11, To: base.Add(8 * time.Hour)}))
+}
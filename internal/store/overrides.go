@@ -0,0 +1,87 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"laundry-status-backend/internal/model"
+)
+
+var (
+	// ErrMachineNotFound is returned when an admin operation references an unknown machine.
+	ErrMachineNotFound = errors.New("machine not found")
+	// ErrOverrideNotFound is returned when deleting an override that does not exist.
+	ErrOverrideNotFound = errors.New("machine override not found")
+)
+
+// VisibleMachines is a query scope that drops machines hidden by an override.
+func VisibleMachines(db *gorm.DB) *gorm.DB {
+	return db.Where("machines.id NOT IN (?)",
+		db.Session(&gorm.Session{NewDB: true}).Model(&model.MachineOverride{}).
+			Select("machine_id").Where("hidden = ?", true))
+}
+
+// SaveMachineOverride replaces the override of a machine and applies it to
+// the machines row right away. Fields cleared from an override fall back to
+// the upstream value on the next scrape.
+func SaveMachineOverride(ctx context.Context, db *gorm.DB, override model.MachineOverride) (model.Machine, error) {
+	var machine model.Machine
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&machine, override.MachineID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrMachineNotFound
+			}
+			return err
+		}
+		if override.DormID != nil {
+			var dorm model.Dorm
+			if err := findDorm(tx, *override.DormID, &dorm); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(&override).Error; err != nil {
+			return fmt.Errorf("failed to save override for machine %d: %w", override.MachineID, err)
+		}
+
+		applyOverride(&machine, &override)
+		return tx.Model(&machine).Select("dorm_id", "display_name", "floor", "seq").Updates(&machine).Error
+	})
+	return machine, err
+}
+
+// DeleteMachineOverride removes a machine's override. The upstream values
+// are restored by the next scrape.
+func DeleteMachineOverride(ctx context.Context, db *gorm.DB, machineID int64) error {
+	res := db.WithContext(ctx).Delete(&model.MachineOverride{}, machineID)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrOverrideNotFound
+	}
+	return nil
+}
+
+// applyOverride merges the non-nil override fields into m.
+func applyOverride(m *model.Machine, o *model.MachineOverride) {
+	if o == nil {
+		return
+	}
+	if o.DisplayName != nil {
+		m.DisplayName = *o.DisplayName
+	}
+	if o.DormID != nil {
+		m.DormID = *o.DormID
+	}
+	if o.Floor != nil {
+		m.Floor = *o.Floor
+	}
+	if o.Seq != nil {
+		m.Seq = *o.Seq
+	}
+}
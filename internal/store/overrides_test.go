@@ -0,0 +1,58 @@
+package store
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"laundry-status-backend/internal/model"
+)
+
+func TestMachineOverrides_SurviveUpserts(t *testing.T) {
+	ctx := context.Background()
+	testDB := newSQLiteDB(t)
+	s := NewGormStore(testDB, nil)
+
+	items := []ApiItem{
+		{ID: 1, Name: "中楼5-12", FloorCode: "5"},
+		{ID: 2, Name: "中楼5-13", FloorCode: "5"},
+	}
+	require.NoError(t, s.UpsertDormsAndMachines(ctx, items))
+
+	name, floor := "中楼 6 层 1 号", 6
+	machine, err := SaveMachineOverride(ctx, testDB, model.MachineOverride{
+		MachineID: 1, DisplayName: &name, Floor: &floor, Notes: "铭牌贴错楼层",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, name, machine.DisplayName)
+
+	_, err = SaveMachineOverride(ctx, testDB, model.MachineOverride{MachineID: 2, Hidden: true})
+	require.NoError(t, err)
+	_, err = SaveMachineOverride(ctx, testDB, model.MachineOverride{MachineID: 404})
+	assert.ErrorIs(t, err, ErrMachineNotFound)
+
+	// Upstream renames the machine; the manual corrections stay in place.
+	items[0].Name = "中楼5-120"
+	require.NoError(t, s.UpsertDormsAndMachines(ctx, items))
+
+	var stored model.Machine
+	require.NoError(t, testDB.First(&stored, 1).Error)
+	assert.Equal(t, name, stored.DisplayName)
+	assert.Equal(t, 6, stored.Floor)
+	assert.Equal(t, 120, stored.Seq, "fields without an override follow upstream")
+
+	var visible []model.Machine
+	require.NoError(t, testDB.Scopes(VisibleMachines).Find(&visible).Error)
+	require.Len(t, visible, 1)
+	assert.Equal(t, int64(1), visible[0].ID)
+
+	// Removing the override restores upstream values on the next scrape.
+	require.NoError(t, DeleteMachineOverride(ctx, testDB, 1))
+	assert.ErrorIs(t, DeleteMachineOverride(ctx, testDB, 1), ErrOverrideNotFound)
+	require.NoError(t, s.UpsertDormsAndMachines(ctx, items))
+	require.NoError(t, testDB.First(&stored, 1).Error)
+	assert.Equal(t, "中楼5-120", stored.DisplayName)
+	assert.Equal(t, 5, stored.Floor)
+}
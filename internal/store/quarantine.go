@@ -0,0 +1,59 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"laundry-status-backend/internal/model"
+)
+
+// ErrQuarantineNotFound is returned when promoting a device that is not quarantined.
+var ErrQuarantineNotFound = errors.New("quarantined machine not found")
+
+// MachinePlacement is the manually assigned location of a promoted machine.
+type MachinePlacement struct {
+	DormID int64
+	Floor  int
+	Seq    int
+}
+
+// PromoteQuarantinedMachine turns a quarantined device into a real machine.
+// Later upserts keep the assigned placement even though the name still does
+// not parse, because the machine now exists.
+func PromoteQuarantinedMachine(ctx context.Context, db *gorm.DB, machineID int64, placement MachinePlacement) (model.Machine, error) {
+	var machine model.Machine
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var q model.QuarantinedMachine
+		if err := tx.First(&q, machineID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrQuarantineNotFound
+			}
+			return err
+		}
+
+		var dorm model.Dorm
+		if err := findDorm(tx, placement.DormID, &dorm); err != nil {
+			return err
+		}
+
+		machine = model.Machine{
+			ID:          q.ID,
+			DormID:      dorm.ID,
+			DisplayName: q.RawName,
+			IMEI:        q.IMEI,
+			DeviceID:    q.DeviceID,
+			FloorCode:   q.FloorCode,
+			Floor:       placement.Floor,
+			Seq:         placement.Seq,
+			Kind:        model.MachineKindWasher, // Reclassified by the kind rules on the next scrape
+		}
+		if err := batchUpsertMachines(tx, []model.Machine{machine}); err != nil {
+			return fmt.Errorf("failed to create machine %d: %w", machineID, err)
+		}
+		return tx.Delete(&q).Error
+	})
+	return machine, err
+}
@@ -0,0 +1,142 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"laundry-status-backend/config"
+	"laundry-status-backend/internal/model"
+	"laundry-status-backend/internal/parse"
+)
+
+func TestUpsertDormsAndMachines_Quarantine(t *testing.T) {
+	ctx := context.Background()
+	testDB := newSQLiteDB(t)
+	s := NewGormStore(testDB, nil)
+
+	items := []ApiItem{
+		{ID: 1, Name: "中楼5-12", FloorCode: "5"},
+		{ID: 2, Name: "洗衣房公共机", IMEI: "imei-2"},
+		{ID: 3, Name: "鞋机", IMEI: "imei-3"},
+	}
+	require.NoError(t, s.UpsertDormsAndMachines(ctx, items))
+
+	var quarantined []model.QuarantinedMachine
+	require.NoError(t, testDB.Order("id").Find(&quarantined).Error)
+	require.Len(t, quarantined, 2)
+	assert.Equal(t, "洗衣房公共机", quarantined[0].RawName)
+	assert.Contains(t, quarantined[0].Error, "unable to parse floor")
+	assert.ErrorIs(t, testDB.First(&model.Machine{}, 2).Error, gorm.ErrRecordNotFound)
+
+	// Promote machine 2 into the existing dorm by hand.
+	dorm := dormOf(t, testDB, 1)
+	promoted, err := PromoteQuarantinedMachine(ctx, testDB, 2, MachinePlacement{DormID: dorm.ID, Floor: 1, Seq: 1})
+	require.NoError(t, err)
+	assert.Equal(t, "洗衣房公共机", promoted.DisplayName)
+
+	_, err = PromoteQuarantinedMachine(ctx, testDB, 2, MachinePlacement{DormID: dorm.ID, Floor: 1})
+	assert.ErrorIs(t, err, ErrQuarantineNotFound)
+	_, err = PromoteQuarantinedMachine(ctx, testDB, 3, MachinePlacement{DormID: 999, Floor: 1})
+	assert.ErrorIs(t, err, ErrDormNotFound)
+
+	// The promoted machine keeps its placement although its name still fails to parse.
+	items[1].IMEI = "imei-2b"
+	require.NoError(t, s.UpsertDormsAndMachines(ctx, items))
+	var machine model.Machine
+	require.NoError(t, testDB.First(&machine, 2).Error)
+	assert.Equal(t, dorm.ID, machine.DormID)
+	assert.Equal(t, 1, machine.Floor)
+	assert.Equal(t, "imei-2b", machine.IMEI)
+
+	var count int64
+	testDB.Model(&model.QuarantinedMachine{}).Count(&count)
+	assert.Equal(t, int64(1), count)
+
+	// Once a rule covers the remaining device it leaves quarantine on the next scrape.
+	parser, err := parse.NewParser(config.ParserConfig{
+		Rules: []config.ParseRule{{Pattern: `^鞋机$`, Dorm: "中楼", Name: "shoes"}},
+	})
+	require.NoError(t, err)
+	items[2].FloorCode = "1"
+	require.NoError(t, NewGormStore(testDB, parser).UpsertDormsAndMachines(ctx, items))
+
+	testDB.Model(&model.QuarantinedMachine{}).Count(&count)
+	assert.Equal(t, int64(0), count)
+	assert.Equal(t, dorm.ID, dormOf(t, testDB, 3).ID)
+}
+
+func TestUpdateOccupancy_IgnoresQuarantine(t *testing.T) {
+	ctx := context.Background()
+	testDB := newSQLiteDB(t)
+	require.NoError(t, testDB.AutoMigrate(&model.OccupancyOpen{}))
+	s := NewGormStore(testDB, nil)
+	busy := func(int) MachineStateType { return StateTypeOccupied }
+	now := time.Now().UTC()
+
+	// Device 3 vanished from the feed long ago and left rows from before
+	// quarantined devices were excluded
+	require.NoError(t, testDB.Create(&model.QuarantinedMachine{
+		ID: 3, RawName: "鞋机", Error: "unable to parse", FirstSeenAt: now.AddDate(0, -3, 0), LastSeenAt: now.AddDate(0, -2, 0),
+	}).Error)
+	require.NoError(t, testDB.Create(&model.OccupancyOpen{MachineID: 3, Status: 2, ObservedAt: now.AddDate(0, -2, 0)}).Error)
+	require.NoError(t, testDB.Create(&model.OccupancyHistory{MachineID: 3, Status: 2, ObservedAt: now.AddDate(0, -2, 0)}).Error)
+
+	items := []ApiItem{
+		{ID: 1, Name: "中楼5-12", FloorCode: "5", State: 2},
+		{ID: 2, Name: "洗衣房公共机", State: 2},
+	}
+	require.NoError(t, s.UpsertDormsAndMachines(ctx, items))
+	// A legacy open record of the quarantined device 2
+	require.NoError(t, testDB.Create(&model.OccupancyOpen{MachineID: 2, Status: 2, ObservedAt: now.Add(-time.Hour)}).Error)
+
+	update, err := s.UpdateOccupancy(ctx, now, items, busy)
+	require.NoError(t, err)
+	require.Len(t, update.Changes, 1)
+	assert.Equal(t, int64(1), update.Changes[0].MachineID)
+
+	var opens []model.OccupancyOpen
+	require.NoError(t, testDB.Find(&opens).Error)
+	require.Len(t, opens, 1, "quarantined devices are not tracked")
+	assert.Equal(t, int64(1), opens[0].MachineID)
+
+	var histories, quarantined int64
+	testDB.Model(&model.OccupancyHistory{}).Count(&histories)
+	assert.Zero(t, histories, "dropped records are not archived, and stale devices lose theirs")
+	testDB.Model(&model.QuarantinedMachine{}).Count(&quarantined)
+	assert.Equal(t, int64(1), quarantined, "only the device still in the feed stays quarantined")
+}
+
+func TestUpsertDormsAndMachines_ClassifiesKind(t *testing.T) {
+	ctx := context.Background()
+	testDB := newSQLiteDB(t)
+
+	parser, err := parse.NewParser(config.ParserConfig{
+		KindRules: []config.KindRule{{Kind: model.MachineKindDryer, NamePattern: `烘干`, DeviceIDs: []int64{9}}},
+	})
+	require.NoError(t, err)
+	s := NewGormStore(testDB, parser)
+
+	items := []ApiItem{
+		{ID: 1, Name: "中楼5-12", FloorCode: "5"},
+		{ID: 2, Name: "中楼5-13", FloorCode: "5", DeviceID: 9},
+	}
+	require.NoError(t, s.UpsertDormsAndMachines(ctx, items))
+
+	var machines []model.Machine
+	require.NoError(t, testDB.Order("id").Find(&machines).Error)
+	require.Len(t, machines, 2)
+	assert.Equal(t, model.MachineKindWasher, machines[0].Kind)
+	assert.Equal(t, model.MachineKindDryer, machines[1].Kind)
+
+	// A rename that matches a kind rule reclassifies the machine.
+	items[0].Name = "中楼5-12烘干"
+	require.NoError(t, s.UpsertDormsAndMachines(ctx, items))
+	var machine model.Machine
+	require.NoError(t, testDB.First(&machine, 1).Error)
+	assert.Equal(t, model.MachineKindDryer, machine.Kind)
+}
@@ -0,0 +1,81 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"laundry-status-backend/internal/model"
+)
+
+// maxScrapeErrorLength matches the size of scraper_states.last_error.
+const maxScrapeErrorLength = 1024
+
+// RecordScrape stores the outcome of a scrape cycle. Machines in seen are
+// marked as confirmed at the cycle time; scrapeErr is nil for a cycle that
+// fetched and stored the whole feed.
+func (s *gormStore) RecordScrape(ctx context.Context, at time.Time, seen []int64, scrapeErr error) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for start := 0; start < len(seen); start += writeBatchSize {
+			err := tx.Model(&model.Machine{}).
+				Where("id IN ?", seen[start:min(start+writeBatchSize, len(seen))]).
+				UpdateColumn("last_confirmed_at", at).Error
+			if err != nil {
+				return fmt.Errorf("failed to confirm %d machines: %w", len(seen), err)
+			}
+		}
+
+		state := model.ScraperState{ID: model.ScraperStateID, LastAttemptAt: at, UpdatedAt: at}
+		assignments := clause.Assignments(map[string]any{"last_attempt_at": at, "updated_at": at})
+		if scrapeErr == nil {
+			state.LastSuccessAt = &at
+			assignments = append(assignments, clause.Assignments(map[string]any{
+				"last_success_at": at, "consecutive_failures": 0, "last_error": "",
+			})...)
+		} else {
+			state.ConsecutiveFailures = 1
+			state.LastError = truncate(scrapeErr.Error(), maxScrapeErrorLength)
+			assignments = append(assignments, clause.Assignments(map[string]any{
+				"consecutive_failures": gorm.Expr("scraper_states.consecutive_failures + 1"),
+				"last_error":           state.LastError,
+			})...)
+		}
+		err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "id"}},
+			DoUpdates: assignments,
+		}).Create(&state).Error
+		if err != nil {
+			return fmt.Errorf("failed to record scrape state: %w", err)
+		}
+		return nil
+	})
+}
+
+// LoadScraperState returns the recorded scrape outcome, or the zero state if
+// no cycle has been recorded yet.
+func LoadScraperState(ctx context.Context, db *gorm.DB) (model.ScraperState, error) {
+	var state model.ScraperState
+	err := db.WithContext(ctx).First(&state, model.ScraperStateID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return model.ScraperState{}, nil
+	}
+	if err != nil {
+		return model.ScraperState{}, fmt.Errorf("failed to load scraper state: %w", err)
+	}
+	return state, nil
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	// 避免截断到多字节字符中间
+	for n > 0 && (s[n]&0xC0) == 0x80 {
+		n--
+	}
+	return s[:n]
+}
@@ -0,0 +1,52 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"laundry-status-backend/internal/model"
+)
+
+func TestRecordScrape(t *testing.T) {
+	ctx := context.Background()
+	testDB := newSQLiteDB(t)
+	s := NewGormStore(testDB, nil)
+	t0 := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	state, err := LoadScraperState(ctx, testDB)
+	require.NoError(t, err)
+	assert.Nil(t, state.LastSuccessAt, "nothing recorded yet")
+
+	require.NoError(t, testDB.Create(&[]model.Dorm{{ID: 1, Name: "东3"}}).Error)
+	require.NoError(t, testDB.Create(&[]model.Machine{{ID: 11, DormID: 1}, {ID: 12, DormID: 1}}).Error)
+
+	require.NoError(t, s.RecordScrape(ctx, t0, []int64{11, 12}, nil))
+	t1 := t0.Add(time.Minute)
+	require.NoError(t, s.RecordScrape(ctx, t1, nil, errors.New("upstream returned 502")))
+	t2 := t1.Add(time.Minute)
+	require.NoError(t, s.RecordScrape(ctx, t2, []int64{11}, errors.New("page 2: timeout")))
+
+	state, err = LoadScraperState(ctx, testDB)
+	require.NoError(t, err)
+	require.NotNil(t, state.LastSuccessAt)
+	assert.True(t, t0.Equal(*state.LastSuccessAt))
+	assert.True(t, t2.Equal(state.LastAttemptAt))
+	assert.Equal(t, 2, state.ConsecutiveFailures)
+	assert.Equal(t, "page 2: timeout", state.LastError)
+
+	var machines []model.Machine
+	require.NoError(t, testDB.Order("id").Find(&machines).Error)
+	assert.True(t, t2.Equal(*machines[0].LastConfirmedAt), "a partial fetch confirms what it returned")
+	assert.True(t, t0.Equal(*machines[1].LastConfirmedAt))
+
+	require.NoError(t, s.RecordScrape(ctx, t2.Add(time.Minute), nil, nil))
+	state, err = LoadScraperState(ctx, testDB)
+	require.NoError(t, err)
+	assert.Zero(t, state.ConsecutiveFailures)
+	assert.Empty(t, state.LastError)
+}
@@ -0,0 +1,83 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// StateAt is the state a machine was in at some instant. Machines without a
+// StateAt were idle.
+type StateAt struct {
+	MachineID   int64
+	Status      int
+	Message     string
+	StartedAt   time.Time
+	PredictedAt *time.Time // Predicted end, if the state had one
+	EndedAt     *time.Time // Observed end; nil if the state is still ongoing
+}
+
+// stateAtRow is the raw row of the point-in-time query.
+type stateAtRow struct {
+	MachineID     int64
+	Status        int
+	Message       string
+	StartedAt     time.Time
+	PeriodEnd     *time.Time
+	EndedAt       *time.Time
+	TimeRemaining int
+}
+
+// MachineStatesAt reconstructs the states of the given machines at an instant
+// in one query: archived segments whose observed span [period_start,
+// observed_at) contains at, plus open records that had already started by
+// then. With TimescaleDB the segment lookup is served by the GIST index on
+// that span; otherwise it is a range filter on the observed_at dimension.
+func MachineStatesAt(ctx context.Context, db *gorm.DB, machineIDs []int64, at time.Time, timescale bool) (map[int64]StateAt, error) {
+	states := make(map[int64]StateAt, len(machineIDs))
+	if len(machineIDs) == 0 {
+		return states, nil
+	}
+
+	contains := "period_start <= @at AND observed_at > @at"
+	if timescale {
+		contains = "tstzrange(period_start, observed_at, '[)') @> CAST(@at AS timestamptz)"
+	}
+	query := "SELECT machine_id, status, message, period_start AS started_at, period_end, observed_at AS ended_at, 0 AS time_remaining " +
+		"FROM occupancy_histories WHERE machine_id IN @ids AND " + contains + " " +
+		"UNION ALL " +
+		"SELECT machine_id, status, message, observed_at, NULL, NULL, time_remaining " +
+		"FROM occupancy_opens WHERE machine_id IN @ids AND observed_at <= @at"
+
+	var rows []stateAtRow
+	err := db.WithContext(ctx).Raw(query, map[string]any{"ids": machineIDs, "at": at}).Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to reconstruct states of %d machines at %s: %w", len(machineIDs), at.Format(time.RFC3339), err)
+	}
+
+	for _, row := range rows {
+		state := StateAt{
+			MachineID: row.MachineID,
+			Status:    row.Status,
+			Message:   row.Message,
+			StartedAt: row.StartedAt,
+			EndedAt:   row.EndedAt,
+		}
+		switch {
+		case row.EndedAt != nil && row.PeriodEnd != nil && !row.PeriodEnd.Equal(*row.EndedAt):
+			// 无预计时长的状态归档时 period_end 等于观测结束时间
+			state.PredictedAt = row.PeriodEnd
+		case row.EndedAt == nil && row.TimeRemaining > 0:
+			predicted := row.StartedAt.Add(time.Duration(row.TimeRemaining) * time.Second)
+			state.PredictedAt = &predicted
+		}
+		// Segments of one machine never overlap, but keep the latest if they do
+		if prev, ok := states[row.MachineID]; ok && prev.StartedAt.After(state.StartedAt) {
+			continue
+		}
+		states[row.MachineID] = state
+	}
+	return states, nil
+}
@@ -0,0 +1,31 @@
+package store
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMachineStatesAt_Timescale(t *testing.T) {
+	db, mock := newTestDB(t)
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery(regexp.QuoteMeta("tstzrange(period_start, observed_at, '[)') @> CAST($3 AS timestamptz) UNION ALL")).
+		WillReturnRows(sqlmock.NewRows([]string{"machine_id", "status", "message", "started_at", "period_end", "ended_at", "time_remaining"}).
+			AddRow(11, 3, "故障", at.Add(-time.Hour), at.Add(time.Minute), at.Add(time.Minute), 0).
+			AddRow(12, 2, "使用中", at.Add(-time.Minute), nil, nil, 600))
+
+	states, err := MachineStatesAt(context.Background(), db, []int64{11, 12}, at, true)
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	require.Len(t, states, 2)
+	assert.Nil(t, states[11].PredictedAt, "archived state without prediction")
+	require.NotNil(t, states[12].PredictedAt)
+	assert.Equal(t, at.Add(9*time.Minute), *states[12].PredictedAt)
+}
@@ -3,7 +3,7 @@ package store
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"time"
 
 	"gorm.io/gorm"
@@ -16,19 +16,23 @@ import (
 // Store defines the interface for all database operations.
 type Store interface {
 	UpsertDormsAndMachines(ctx context.Context, items []ApiItem) error
-	UpdateOccupancy(ctx context.Context, now time.Time, items []ApiItem, getStateType func(int) MachineStateType) ([]int64, error)
+	UpdateOccupancy(ctx context.Context, now time.Time, items []ApiItem, getStateType func(int) MachineStateType) (*OccupancyUpdate, error)
+	RecordScrape(ctx context.Context, at time.Time, seen []int64, scrapeErr error) error
 	DB() *gorm.DB
 }
 
 // gormStore implements the Store interface using GORM.
 type gormStore struct {
-	db *gorm.DB
+	db     *gorm.DB
+	parser *parse.Parser
 }
 
-// NewGormStore creates a new GORM-backed store.
-func NewGormStore(db *gorm.DB) Store {
+// NewGormStore creates a new GORM-backed store. A nil parser uses the
+// built-in naming scheme.
+func NewGormStore(db *gorm.DB, parser *parse.Parser) Store {
 	return &gormStore{
-		db: db,
+		db:     db,
+		parser: parser,
 	}
 }
 
@@ -37,77 +41,218 @@ func (s *gormStore) DB() *gorm.DB {
 	return s.db
 }
 
+// writeBatchSize bounds the number of rows per multi-row statement so that
+// bind parameters stay well below driver limits at campus scale.
+const writeBatchSize = 1000
+
 // UpdateOccupancy processes state changes and updates the database transactionally.
-func (s *gormStore) UpdateOccupancy(ctx context.Context, now time.Time, allItems []ApiItem, getStateType func(int) MachineStateType) ([]int64, error) {
-	var machineIDsToNotify []int64
+func (s *gormStore) UpdateOccupancy(ctx context.Context, now time.Time, allItems []ApiItem, getStateType func(int) MachineStateType) (*OccupancyUpdate, error) {
 	currentOpenRecords, err := s.fetchAllOpenOccupancies(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch open occupancy records: %w", err)
 	}
+	quarantinedIDs, err := s.fetchQuarantinedIDs(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch quarantined machines: %w", err)
+	}
 
-	latestDataMap := make(map[int64]ApiItem)
+	// Quarantined devices have no machines row, so their state is only
+	// tracked once they are promoted or parse. Open records left from before
+	// they were quarantined are dropped without history.
+	items := make([]ApiItem, 0, len(allItems))
 	for _, item := range allItems {
-		latestDataMap[item.ID] = item
+		if _, ok := quarantinedIDs[item.ID]; !ok {
+			items = append(items, item)
+		}
 	}
+	var discard []int64
+	for machineID := range currentOpenRecords {
+		if _, ok := quarantinedIDs[machineID]; ok {
+			discard = append(discard, machineID)
+			delete(currentOpenRecords, machineID)
+		}
+	}
+
+	transitions := computeTransitions(currentOpenRecords, items, now, getStateType)
+	transitions.Delete = append(transitions.Delete, discard...)
 
 	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		// Process each machine from the latest API data.
-		for _, machineData := range allItems {
-			oldRecord, exists := currentOpenRecords[machineData.ID]
-
-			if exists {
-				// State has changed, archive the old record.
-				if machineData.State != oldRecord.Status {
-					if err := archiveRecord(tx, oldRecord, now); err != nil {
-						return err
-					}
-
-					// 判断新状态
-					if getStateType(machineData.State) == StateTypeIdle {
-						// The machine is now idle. Add to notification list.
-						machineIDsToNotify = append(machineIDsToNotify, oldRecord.MachineID)
-
-						// 如果新状态是 Idle，则从 open 表中删除该记录
-						if err := tx.Delete(&model.OccupancyOpen{}, oldRecord.MachineID).Error; err != nil {
-							return fmt.Errorf("failed to delete open occupancy record for machine %d: %w", oldRecord.MachineID, err)
-						}
-					} else {
-						// 如果新状态不是 Idle，则更新记录
-						updatedRecord := s.prepareOccupancy(machineData, now, getStateType)
-						if err := tx.Save(&updatedRecord).Error; err != nil {
-							return fmt.Errorf("failed to update occupancy record for machine %d: %w", machineData.ID, err)
-						}
-					}
-				}
-				// Remove the machine from the map to track which machines we've seen.
-				delete(currentOpenRecords, machineData.ID)
-			} else {
-				// This is a new machine not previously tracked.
-				if getStateType(machineData.State) != StateTypeIdle {
-					newRecord := s.prepareOccupancy(machineData, now, getStateType)
-					if err := tx.Create(&newRecord).Error; err != nil {
-						return fmt.Errorf("failed to create new occupancy record for machine %d: %w", machineData.ID, err)
-					}
-				}
-			}
+		return applyTransitions(tx, transitions)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	update := &OccupancyUpdate{Notify: transitions.Notify}
+	// The writes are committed at this point; a failed lookup only costs the live events.
+	if update.Changes, err = s.placeChanges(ctx, transitions.Changes); err != nil {
+		slog.ErrorContext(ctx, "Error resolving dorms of changed machines", "changes", len(transitions.Changes), "error", err)
+	}
+	return update, nil
+}
+
+// placeChanges fills in the dorm and floor of each change, dropping changes of
+// hidden or unknown machines.
+func (s *gormStore) placeChanges(ctx context.Context, changes []MachineChange) ([]MachineChange, error) {
+	if len(changes) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]int64, len(changes))
+	for i, c := range changes {
+		ids[i] = c.MachineID
+	}
+	placements := make(map[int64]model.Machine, len(ids))
+	for start := 0; start < len(ids); start += writeBatchSize {
+		var machines []model.Machine
+		err := s.db.WithContext(ctx).Scopes(VisibleMachines).
+			Select("id", "dorm_id", "floor").
+			Where("id IN ?", ids[start:min(start+writeBatchSize, len(ids))]).
+			Find(&machines).Error
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range machines {
+			placements[m.ID] = m
 		}
+	}
 
-		// Handle machines that were in our database but are no longer in the API feed.
-		for _, remainingRecord := range currentOpenRecords {
-			if err := archiveRecord(tx, remainingRecord, now); err != nil {
-				return err
-			}
-			if err := tx.Delete(&model.OccupancyOpen{}, remainingRecord.MachineID).Error; err != nil {
-				return fmt.Errorf("failed to delete open occupancy record for machine %d: %w", remainingRecord.MachineID, err)
+	placed := make([]MachineChange, 0, len(changes))
+	for _, c := range changes {
+		m, ok := placements[c.MachineID]
+		if !ok {
+			continue
+		}
+		c.DormID, c.Floor = m.DormID, m.Floor
+		placed = append(placed, c)
+	}
+	return placed, nil
+}
+
+// occupancyTransitions is the set of writes that moves occupancy_opens from
+// its current contents to the state reported by the latest scrape.
+type occupancyTransitions struct {
+	Archive []model.OccupancyHistory // Completed states moved to the cold table
+	Insert  []model.OccupancyOpen    // Machines that became busy
+	Update  []model.OccupancyOpen    // Busy machines whose state changed
+	Delete  []int64                  // Machines that became idle or vanished
+	Notify  []int64                  // Machines that became idle
+	Changes []MachineChange          // Every change above, for live updates
+}
+
+// computeTransitions compares the open records with the latest API data. It
+// performs no I/O so the scrape semantics can be tested and benchmarked alone.
+func computeTransitions(openRecords map[int64]model.OccupancyOpen, allItems []ApiItem, now time.Time, getStateType func(int) MachineStateType) occupancyTransitions {
+	var t occupancyTransitions
+	seen := make(map[int64]struct{}, len(allItems))
+
+	// Process each machine from the latest API data.
+	for _, machineData := range allItems {
+		if _, dup := seen[machineData.ID]; dup {
+			continue
+		}
+		seen[machineData.ID] = struct{}{}
+
+		oldRecord, exists := openRecords[machineData.ID]
+		if !exists {
+			// This is a new machine not previously tracked.
+			if getStateType(machineData.State) != StateTypeIdle {
+				open := prepareOccupancy(machineData, now, getStateType)
+				t.Insert = append(t.Insert, open)
+				t.Changes = append(t.Changes, busyChange(open))
 			}
+			continue
 		}
-		return nil
-	})
-	return machineIDsToNotify, err
+
+		// No state change, nothing to write.
+		if machineData.State == oldRecord.Status {
+			continue
+		}
+
+		// State has changed, archive the old record.
+		t.Archive = append(t.Archive, newHistoryRecord(oldRecord, now))
+
+		// 判断新状态
+		if getStateType(machineData.State) == StateTypeIdle {
+			// 如果新状态是 Idle，则从 open 表中删除该记录，并通知订阅者
+			t.Delete = append(t.Delete, oldRecord.MachineID)
+			t.Notify = append(t.Notify, oldRecord.MachineID)
+			t.Changes = append(t.Changes, idleChange(oldRecord.MachineID, machineData.State, now))
+		} else {
+			// 如果新状态不是 Idle，则更新记录
+			open := prepareOccupancy(machineData, now, getStateType)
+			t.Update = append(t.Update, open)
+			t.Changes = append(t.Changes, busyChange(open))
+		}
+	}
+
+	// Handle machines that were in our database but are no longer in the API feed.
+	for machineID, remainingRecord := range openRecords {
+		if _, ok := seen[machineID]; ok {
+			continue
+		}
+		t.Archive = append(t.Archive, newHistoryRecord(remainingRecord, now))
+		t.Delete = append(t.Delete, machineID)
+		// 从接口中消失的机器按空闲处理，与状态接口的展示一致
+		t.Changes = append(t.Changes, idleChange(machineID, 1, now))
+	}
+	return t
+}
+
+func busyChange(open model.OccupancyOpen) MachineChange {
+	return MachineChange{
+		MachineID:     open.MachineID,
+		Status:        open.Status,
+		Message:       open.Message,
+		TimeRemaining: open.TimeRemaining,
+		ObservedAt:    open.ObservedAt,
+	}
+}
+
+func idleChange(machineID int64, status int, now time.Time) MachineChange {
+	return MachineChange{
+		MachineID:  machineID,
+		Status:     status,
+		Message:    "空闲",
+		ObservedAt: now,
+		Idle:       true,
+	}
 }
 
-// archiveRecord creates a historical record of a completed machine state.
-func archiveRecord(tx *gorm.DB, recordToArchive model.OccupancyOpen, observationTime time.Time) error {
+// applyTransitions writes the transition sets using multi-row statements.
+func applyTransitions(tx *gorm.DB, t occupancyTransitions) error {
+	if len(t.Archive) > 0 {
+		if err := tx.CreateInBatches(&t.Archive, writeBatchSize).Error; err != nil {
+			return fmt.Errorf("failed to archive %d occupancy records: %w", len(t.Archive), err)
+		}
+	}
+
+	for start := 0; start < len(t.Delete); start += writeBatchSize {
+		ids := t.Delete[start:min(start+writeBatchSize, len(t.Delete))]
+		if err := tx.Where("machine_id IN ?", ids).Delete(&model.OccupancyOpen{}).Error; err != nil {
+			return fmt.Errorf("failed to delete %d open occupancy records: %w", len(ids), err)
+		}
+	}
+
+	if len(t.Update) > 0 {
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "machine_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"observed_at", "status", "message", "time_remaining"}),
+		}).CreateInBatches(&t.Update, writeBatchSize).Error; err != nil {
+			return fmt.Errorf("failed to update %d occupancy records: %w", len(t.Update), err)
+		}
+	}
+
+	if len(t.Insert) > 0 {
+		if err := tx.CreateInBatches(&t.Insert, writeBatchSize).Error; err != nil {
+			return fmt.Errorf("failed to create %d occupancy records: %w", len(t.Insert), err)
+		}
+	}
+	return nil
+}
+
+// newHistoryRecord builds the historical record of a completed machine state.
+func newHistoryRecord(recordToArchive model.OccupancyOpen, observationTime time.Time) model.OccupancyHistory {
 	startTime := recordToArchive.ObservedAt
 	// Calculate the PREDICTED end time.
 	var periodEnd time.Time
@@ -121,7 +266,7 @@ func archiveRecord(tx *gorm.DB, recordToArchive model.OccupancyOpen, observation
 		periodEnd = observationTime
 	}
 
-	historyRecord := model.OccupancyHistory{
+	return model.OccupancyHistory{
 		MachineID:  recordToArchive.MachineID,
 		ObservedAt: observationTime, // WHEN we confirmed the state's completion.
 		Status:     recordToArchive.Status,
@@ -130,18 +275,13 @@ func archiveRecord(tx *gorm.DB, recordToArchive model.OccupancyOpen, observation
 		PeriodStart: startTime,
 		PeriodEnd:   periodEnd,
 	}
-
-	if err := tx.Create(&historyRecord).Error; err != nil {
-		return fmt.Errorf("failed to archive occupancy record for machine %d: %w", recordToArchive.MachineID, err)
-	}
-	return nil
 }
 
 // UpsertDormsAndMachines handles the database updates for dorm and machine metadata.
 func (s *gormStore) UpsertDormsAndMachines(ctx context.Context, items []ApiItem) error {
 	existingMachines, err := s.fetchAllMachines(ctx)
 	if err != nil {
-		log.Printf("Warning: could not pre-fetch machines: %v", err)
+		slog.WarnContext(ctx, "Could not pre-fetch machines", "error", err)
 		existingMachines = make(map[int64]model.Machine)
 	}
 
@@ -151,33 +291,107 @@ func (s *gormStore) UpsertDormsAndMachines(ctx context.Context, items []ApiItem)
 		return fmt.Errorf("failed to process dorms: %w", err)
 	}
 
+	overrides, err := s.fetchAllOverrides(ctx)
+	if err != nil {
+		slog.WarnContext(ctx, "Could not pre-fetch machine overrides", "error", err)
+		overrides = make(map[int64]*model.MachineOverride)
+	}
+
+	quarantinedIDs, err := s.fetchQuarantinedIDs(ctx)
+	if err != nil {
+		slog.WarnContext(ctx, "Could not pre-fetch quarantined machines", "error", err)
+		quarantinedIDs = make(map[int64]struct{})
+	}
+
 	// Phase 2: Build machine slice for upserting
+	now := time.Now().UTC()
 	var machinesToUpsert []model.Machine
+	var quarantined []model.QuarantinedMachine
+	var released []int64
 	for _, item := range items {
-		parsedName, err := parse.ParseName(item.Name, item.FloorCode)
+		var dormID int64
+		parsedName, err := s.parser.Parse(item.Name, item.FloorCode)
 		if err != nil {
-			log.Printf("Error parsing name for item %d (%s): %v", item.ID, item.Name, err)
-			continue
+			old, known := existingMachines[item.ID]
+			if !known {
+				// Never seen with a parseable name: keep it visible in quarantine.
+				quarantined = append(quarantined, newQuarantinedMachine(item, err, now))
+				continue
+			}
+			// Known (or promoted) machine: keep its placement, refresh the rest.
+			parsedName = parse.ParsedName{Floor: old.Floor, Seq: old.Seq}
+			dormID = old.DormID
+		} else {
+			dorm, ok := dormMap[parsedName.Dorm]
+			if !ok {
+				slog.ErrorContext(ctx, "Could not find dorm in map after upserting, skipping machine", "dorm", parsedName.Dorm, "machine_id", item.ID)
+				continue
+			}
+			dormID = dorm.ID
 		}
 
-		dorm, ok := dormMap[parsedName.Dorm]
-		if !ok {
-			log.Printf("Error: could not find dorm %q in map after upserting. Skipping machine %d.", parsedName.Dorm, item.ID)
-			continue
+		if _, ok := quarantinedIDs[item.ID]; ok {
+			released = append(released, item.ID)
 		}
 
-		machine, needsUpsert := prepareMachine(item, parsedName, existingMachines, dorm.ID)
+		kind := s.parser.Kind(item.Name, item.DeviceID)
+		machine, needsUpsert := prepareMachine(item, parsedName, kind, existingMachines, dormID, overrides[item.ID])
 		if needsUpsert {
 			machinesToUpsert = append(machinesToUpsert, machine)
 		}
 	}
 
+	if len(quarantined) > 0 {
+		slog.InfoContext(ctx, "Quarantining machines with unparseable names", "machines", len(quarantined))
+	}
+
 	// Execute batch operation for machines
-	if len(machinesToUpsert) > 0 {
-		log.Printf("Batch upserting %d machines...", len(machinesToUpsert))
-		return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-			return batchUpsertMachines(tx, machinesToUpsert)
-		})
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if len(machinesToUpsert) > 0 {
+			slog.InfoContext(ctx, "Batch upserting machines", "machines", len(machinesToUpsert))
+			if err := batchUpsertMachines(tx, machinesToUpsert); err != nil {
+				return err
+			}
+		}
+		if len(quarantined) > 0 {
+			if err := batchUpsertQuarantine(tx, quarantined); err != nil {
+				return fmt.Errorf("failed to quarantine machines: %w", err)
+			}
+		}
+		if len(released) > 0 {
+			if err := tx.Where("id IN ?", released).Delete(&model.QuarantinedMachine{}).Error; err != nil {
+				return fmt.Errorf("failed to release quarantined machines: %w", err)
+			}
+		}
+		return dropStaleQuarantine(ctx, tx, now)
+	})
+}
+
+// quarantineExpiry is how long a quarantined device may be missing from the
+// feed before it is forgotten.
+const quarantineExpiry = 30 * 24 * time.Hour
+
+// dropStaleQuarantine forgets quarantined devices the feed no longer lists,
+// together with any occupancy rows recorded for them before quarantined
+// devices were excluded from occupancy tracking.
+func dropStaleQuarantine(ctx context.Context, tx *gorm.DB, now time.Time) error {
+	var stale []int64
+	if err := tx.Model(&model.QuarantinedMachine{}).Where("last_seen_at < ?", now.Add(-quarantineExpiry)).
+		Pluck("id", &stale).Error; err != nil {
+		return fmt.Errorf("failed to find stale quarantined machines: %w", err)
+	}
+	if len(stale) == 0 {
+		return nil
+	}
+
+	slog.InfoContext(ctx, "Dropping quarantined machines missing from the feed", "machines", len(stale))
+	for _, table := range []any{&model.OccupancyOpen{}, &model.OccupancyHistory{}} {
+		if err := tx.Where("machine_id IN ?", stale).Delete(table).Error; err != nil {
+			return fmt.Errorf("failed to delete occupancy of stale quarantined machines: %w", err)
+		}
+	}
+	if err := tx.Where("id IN ?", stale).Delete(&model.QuarantinedMachine{}).Error; err != nil {
+		return fmt.Errorf("failed to drop stale quarantined machines: %w", err)
 	}
 	return nil
 }
@@ -196,6 +410,30 @@ func (s *gormStore) fetchAllOpenOccupancies(ctx context.Context) (map[int64]mode
 	return recordMap, nil
 }
 
+func (s *gormStore) fetchAllOverrides(ctx context.Context) (map[int64]*model.MachineOverride, error) {
+	var overrides []model.MachineOverride
+	if err := s.db.WithContext(ctx).Find(&overrides).Error; err != nil {
+		return nil, err
+	}
+	overrideMap := make(map[int64]*model.MachineOverride, len(overrides))
+	for i := range overrides {
+		overrideMap[overrides[i].MachineID] = &overrides[i]
+	}
+	return overrideMap, nil
+}
+
+func (s *gormStore) fetchQuarantinedIDs(ctx context.Context) (map[int64]struct{}, error) {
+	var ids []int64
+	if err := s.db.WithContext(ctx).Model(&model.QuarantinedMachine{}).Pluck("id", &ids).Error; err != nil {
+		return nil, err
+	}
+	idSet := make(map[int64]struct{}, len(ids))
+	for _, id := range ids {
+		idSet[id] = struct{}{}
+	}
+	return idSet, nil
+}
+
 func (s *gormStore) fetchAllMachines(ctx context.Context) (map[int64]model.Machine, error) {
 	var machines []model.Machine
 	if err := s.db.WithContext(ctx).Find(&machines).Error; err != nil {
@@ -208,48 +446,92 @@ func (s *gormStore) fetchAllMachines(ctx context.Context) (map[int64]model.Machi
 	return machineMap, nil
 }
 
+// processAndSaveDorms resolves every parsed dorm name through dorm_aliases.
+// Names seen for the first time get a dorm (reusing one with the same name, if
+// any) and an alias, so later renames and merges keep applying to them.
 func (s *gormStore) processAndSaveDorms(ctx context.Context, items []ApiItem) (map[string]model.Dorm, error) {
-	dormsToUpsert := make(map[string]model.Dorm)
+	names := make(map[string]struct{})
 	for _, item := range items {
-		parsedName, err := parse.ParseName(item.Name, item.FloorCode)
+		parsedName, err := s.parser.Parse(item.Name, item.FloorCode)
 		if err != nil {
 			continue
 		}
-		if _, exists := dormsToUpsert[parsedName.Dorm]; !exists {
-			dormsToUpsert[parsedName.Dorm] = model.Dorm{Name: parsedName.Dorm}
-		}
+		names[parsedName.Dorm] = struct{}{}
 	}
 
-	if len(dormsToUpsert) == 0 {
+	if len(names) == 0 {
 		return make(map[string]model.Dorm), nil
 	}
 
-	var dormList []model.Dorm
-	for _, d := range dormsToUpsert {
-		dormList = append(dormList, d)
+	nameList := make([]string, 0, len(names))
+	for name := range names {
+		nameList = append(nameList, name)
 	}
 
-	log.Printf("Batch upserting %d dorms...", len(dormList))
-	if err := s.db.WithContext(ctx).Clauses(clause.OnConflict{
-		Columns:   []clause.Column{{Name: "name"}},
-		DoUpdates: clause.AssignmentColumns([]string{"name"}),
-	}).Create(&dormList).Error; err != nil {
-		return nil, fmt.Errorf("batch upsert dorms failed: %w", err)
+	dormMap, err := resolveDormAliases(s.db.WithContext(ctx), nameList)
+	if err != nil {
+		return nil, err
 	}
 
-	var allDorms []model.Dorm
-	if err := s.db.WithContext(ctx).Find(&allDorms).Error; err != nil {
-		return nil, fmt.Errorf("failed to retrieve dorms after upsert: %w", err)
+	var unresolved []model.Dorm
+	for _, name := range nameList {
+		if _, ok := dormMap[name]; !ok {
+			unresolved = append(unresolved, model.Dorm{Name: name})
+		}
+	}
+	if len(unresolved) == 0 {
+		return dormMap, nil
 	}
 
-	dormMap := make(map[string]model.Dorm, len(allDorms))
-	for _, d := range allDorms {
-		dormMap[d.Name] = d
+	slog.InfoContext(ctx, "Batch upserting dorms", "dorms", len(unresolved))
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "name"}},
+			DoUpdates: clause.AssignmentColumns([]string{"name"}),
+		}).Create(&unresolved).Error; err != nil {
+			return fmt.Errorf("batch upsert dorms failed: %w", err)
+		}
+
+		// Re-read by name: on conflict the returned IDs are not reliable across drivers.
+		newNames := make([]string, len(unresolved))
+		for i, d := range unresolved {
+			newNames[i] = d.Name
+		}
+		var dorms []model.Dorm
+		if err := tx.Where("name IN ?", newNames).Find(&dorms).Error; err != nil {
+			return fmt.Errorf("failed to retrieve dorms after upsert: %w", err)
+		}
+
+		aliases := make([]model.DormAlias, len(dorms))
+		for i, d := range dorms {
+			aliases[i] = model.DormAlias{Alias: d.Name, DormID: d.ID}
+			dormMap[d.Name] = d
+		}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&aliases).Error; err != nil {
+			return fmt.Errorf("failed to create dorm aliases: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 	return dormMap, nil
 }
 
-func (s *gormStore) prepareOccupancy(item ApiItem, now time.Time, getStateType func(int) MachineStateType) model.OccupancyOpen {
+// resolveDormAliases maps each known alias in names to its canonical dorm.
+func resolveDormAliases(db *gorm.DB, names []string) (map[string]model.Dorm, error) {
+	var aliases []model.DormAlias
+	if err := db.Preload("Dorm").Where("alias IN ?", names).Find(&aliases).Error; err != nil {
+		return nil, fmt.Errorf("failed to resolve dorm aliases: %w", err)
+	}
+	dormMap := make(map[string]model.Dorm, len(names))
+	for _, a := range aliases {
+		dormMap[a.Alias] = a.Dorm
+	}
+	return dormMap, nil
+}
+
+func prepareOccupancy(item ApiItem, now time.Time, getStateType func(int) MachineStateType) model.OccupancyOpen {
 	var timeRemaining int
 	// Use the pre-parsed timestamp from the scraper
 	if item.FinishTimeParsed != nil && item.FinishTimeParsed.After(now) {
@@ -275,7 +557,9 @@ func (s *gormStore) prepareOccupancy(item ApiItem, now time.Time, getStateType f
 	}
 }
 
-func prepareMachine(item ApiItem, parsedName parse.ParsedName, existingMachines map[int64]model.Machine, dormID int64) (model.Machine, bool) {
+// prepareMachine builds the machine row from upstream data with the admin
+// override (if any) applied, and reports whether it differs from the stored row.
+func prepareMachine(item ApiItem, parsedName parse.ParsedName, kind string, existingMachines map[int64]model.Machine, dormID int64, override *model.MachineOverride) (model.Machine, bool) {
 	newMachine := model.Machine{
 		ID:          item.ID,
 		DormID:      dormID,
@@ -285,15 +569,19 @@ func prepareMachine(item ApiItem, parsedName parse.ParsedName, existingMachines
 		FloorCode:   item.FloorCode,
 		Floor:       parsedName.Floor,
 		Seq:         parsedName.Seq,
+		Kind:        kind,
 	}
+	applyOverride(&newMachine, override)
 
 	if oldMachine, exists := existingMachines[newMachine.ID]; exists {
-		if oldMachine.DisplayName == newMachine.DisplayName &&
+		if oldMachine.DormID == newMachine.DormID &&
+			oldMachine.DisplayName == newMachine.DisplayName &&
 			oldMachine.IMEI == newMachine.IMEI &&
 			oldMachine.DeviceID == newMachine.DeviceID &&
 			oldMachine.FloorCode == newMachine.FloorCode &&
 			oldMachine.Floor == newMachine.Floor &&
-			oldMachine.Seq == newMachine.Seq {
+			oldMachine.Seq == newMachine.Seq &&
+			oldMachine.Kind == newMachine.Kind {
 			return newMachine, false
 		}
 	}
@@ -303,6 +591,26 @@ func prepareMachine(item ApiItem, parsedName parse.ParsedName, existingMachines
 func batchUpsertMachines(tx *gorm.DB, machines []model.Machine) error {
 	return tx.Clauses(clause.OnConflict{
 		Columns:   []clause.Column{{Name: "id"}},
-		DoUpdates: clause.AssignmentColumns([]string{"dorm_id", "display_name", "imei", "device_id", "floor_code", "floor", "seq", "updated_at"}),
+		DoUpdates: clause.AssignmentColumns([]string{"dorm_id", "display_name", "imei", "device_id", "floor_code", "floor", "seq", "kind", "updated_at"}),
 	}).Create(&machines).Error
 }
+
+func newQuarantinedMachine(item ApiItem, parseErr error, now time.Time) model.QuarantinedMachine {
+	return model.QuarantinedMachine{
+		ID:          item.ID,
+		RawName:     item.Name,
+		FloorCode:   item.FloorCode,
+		IMEI:        item.IMEI,
+		DeviceID:    item.DeviceID,
+		Error:       parseErr.Error(),
+		FirstSeenAt: now,
+		LastSeenAt:  now,
+	}
+}
+
+func batchUpsertQuarantine(tx *gorm.DB, machines []model.QuarantinedMachine) error {
+	return tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"raw_name", "floor_code", "imei", "device_id", "error", "last_seen_at"}),
+	}).CreateInBatches(&machines, writeBatchSize).Error
+}
@@ -11,7 +11,9 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
 
 	"laundry-status-backend/internal/model"
 )
@@ -61,14 +63,18 @@ func TestGormStore_UpdateOccupancy(t *testing.T) {
 					WillReturnRows(sqlmock.NewRows([]string{"machine_id", "status", "observed_at"}).
 						AddRow(101, 2, now.Add(-10*time.Minute)))
 
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT "id" FROM "quarantined_machines"`)).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}))
 				mock.ExpectBegin()
 				mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "occupancy_histories"`)).
 					WithArgs(101, Any{}, 2, "", Any{}, Any{}).
 					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
-				mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "occupancy_opens" WHERE "occupancy_opens"."machine_id" = $1`)).
+				mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "occupancy_opens" WHERE machine_id IN ($1)`)).
 					WithArgs(101).
 					WillReturnResult(sqlmock.NewResult(1, 1))
 				mock.ExpectCommit()
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT "id","dorm_id","floor" FROM "machines"`)).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "dorm_id", "floor"}).AddRow(101, 1, 3))
 			},
 			expectedNotifyIDs: []int64{101},
 			expectedErr:       false,
@@ -86,15 +92,19 @@ func TestGormStore_UpdateOccupancy(t *testing.T) {
 					WillReturnRows(sqlmock.NewRows([]string{"machine_id", "status", "observed_at"}).
 						AddRow(102, 2, now.Add(-10*time.Minute)))
 
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT "id" FROM "quarantined_machines"`)).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}))
 				mock.ExpectBegin()
 				mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "occupancy_histories"`)).
 					WithArgs(102, Any{}, 2, "", Any{}, Any{}).
 					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
-				// Expect an UPDATE (via Save)
-				mock.ExpectExec(regexp.QuoteMeta(`UPDATE "occupancy_opens"`)).
+				// Expect a multi-row upsert
+				mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "occupancy_opens"`)+`.*`+regexp.QuoteMeta(`ON CONFLICT ("machine_id") DO UPDATE`)).
 					WithArgs(Any{}, 3, "使用中", 0, 102).
-					WillReturnResult(sqlmock.NewResult(1, 1))
+					WillReturnRows(sqlmock.NewRows([]string{"machine_id"}).AddRow(102))
 				mock.ExpectCommit()
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT "id","dorm_id","floor" FROM "machines"`)).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "dorm_id", "floor"}).AddRow(102, 1, 3))
 			},
 			expectedNotifyIDs: nil,
 			expectedErr:       false,
@@ -111,6 +121,8 @@ func TestGormStore_UpdateOccupancy(t *testing.T) {
 				mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "occupancy_opens"`)).
 					WillReturnRows(sqlmock.NewRows([]string{"machine_id", "status", "observed_at"}).
 						AddRow(103, 2, now.Add(-10*time.Minute)))
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT "id" FROM "quarantined_machines"`)).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}))
 				mock.ExpectBegin()
 				// No database writes expected
 				mock.ExpectCommit()
@@ -128,11 +140,15 @@ func TestGormStore_UpdateOccupancy(t *testing.T) {
 				mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "occupancy_opens"`)).
 					WillReturnRows(sqlmock.NewRows([]string{"machine_id", "status", "observed_at"}))
 
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT "id" FROM "quarantined_machines"`)).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}))
 				mock.ExpectBegin()
 				mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "occupancy_opens"`)).
 					WithArgs(Any{}, 2, "使用中", 0, 104).
 					WillReturnRows(sqlmock.NewRows([]string{"machine_id"}).AddRow(104))
 				mock.ExpectCommit()
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT "id","dorm_id","floor" FROM "machines"`)).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "dorm_id", "floor"}).AddRow(104, 1, 3))
 			},
 			expectedNotifyIDs: nil,
 			expectedErr:       false,
@@ -148,6 +164,8 @@ func TestGormStore_UpdateOccupancy(t *testing.T) {
 					WillReturnRows(sqlmock.NewRows([]string{"machine_id", "status", "observed_at"}).
 						AddRow(105, 2, now.Add(-10*time.Minute)))
 
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT "id" FROM "quarantined_machines"`)).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}))
 				mock.ExpectBegin()
 				mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "occupancy_histories"`)).
 					WithArgs(105, Any{}, 2, "", Any{}, Any{}).
@@ -156,6 +174,8 @@ func TestGormStore_UpdateOccupancy(t *testing.T) {
 					WithArgs(105).
 					WillReturnResult(sqlmock.NewResult(1, 1))
 				mock.ExpectCommit()
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT "id","dorm_id","floor" FROM "machines"`)).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "dorm_id", "floor"}).AddRow(1, 1, 3))
 			},
 			expectedNotifyIDs: nil,
 			expectedErr:       false,
@@ -165,17 +185,21 @@ func TestGormStore_UpdateOccupancy(t *testing.T) {
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			gormDB, mock := newTestDB(t)
-			store := NewGormStore(gormDB)
+			store := NewGormStore(gormDB, nil)
 
 			tc.mockExpectations(mock)
 
-			notifyIDs, err := store.UpdateOccupancy(context.Background(), now, tc.apiItems, getStateType)
+			update, err := store.UpdateOccupancy(context.Background(), now, tc.apiItems, getStateType)
 
 			if tc.expectedErr {
 				assert.Error(t, err)
 			} else {
 				assert.NoError(t, err)
-				assert.ElementsMatch(t, tc.expectedNotifyIDs, notifyIDs)
+				assert.ElementsMatch(t, tc.expectedNotifyIDs, update.Notify)
+				for _, c := range update.Changes {
+					assert.Equal(t, int64(1), c.DormID)
+					assert.Equal(t, 3, c.Floor)
+				}
 			}
 
 			assert.NoError(t, mock.ExpectationsWereMet())
@@ -183,6 +207,111 @@ func TestGormStore_UpdateOccupancy(t *testing.T) {
 	}
 }
 
+func TestComputeTransitions(t *testing.T) {
+	now := time.Now()
+	getStateType := func(state int) MachineStateType {
+		switch state {
+		case 1:
+			return StateTypeIdle
+		case 3:
+			return StateTypeFaulty
+		}
+		return StateTypeOccupied
+	}
+
+	open := map[int64]model.OccupancyOpen{
+		1: {MachineID: 1, Status: 2, ObservedAt: now.Add(-40 * time.Minute), TimeRemaining: 1800},
+		2: {MachineID: 2, Status: 2, ObservedAt: now.Add(-10 * time.Minute)},
+		3: {MachineID: 3, Status: 3, ObservedAt: now.Add(-time.Hour)},
+		4: {MachineID: 4, Status: 2, ObservedAt: now.Add(-5 * time.Minute)},
+	}
+	items := []ApiItem{
+		{ID: 1, State: 1}, // busy -> idle
+		{ID: 2, State: 3}, // busy -> faulty
+		{ID: 3, State: 3}, // unchanged
+		{ID: 5, State: 2}, // new busy machine
+		{ID: 6, State: 1}, // new idle machine
+		{ID: 5, State: 1}, // duplicate entries are ignored
+		// machine 4 disappeared from the feed
+	}
+
+	tr := computeTransitions(open, items, now, getStateType)
+
+	assert.ElementsMatch(t, []int64{1}, tr.Notify)
+	assert.ElementsMatch(t, []int64{1, 4}, tr.Delete)
+	require.Len(t, tr.Update, 1)
+	assert.Equal(t, int64(2), tr.Update[0].MachineID)
+	assert.Equal(t, "设备故障", tr.Update[0].Message)
+	require.Len(t, tr.Insert, 1)
+	assert.Equal(t, int64(5), tr.Insert[0].MachineID)
+
+	archived := make(map[int64]model.OccupancyHistory)
+	for _, h := range tr.Archive {
+		archived[h.MachineID] = h
+	}
+	require.Len(t, archived, 3)
+	assert.Equal(t, now.Add(-10*time.Minute), archived[1].PeriodEnd, "predicted end comes from TimeRemaining")
+	assert.Equal(t, now, archived[2].PeriodEnd, "states without a prediction end when observed")
+	assert.Equal(t, now, archived[4].ObservedAt)
+
+	changes := make(map[int64]MachineChange)
+	for _, c := range tr.Changes {
+		changes[c.MachineID] = c
+	}
+	require.Len(t, changes, 4, "unchanged and still idle machines produce no change")
+	assert.True(t, changes[1].Idle)
+	assert.Equal(t, 3, changes[2].Status)
+	assert.False(t, changes[5].Idle)
+	assert.True(t, changes[4].Idle, "machines missing from the feed are reported idle")
+}
+
+// BenchmarkGormStore_UpdateOccupancy measures a full scrape cycle at campus
+// scale, with every machine flipping state on each iteration.
+func BenchmarkGormStore_UpdateOccupancy(b *testing.B) {
+	const machines = 5000
+
+	testDB, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(b, err)
+	require.NoError(b, testDB.AutoMigrate(&model.Machine{}, &model.MachineOverride{}, &model.QuarantinedMachine{}, &model.OccupancyOpen{}, &model.OccupancyHistory{}))
+	sqlDB, _ := testDB.DB()
+	defer sqlDB.Close()
+	sqlDB.SetMaxOpenConns(1) // Each connection to ":memory:" is a separate database.
+
+	getStateType := func(state int) MachineStateType {
+		if state == 1 {
+			return StateTypeIdle
+		}
+		return StateTypeOccupied
+	}
+
+	// Rotate between all busy, half idle/half in a new busy state, and back, so
+	// each cycle exercises archive, insert, update and delete together.
+	feeds := make([][]ApiItem, 3)
+	for i := range feeds {
+		feeds[i] = make([]ApiItem, machines)
+	}
+	for id := 0; id < machines; id++ {
+		feeds[0][id] = ApiItem{ID: int64(id + 1), State: 2}
+		feeds[1][id] = ApiItem{ID: int64(id + 1), State: 1 + (id%2)*2}
+		feeds[2][id] = ApiItem{ID: int64(id + 1), State: 2 + (id%2)*2}
+	}
+
+	s := NewGormStore(testDB, nil)
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		now = now.Add(time.Minute)
+		if _, err := s.UpdateOccupancy(ctx, now, feeds[i%len(feeds)], getStateType); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(machines*b.N)/b.Elapsed().Seconds(), "machines/s")
+}
+
 // Any is a helper for sqlmock to match any argument.
 type Any struct{}
 
@@ -26,3 +26,21 @@ const (
 	StateTypeFaulty   MachineStateType = "faulty"
 	StateTypeUnknown  MachineStateType = "unknown"
 )
+
+// MachineChange is a committed change of one machine's occupancy state.
+type MachineChange struct {
+	MachineID     int64
+	DormID        int64
+	Floor         int
+	Status        int
+	Message       string
+	TimeRemaining int
+	ObservedAt    time.Time
+	Idle          bool
+}
+
+// OccupancyUpdate is the outcome of one UpdateOccupancy call.
+type OccupancyUpdate struct {
+	Notify  []int64         // Machines that became idle and whose subscribers should be notified
+	Changes []MachineChange // State changes of visible machines, in scrape order
+}